@@ -19,12 +19,30 @@ func main() {
 	switch sub {
 	case "upload":
 		uploadMain(os.Args[2:])
+	case "cache":
+		cacheMain(os.Args[2:])
 	case "check":
 		checkMain(os.Args[2:])
+	case "export":
+		exportMain(os.Args[2:])
 	case "latest":
 		latestMain(os.Args[2:])
+	case "lock":
+		lockMain(os.Args[2:])
+	case "rollback":
+		rollbackMain(os.Args[2:])
+	case "status":
+		statusMain(os.Args[2:])
+	case "strays":
+		straysMain(os.Args[2:])
+	case "uninstall":
+		uninstallMain(os.Args[2:])
+	case "verify":
+		verifyMain(os.Args[2:])
 	case "version":
 		versionMain()
+	case "versions":
+		versionsMain(os.Args[2:])
 	case "download":
 		log.Fatal("there is no need to supply 'download' as a sub-command")
 	default:
@@ -41,6 +59,14 @@ func uploadMain(args []string) {
 	transfer.NewUploadApp(config).Run()
 }
 
+func cacheMain(args []string) {
+	config, err := transfer.ParseCacheConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewCacheApp(config).Run()
+}
+
 func checkMain(args []string) {
 	loader := core.NewUploadConfigLoader(shell.NewDiskFileSystem(""), shell.NewEnvironment(), os.Stdin, os.Stderr)
 	config, err := loader.LoadConfig("check", args)
@@ -58,6 +84,14 @@ func downloadMain(args []string) {
 	transfer.NewDownloadApp(config).Run()
 }
 
+func exportMain(args []string) {
+	config, err := transfer.ParseExportConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewExportApp(config).Run()
+}
+
 func latestMain(args []string) {
 	config, err := transfer.ParseLatestConfig(args)
 	if err != nil {
@@ -66,8 +100,65 @@ func latestMain(args []string) {
 	transfer.NewLatestApp(config).Run()
 }
 
+func lockMain(args []string) {
+	config, err := transfer.ParseLockConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewLockApp(config).Run()
+}
+
+func rollbackMain(args []string) {
+	config, err := transfer.ParseRollbackConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewRollbackApp(config).Run()
+}
+
+func statusMain(args []string) {
+	config, err := transfer.ParseStatusConfig(args)
+	if err != nil {
+		log.Println(err)
+		os.Exit(transfer.StatusExitUnknown) // a broken configuration or listing is not "action needed"
+	}
+	os.Exit(transfer.NewStatusApp(config).TryRun())
+}
+
+func straysMain(args []string) {
+	config, err := transfer.ParseStraysConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewStraysApp(config).Run()
+}
+
+func uninstallMain(args []string) {
+	config, err := transfer.ParseUninstallConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewUninstallApp(config).Run()
+}
+
+func verifyMain(args []string) {
+	config, err := transfer.ParseVerifyConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewVerifyApp(config).Run()
+}
+
 func versionMain() {
 	log.Printf("satisfy [%s]\n", ldflagsSoftwareVersion)
 }
 
+func versionsMain(args []string) {
+	config, err := transfer.ParseVersionsConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transfer.NewVersionsApp(config).Run()
+}
+
 var ldflagsSoftwareVersion = "debug"
@@ -0,0 +1,25 @@
+package contracts
+
+import (
+	"io"
+	"net/url"
+)
+
+// ArchiveCache stores downloaded package archives, keyed by the checksum of their contents,
+// so that each archive is downloaded once per machine rather than once per installation.
+// The manifests of the archives are kept as well (by remote address) for offline use.
+type ArchiveCache interface {
+	Load(key string) (io.ReadCloser, bool)
+	Store(key string) (ArchiveCacheEntry, error)
+	Evict(key string) error
+	StoreManifest(remoteAddress url.URL, rawManifest []byte) error
+}
+
+// ArchiveCacheEntry receives the contents of an archive as it is downloaded. Nothing is
+// visible to other readers of the cache until the entry is committed. Write never fails
+// (so a full cache can't fail an installation); any failure is reported by Commit instead.
+type ArchiveCacheEntry interface {
+	io.Writer
+	Commit() error
+	Abort()
+}
@@ -26,6 +26,10 @@ type PackageConfig struct {
 	PackageName          string `json:"package_name"`
 	PackageVersion       string `json:"package_version"`
 	RemoteAddressPrefix  *URL   `json:"remote_address"`
+
+	// Dependencies lists the packages required alongside this one: their version (or version
+	// constraint) and where they are installed. The remote address defaults to that of this package.
+	Dependencies []ManifestDependency `json:"dependencies,omitempty"`
 }
 
 func (this PackageConfig) ComposeRemoteAddress(filename string) url.URL {
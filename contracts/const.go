@@ -4,3 +4,8 @@ const (
 	RemoteManifestFilename = "manifest.json"
 	RemoteArchiveFilename  = "archive"
 )
+
+const (
+	VersionedLayout       = "versioned"
+	DefaultRetainVersions = 3
+)
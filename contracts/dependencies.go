@@ -13,11 +13,30 @@ import (
 type DependencyListing struct {
 	Credentials string       `json:"credentials"`
 	Listing     []Dependency `json:"dependencies"`
+
+	// RemoteCredentials assigns credentials to the remote addresses of the dependencies
+	// without credentials of their own, by host; the first matching host pattern applies.
+	RemoteCredentials []RemoteCredentials `json:"remote_credentials,omitempty"`
+}
+
+// RemoteCredentials names the credentials to use for the hosts (buckets) matching the pattern (see path.Match).
+type RemoteCredentials struct {
+	Host        string `json:"host"`
+	Credentials string `json:"credentials"`
 }
 
 func (this *DependencyListing) Validate() error {
-	inventory := make(map[string]struct{}) // map[PackageName+LocalDirectory]struct
+	inventory := make(map[string]struct{})       // map[PackageName+LocalDirectory]struct
+	directories := make(map[string][]Dependency) // map[LocalDirectory][]Dependency
 
+	for _, remote := range this.RemoteCredentials {
+		if _, err := path.Match(remote.Host, ""); err != nil || remote.Host == "" {
+			return fmt.Errorf("invalid remote credentials host pattern: %q", remote.Host)
+		}
+		if remote.Credentials == "" {
+			return fmt.Errorf("remote credentials for %q require credentials", remote.Host)
+		}
+	}
 	for i, dependency := range this.Listing {
 		if dependency.LocalDirectory == "" {
 			return errors.New("local directory is required")
@@ -31,6 +50,24 @@ func (this *DependencyListing) Validate() error {
 		if dependency.RemoteAddress.Value().String() == "" {
 			return errors.New("remote address is required")
 		}
+		if err := ValidateVersion(dependency.PackageVersion); err != nil {
+			return err
+		}
+		if err := dependency.Selection().Validate(); err != nil {
+			return err
+		}
+		if err := dependency.Remapping().Validate(); err != nil {
+			return err
+		}
+		if dependency.Layout != "" && dependency.Layout != VersionedLayout {
+			return fmt.Errorf("unrecognized layout: %q", dependency.Layout)
+		}
+		if dependency.RetainVersions < 0 {
+			return errors.New("retain versions must not be negative")
+		}
+		if dependency.Anonymous && dependency.Credentials != "" {
+			return fmt.Errorf("%s can't be anonymous and have credentials", dependency.Title())
+		}
 
 		dependency.LocalDirectory = resolveLocalDirectory(dependency.LocalDirectory)
 		this.Listing[i] = dependency
@@ -40,6 +77,14 @@ func (this *DependencyListing) Validate() error {
 		}
 
 		inventory[key] = struct{}{}
+		directories[dependency.LocalDirectory] = append(directories[dependency.LocalDirectory], dependency)
+	}
+	for directory, dependencies := range directories {
+		for _, dependency := range dependencies {
+			if dependency.IsVersioned() && len(dependencies) > 1 {
+				return fmt.Errorf("versioned layout of %s requires exclusive use of local directory %q", dependency.Title(), directory)
+			}
+		}
 	}
 	return nil
 }
@@ -63,10 +108,77 @@ func formatLocalDirectory(value string) string {
 }
 
 type Dependency struct {
-	PackageName    string `json:"package_name"`
-	PackageVersion string `json:"package_version"`
-	RemoteAddress  URL    `json:"remote_address"`
-	LocalDirectory string `json:"local_directory"`
+	PackageName    string   `json:"package_name"`
+	PackageVersion string   `json:"package_version"`
+	RemoteAddress  URL      `json:"remote_address"`
+	LocalDirectory string   `json:"local_directory"`
+	Include        []string `json:"include,omitempty"`
+	Exclude        []string `json:"exclude,omitempty"`
+
+	StripComponents int           `json:"strip_components,omitempty"`
+	PathMappings    []PathMapping `json:"path_mappings,omitempty"`
+
+	Layout         string `json:"layout,omitempty"`
+	RetainVersions int    `json:"retain_versions,omitempty"`
+
+	// ManifestDigest, when set, must match the digest of the remote manifest (see ComputeManifestDigest).
+	ManifestDigest string `json:"manifest_digest,omitempty"`
+
+	// Precedence decides which of the packages sharing a local directory installs a file that
+	// more than one of them contains (the highest precedence wins); without it that is an error.
+	Precedence int `json:"precedence,omitempty"`
+
+	// Overridden lists the archive items (by exact path) that a package of higher precedence installs
+	// instead (see core.ResolveCollisions). Unlike Exclude, it is decided on each run and not listed.
+	Overridden []string `json:"-"`
+
+	// PostInstall is a shell command run in the install directory once the package has been installed,
+	// updated or repaired (for a versioned layout: in the version directory, before the version is
+	// activated); a failure is recorded and the command is run again on the next install.
+	// PreUninstall is run there before the package is uninstalled (see core.HookRunner).
+	PostInstall  string `json:"post_install,omitempty"`
+	PreUninstall string `json:"pre_uninstall,omitempty"`
+
+	// Credentials is the access token, or names the key file of the service account, used to download
+	// the package, as for the credentials of the listing (see DependencyListing.RemoteCredentials for
+	// credentials shared by the packages of a bucket).
+	Credentials string `json:"credentials,omitempty"`
+
+	// Anonymous downloads the package from a public bucket with unsigned requests (no credentials at all).
+	Anonymous bool `json:"anonymous,omitempty"`
+}
+
+// IsVersioned reports whether each version of the package is installed side-by-side
+// beneath the local directory with a "current" symlink referring to the active one.
+func (this Dependency) IsVersioned() bool {
+	return this.Layout == VersionedLayout
+}
+
+// HasVersionConstraint reports whether the package version is a constraint (e.g. "^1.4")
+// to be resolved against the published versions rather than a literal version.
+func (this Dependency) HasVersionConstraint() bool {
+	return IsVersionConstraint(this.PackageVersion)
+}
+
+func (this Dependency) ComposeRemotePackagePrefix() url.URL {
+	address := url.URL(this.RemoteAddress)
+	address.Path = path.Join("/", address.Path, this.PackageName) + "/"
+	return address
+}
+
+func (this Dependency) ComposeRetainVersions() int {
+	if this.RetainVersions < 1 {
+		return DefaultRetainVersions
+	}
+	return this.RetainVersions
+}
+
+func (this Dependency) Remapping() PathRemapping {
+	return PathRemapping{StripComponents: this.StripComponents, Mappings: this.PathMappings}
+}
+
+func (this Dependency) Selection() PathSelection {
+	return PathSelection{Include: this.Include, Exclude: this.Exclude}
 }
 
 func (this Dependency) ComposeRemoteAddress(fileName string) url.URL {
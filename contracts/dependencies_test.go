@@ -63,6 +63,15 @@ func (this *DependencyListingFixture) TestValidateEachDependencyMustHaveARemoteA
 	this.So(err, should.NotBeNil)
 }
 
+func (this *DependencyListingFixture) TestValidateEachDependencyMustHaveWellFormedPathPatterns() {
+	this.appendDependency("name", "1.2.3", "host", "local")
+	this.listing.Listing[0].Include = []string{"states/["}
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
 func (this *DependencyListingFixture) TestValidateResolvesLocalDirectory() {
 	this.appendDependency("name", "1.2.3", "address", "~/")
 	this.appendDependency("name", "1.2.3", "address", "~/path1")
@@ -90,6 +99,59 @@ func (this *DependencyListingFixture) TestMultiplePackagesWithSameNameCannotBeIn
 	this.So(err, should.NotBeNil)
 }
 
+func (this *DependencyListingFixture) TestValidateRejectsUnrecognizedLayout() {
+	this.appendDependency("name", "1.2.3", "address", "local")
+	this.listing.Listing[0].Layout = "sideways"
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestVersionedLayoutRequiresExclusiveLocalDirectory() {
+	this.appendDependency("name1", "1.2.3", "address", "local")
+	this.appendDependency("name2", "1.2.3", "address", "local")
+	this.listing.Listing[1].Layout = VersionedLayout
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestValidateVersionConstraints() {
+	this.appendDependency("name", "^1.4", "host", "directory")
+	this.So(this.listing.Validate(), should.BeNil)
+
+	this.listing.Listing[0].PackageVersion = ">=1.0 <2.x.0"
+	this.So(this.listing.Validate(), should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestValidateRemoteCredentials() {
+	this.appendDependency("name", "1.2.3", "host", "directory")
+	this.listing.RemoteCredentials = []RemoteCredentials{{Host: "project-a-*", Credentials: "project-a.json"}}
+	this.So(this.listing.Validate(), should.BeNil)
+
+	this.listing.RemoteCredentials[0].Host = "project-[a"
+	this.So(this.listing.Validate(), should.NotBeNil)
+
+	this.listing.RemoteCredentials[0] = RemoteCredentials{Host: "project-a-*"}
+	this.So(this.listing.Validate(), should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestValidateAnonymousWithoutCredentials() {
+	this.appendDependency("name", "1.2.3", "host", "directory")
+	this.listing.Listing[0].Anonymous = true
+	this.So(this.listing.Validate(), should.BeNil)
+
+	this.listing.Listing[0].Credentials = "key.json"
+	this.So(this.listing.Validate(), should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestComposeRetainVersions() {
+	this.So(Dependency{}.ComposeRetainVersions(), should.Equal, DefaultRetainVersions)
+	this.So(Dependency{RetainVersions: 5}.ComposeRetainVersions(), should.Equal, 5)
+}
+
 func (this *DependencyListingFixture) TestAppendRemoteAddress() {
 	address, err := url.Parse("https://www.google.com/folder")
 	this.So(err, should.BeNil)
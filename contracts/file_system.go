@@ -9,7 +9,7 @@ import (
 // FUTURE: make each file system path return any underlying error.
 
 type PathLister interface {
-	Listing() []FileInfo
+	Listing() ([]FileInfo, error)
 }
 
 type FileOpener interface {
@@ -36,6 +36,18 @@ type Deleter interface {
 	Delete(path string)
 }
 
+type Renamer interface {
+	Rename(source, target string) error
+}
+
+type TreeDeleter interface {
+	DeleteAll(path string)
+}
+
+type EmptyDirectoryDeleter interface {
+	DeleteEmptyDirectory(path string) bool
+}
+
 type FileChecker interface {
 	Stat(path string) (FileInfo, error)
 }
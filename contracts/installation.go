@@ -6,6 +6,15 @@ type InstallationRequest struct {
 	RemoteAddress url.URL
 	LocalPath     string
 	PackageName   string
+	Selection     PathSelection
+	Remapping     PathRemapping
+
+	// Overridden lists the archive items (by exact path) left to another package; unlike the
+	// selection, it is not recorded in the installed manifest.
+	Overridden []string
+
+	// ManifestDigest, when set, is the digest the downloaded manifest is required to have.
+	ManifestDigest string
 }
 
 type IntegrityCheck interface {
@@ -0,0 +1,71 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// LockFile pins each dependency of a listing to a concrete version and to the digest
+// of that version's manifest, so that repeated installations are reproducible.
+type LockFile struct {
+	Packages []LockEntry `json:"packages"`
+}
+
+type LockEntry struct {
+	PackageName      string `json:"package_name"`
+	RemoteAddress    string `json:"remote_address"`
+	RequestedVersion string `json:"requested_version"`
+	PackageVersion   string `json:"package_version"`
+	ManifestDigest   string `json:"manifest_digest"`
+}
+
+// matches ignores the remote address, which a profile may rewrite (e.g. to a mirror): the
+// manifest digest still has to match wherever the package is downloaded from.
+func (this LockEntry) matches(dependency Dependency) bool {
+	return this.PackageName == dependency.PackageName && this.RequestedVersion == dependency.PackageVersion
+}
+
+// Find returns the entry recorded for the dependency as declared in the listing (or in the manifest requiring it).
+func (this LockFile) Find(dependency Dependency) (LockEntry, bool) {
+	for _, entry := range this.Packages {
+		if entry.matches(dependency) {
+			return entry, true
+		}
+	}
+	return LockEntry{}, false
+}
+
+// Put records the entry, replacing any entry for the same dependency.
+func (this *LockFile) Put(entry LockEntry) {
+	for i, existing := range this.Packages {
+		if existing.PackageName == entry.PackageName && existing.RequestedVersion == entry.RequestedVersion {
+			this.Packages[i] = entry
+			return
+		}
+	}
+	this.Packages = append(this.Packages, entry)
+	sort.SliceStable(this.Packages, func(i, j int) bool {
+		return this.Packages[i].PackageName < this.Packages[j].PackageName
+	})
+}
+
+// Pin returns the dependency with its version and manifest digest taken from the lock file.
+func (this LockFile) Pin(dependency Dependency) (Dependency, error) {
+	entry, found := this.Find(dependency)
+	if !found {
+		return dependency, fmt.Errorf("%s is not in the lock file (run 'satisfy lock' to add it)", dependency.Title())
+	}
+	dependency.PackageVersion = entry.PackageVersion
+	dependency.ManifestDigest = entry.ManifestDigest
+	return dependency, nil
+}
+
+// ComputeManifestDigest returns the digest of a manifest exactly as it was published.
+func ComputeManifestDigest(rawManifest []byte) string {
+	sum := sha256.Sum256(rawManifest)
+	return ManifestDigestAlgorithm + ":" + hex.EncodeToString(sum[:])
+}
+
+const ManifestDigestAlgorithm = "sha256"
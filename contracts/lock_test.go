@@ -0,0 +1,81 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestLockFileFixture(t *testing.T) {
+	gunit.Run(new(LockFileFixture), t)
+}
+
+type LockFileFixture struct {
+	*gunit.Fixture
+
+	lock       LockFile
+	dependency Dependency
+}
+
+func (this *LockFileFixture) Setup() {
+	this.dependency = Dependency{
+		PackageName:    "package",
+		PackageVersion: "latest",
+		RemoteAddress:  URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"},
+		LocalDirectory: "local",
+	}
+	this.lock.Put(LockEntry{
+		PackageName:      "package",
+		RemoteAddress:    "gcs://bucket/prefix",
+		RequestedVersion: "latest",
+		PackageVersion:   "1.2.3",
+		ManifestDigest:   "sha256:abc",
+	})
+}
+
+func (this *LockFileFixture) TestPinReplacesVersionAndRecordsDigest() {
+	pinned, err := this.lock.Pin(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(pinned.PackageVersion, should.Equal, "1.2.3")
+	this.So(pinned.ManifestDigest, should.Equal, "sha256:abc")
+	this.So(pinned.LocalDirectory, should.Equal, "local")
+}
+
+func (this *LockFileFixture) TestPinIgnoresRewrittenRemoteAddress() {
+	this.dependency.RemoteAddress = URL{Scheme: "gcs", Host: "mirror", Path: "/prefix"}
+
+	pinned, err := this.lock.Pin(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(pinned.PackageVersion, should.Equal, "1.2.3")
+	this.So(pinned.ManifestDigest, should.Equal, "sha256:abc")
+}
+
+func (this *LockFileFixture) TestPinFailsForDependencyNotInLockFile() {
+	this.dependency.PackageVersion = "2.0.0"
+
+	_, err := this.lock.Pin(this.dependency)
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *LockFileFixture) TestPutReplacesExistingEntry() {
+	this.lock.Put(LockEntry{
+		PackageName:      "package",
+		RemoteAddress:    "gcs://bucket/prefix",
+		RequestedVersion: "latest",
+		PackageVersion:   "1.2.4",
+	})
+	this.lock.Put(LockEntry{PackageName: "another", RequestedVersion: "latest"})
+
+	this.So(this.lock.Packages, should.HaveLength, 2)
+	this.So(this.lock.Packages[0].PackageName, should.Equal, "another")
+	this.So(this.lock.Packages[1].PackageVersion, should.Equal, "1.2.4")
+}
+
+func (this *LockFileFixture) TestManifestDigest() {
+	this.So(ComputeManifestDigest([]byte("{}")), should.Equal,
+		"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a")
+}
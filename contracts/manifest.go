@@ -1,9 +1,82 @@
 package contracts
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
 type Manifest struct {
 	Name    string  `json:"name"` //a-z 0-9 _-/
 	Version string  `json:"version"`
 	Archive Archive `json:"archive"`
+
+	// Selection records the subset of the archive installed locally (nil when the whole archive was installed).
+	Selection *PathSelection `json:"selection,omitempty"`
+
+	// Remapping records how archive paths were relocated when installed locally (nil when extracted as-is).
+	Remapping *PathRemapping `json:"remapping,omitempty"`
+
+	// Digest records the digest of the manifest as it was published (see ComputeManifestDigest).
+	Digest string `json:"digest,omitempty"`
+
+	// Dependencies lists the packages required alongside this one (see PackageConfig.Dependencies).
+	Dependencies []ManifestDependency `json:"dependencies,omitempty"`
+}
+
+// ComposeLocalPath returns where the archive item is (or would be) installed
+// beneath localPath, or false when the item is not installed at all.
+func (this Manifest) ComposeLocalPath(localPath, itemPath string) (string, bool) {
+	return this.InstalledRemapping().ComposeLocalPath(localPath, itemPath)
+}
+
+func (this Manifest) InstalledRemapping() PathRemapping {
+	if this.Remapping == nil {
+		return PathRemapping{}
+	}
+	return *this.Remapping
+}
+
+func (this Manifest) Selects(itemPath string) bool {
+	return this.Selection == nil || this.Selection.Selects(itemPath)
+}
+
+func (this Manifest) InstalledSelection() PathSelection {
+	if this.Selection == nil {
+		return PathSelection{}
+	}
+	return *this.Selection
+}
+
+// ManifestDependency is a package required by a published package. Unlike a Dependency of the
+// listing it is written by whoever publishes the package, so it only names the package, its version
+// (or version constraint), where it is published and where it is installed: a directory relative to
+// the parent of the local directory of the requiring package (i.e. next to it). Hooks, credentials
+// and path mappings are left to the listing; a manifest that declares them is rejected.
+type ManifestDependency struct {
+	PackageName    string `json:"package_name"`
+	PackageVersion string `json:"package_version"`
+	RemoteAddress  URL    `json:"remote_address"`
+	LocalDirectory string `json:"local_directory,omitempty"`
+}
+
+func (this *ManifestDependency) UnmarshalJSON(p []byte) error {
+	type plain ManifestDependency // without this method
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*plain)(this)); err != nil {
+		return fmt.Errorf("dependency declared in manifest: %w", err)
+	}
+	return this.Validate()
+}
+
+// Validate reports a local directory that is absolute or escapes the parent directory it is relative to.
+func (this ManifestDependency) Validate() error {
+	if this.LocalDirectory != "" && !filepath.IsLocal(this.LocalDirectory) {
+		return fmt.Errorf("dependency %q: local directory %q must be relative and must not leave its parent directory", this.PackageName, this.LocalDirectory)
+	}
+	return nil
 }
 
 type Archive struct {
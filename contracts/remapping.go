@@ -0,0 +1,77 @@
+package contracts
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PathRemapping describes where archive items land relative to the local directory.
+// Leading path components are stripped first, then the first mapping whose source
+// is a prefix (at a path component boundary) of what remains is applied. Absolute
+// targets are used as-is; relative targets are placed beneath the local directory.
+type PathRemapping struct {
+	StripComponents int           `json:"strip_components,omitempty"`
+	Mappings        []PathMapping `json:"path_mappings,omitempty"`
+}
+
+type PathMapping struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+func (this PathRemapping) IsEmpty() bool {
+	return this.StripComponents == 0 && len(this.Mappings) == 0
+}
+
+func (this PathRemapping) Validate() error {
+	if this.StripComponents < 0 {
+		return errors.New("strip components must not be negative")
+	}
+	for _, mapping := range this.Mappings {
+		if strings.Trim(mapping.Source, "/") == "" {
+			return errors.New("path mapping source is required")
+		}
+		if mapping.Target == "" {
+			return errors.New("path mapping target is required")
+		}
+	}
+	return nil
+}
+
+// ComposeLocalPath returns the location of the archive item once installed,
+// or false when the item is stripped away entirely.
+func (this PathRemapping) ComposeLocalPath(localDirectory, itemPath string) (string, bool) {
+	remapped, ok := this.remap(itemPath)
+	if !ok {
+		return "", false
+	}
+	if filepath.IsAbs(remapped) {
+		return remapped, true
+	}
+	return filepath.Join(localDirectory, remapped), true
+}
+
+func (this PathRemapping) remap(itemPath string) (string, bool) {
+	parts := strings.Split(strings.Trim(itemPath, "/"), "/")
+	if len(parts) <= this.StripComponents {
+		return "", false
+	}
+	remaining := path.Join(parts[this.StripComponents:]...)
+	for _, mapping := range this.Mappings {
+		source := strings.Trim(mapping.Source, "/")
+		if remaining == source {
+			return filepath.FromSlash(mapping.Target), true
+		}
+		if strings.HasPrefix(remaining, source+"/") {
+			return filepath.Join(filepath.FromSlash(mapping.Target), filepath.FromSlash(remaining[len(source)+1:])), true
+		}
+	}
+	return filepath.FromSlash(remaining), true
+}
+
+func (this PathRemapping) Equal(that PathRemapping) bool {
+	return this.StripComponents == that.StripComponents && slices.Equal(this.Mappings, that.Mappings)
+}
@@ -0,0 +1,60 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestPathRemappingFixture(t *testing.T) {
+	gunit.Run(new(PathRemappingFixture), t)
+}
+
+type PathRemappingFixture struct {
+	*gunit.Fixture
+}
+
+func (this *PathRemappingFixture) TestEmptyRemappingJoinsLocalDirectory() {
+	path, installed := PathRemapping{}.ComposeLocalPath("/local", "/bin/tool")
+
+	this.So(installed, should.BeTrue)
+	this.So(path, should.Equal, "/local/bin/tool")
+}
+
+func (this *PathRemappingFixture) TestStripComponents() {
+	remapping := PathRemapping{StripComponents: 1}
+
+	path, installed := remapping.ComposeLocalPath("/local", "package-1.2.3/bin/tool")
+	this.So(installed, should.BeTrue)
+	this.So(path, should.Equal, "/local/bin/tool")
+
+	_, installed = remapping.ComposeLocalPath("/local", "README")
+	this.So(installed, should.BeFalse)
+}
+
+func (this *PathRemappingFixture) TestMappingsAppliedAfterStripping() {
+	remapping := PathRemapping{
+		StripComponents: 1,
+		Mappings: []PathMapping{
+			{Source: "bin/", Target: "/usr/local/bin"},
+			{Source: "share", Target: "data"},
+		},
+	}
+
+	path, _ := remapping.ComposeLocalPath("/local", "package/bin/tool")
+	this.So(path, should.Equal, "/usr/local/bin/tool")
+
+	path, _ = remapping.ComposeLocalPath("/local", "package/share/states/UT")
+	this.So(path, should.Equal, "/local/data/states/UT")
+
+	path, _ = remapping.ComposeLocalPath("/local", "package/binary")
+	this.So(path, should.Equal, "/local/binary")
+}
+
+func (this *PathRemappingFixture) TestValidate() {
+	this.So(PathRemapping{StripComponents: 2}.Validate(), should.BeNil)
+	this.So(PathRemapping{StripComponents: -1}.Validate(), should.NotBeNil)
+	this.So(PathRemapping{Mappings: []PathMapping{{Source: "/", Target: "x"}}}.Validate(), should.NotBeNil)
+	this.So(PathRemapping{Mappings: []PathMapping{{Source: "bin", Target: ""}}}.Validate(), should.NotBeNil)
+}
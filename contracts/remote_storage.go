@@ -8,11 +8,13 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type RemoteStorage interface {
 	Uploader
 	Downloader
+	Lister
 }
 
 type Uploader interface {
@@ -33,6 +35,17 @@ type Downloader interface {
 	Size(url.URL) (int64, error)
 }
 
+// Lister enumerates the remote objects whose path begins with the path of the prefix address.
+type Lister interface {
+	List(prefix url.URL) ([]RemoteObject, error)
+}
+
+type RemoteObject struct {
+	Path    string // includes a leading slash, like url.URL.Path
+	Size    int64
+	Updated time.Time
+}
+
 type DownloadSetter interface {
 	SetDownloader(url.URL, Downloader)
 }
@@ -51,10 +64,13 @@ func AppendRemotePath(prefix url.URL, packageName, version, fileName string) url
 
 var RetryErr = errors.New("retry")
 
+var ErrNotAvailableOffline = errors.New("not available offline")
+
 type StatusCodeError struct {
 	actualStatusCode   int
 	expectedStatusCode []int
 	remoteAddress      url.URL
+	hint               string
 }
 
 func NewStatusCodeError(actual int, expected []int, remoteAddress url.URL) *StatusCodeError {
@@ -67,10 +83,20 @@ func (this *StatusCodeError) Error() string {
 		IDs = append(IDs, strconv.Itoa(i))
 	}
 
-	return fmt.Sprintf(
+	message := fmt.Sprintf(
 		"expected status code: [%s] actual status code: [%d] remote address: [%s]",
 		strings.Join(IDs, " or "), this.actualStatusCode, this.remoteAddress.String(),
 	)
+	if this.hint != "" {
+		message += " (" + this.hint + ")"
+	}
+	return message
+}
+
+// WithHint adds an explanation of what the status code likely means to the error message.
+func (this *StatusCodeError) WithHint(hint string) *StatusCodeError {
+	this.hint = hint
+	return this
 }
 
 func (this *StatusCodeError) StatusCode() int {
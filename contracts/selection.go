@@ -0,0 +1,76 @@
+package contracts
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// PathSelection narrows the contents of a package to the archive items whose
+// paths match at least one Include pattern (all items when none are given)
+// and none of the Exclude patterns. Patterns use path.Match syntax and also
+// select everything beneath a matching directory.
+type PathSelection struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (this PathSelection) IsEmpty() bool {
+	return len(this.Include) == 0 && len(this.Exclude) == 0
+}
+
+func (this PathSelection) Validate() error {
+	for _, pattern := range append(slices.Clone(this.Include), this.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("malformed path pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (this PathSelection) Selects(itemPath string) bool {
+	if len(this.Include) > 0 && !matchesAny(this.Include, itemPath) {
+		return false
+	}
+	return !matchesAny(this.Exclude, itemPath)
+}
+
+func (this PathSelection) Apply(items []ArchiveItem) (selected []ArchiveItem) {
+	if this.IsEmpty() {
+		return items
+	}
+	for _, item := range items {
+		if this.Selects(item.Path) {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+func (this PathSelection) Equal(that PathSelection) bool {
+	return slices.Equal(this.Include, that.Include) && slices.Equal(this.Exclude, that.Exclude)
+}
+
+func matchesAny(patterns []string, itemPath string) bool {
+	itemPath = strings.Trim(itemPath, "/")
+	for _, pattern := range patterns {
+		if matches(strings.Trim(pattern, "/"), itemPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(pattern, itemPath string) bool {
+	for candidate := itemPath; ; {
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+		parent := path.Dir(candidate)
+		if parent == "." || parent == candidate {
+			return false
+		}
+		candidate = parent
+	}
+}
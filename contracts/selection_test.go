@@ -0,0 +1,52 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestPathSelectionFixture(t *testing.T) {
+	gunit.Run(new(PathSelectionFixture), t)
+}
+
+type PathSelectionFixture struct {
+	*gunit.Fixture
+}
+
+func (this *PathSelectionFixture) TestEmptySelectionSelectsEverything() {
+	selection := PathSelection{}
+
+	this.So(selection.IsEmpty(), should.BeTrue)
+	this.So(selection.Selects("any/path"), should.BeTrue)
+}
+
+func (this *PathSelectionFixture) TestIncludeSelectsMatchingPathsAndEverythingBeneath() {
+	selection := PathSelection{Include: []string{"states/UT", "states/N*"}}
+
+	this.So(selection.Selects("states/UT/data.bin"), should.BeTrue)
+	this.So(selection.Selects("/states/NV/data.bin"), should.BeTrue)
+	this.So(selection.Selects("states/AZ/data.bin"), should.BeFalse)
+	this.So(selection.Selects("states"), should.BeFalse)
+}
+
+func (this *PathSelectionFixture) TestExcludeTakesPrecedenceOverInclude() {
+	selection := PathSelection{Include: []string{"states"}, Exclude: []string{"states/*/index.*"}}
+
+	this.So(selection.Selects("states/UT/data.bin"), should.BeTrue)
+	this.So(selection.Selects("states/UT/index.db"), should.BeFalse)
+}
+
+func (this *PathSelectionFixture) TestApply() {
+	items := []ArchiveItem{{Path: "a/1"}, {Path: "b/2"}, {Path: "c/3"}}
+
+	this.So(PathSelection{}.Apply(items), should.Resemble, items)
+	this.So(PathSelection{Exclude: []string{"b"}}.Apply(items), should.Resemble, []ArchiveItem{{Path: "a/1"}, {Path: "c/3"}})
+}
+
+func (this *PathSelectionFixture) TestEqual() {
+	this.So(PathSelection{}.Equal(PathSelection{Include: []string{}}), should.BeTrue)
+	this.So(PathSelection{Include: []string{"a"}}.Equal(PathSelection{Include: []string{"a"}}), should.BeTrue)
+	this.So(PathSelection{Include: []string{"a"}}.Equal(PathSelection{Exclude: []string{"a"}}), should.BeFalse)
+}
@@ -0,0 +1,297 @@
+package contracts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemanticVersion is a parsed major.minor.patch[-prerelease][+build] version
+// (a leading "v" and missing minor/patch components are tolerated).
+type SemanticVersion struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	components          int
+}
+
+func ParseSemanticVersion(value string) (version SemanticVersion, ok bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if index := strings.IndexByte(value, '+'); index >= 0 {
+		value = value[:index]
+	}
+	if index := strings.IndexByte(value, '-'); index >= 0 {
+		version.Prerelease = value[index+1:]
+		value = value[:index]
+		if version.Prerelease == "" {
+			return SemanticVersion{}, false
+		}
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return SemanticVersion{}, false
+	}
+	numbers := []*int{&version.Major, &version.Minor, &version.Patch}
+	for i, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return SemanticVersion{}, false
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return SemanticVersion{}, false
+		}
+		*numbers[i] = number
+	}
+	version.components = len(parts)
+	return version, true
+}
+
+// Compare returns -1, 0 or 1 according to semantic version precedence.
+func (this SemanticVersion) Compare(that SemanticVersion) int {
+	for _, pair := range [][2]int{{this.Major, that.Major}, {this.Minor, that.Minor}, {this.Patch, that.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1])
+		}
+	}
+	return comparePrereleases(this.Prerelease, that.Prerelease)
+}
+
+func (this SemanticVersion) String() string {
+	if this.Prerelease == "" {
+		return fmt.Sprintf("%d.%d.%d", this.Major, this.Minor, this.Patch)
+	}
+	return fmt.Sprintf("%d.%d.%d-%s", this.Major, this.Minor, this.Patch, this.Prerelease)
+}
+
+func comparePrereleases(a, b string) int {
+	if a == b {
+		return 0
+	} else if a == "" {
+		return 1 // a release has precedence over any of its pre-releases
+	} else if b == "" {
+		return -1
+	}
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] == right[i] {
+			continue
+		}
+		leftNumber, leftErr := strconv.Atoi(left[i])
+		rightNumber, rightErr := strconv.Atoi(right[i])
+		switch {
+		case leftErr == nil && rightErr == nil:
+			return compareInts(leftNumber, rightNumber)
+		case leftErr == nil:
+			return -1 // numeric identifiers have lower precedence
+		case rightErr == nil:
+			return 1
+		default:
+			return strings.Compare(left[i], right[i])
+		}
+	}
+	return compareInts(len(left), len(right))
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// VersionConstraint is a set of alternatives (separated by "||"), each of which is a
+// space-separated list of comparators that must all be satisfied, for example:
+// "^1.4", "~2.3.1", ">=1.0 <2.0" or "1.2.x || >=3".
+type VersionConstraint struct {
+	raw          string
+	alternatives [][]comparator
+	prerelease   bool
+}
+
+type comparator struct {
+	operator string
+	version  SemanticVersion
+}
+
+// IsVersionConstraint reports whether the package version is a constraint to be
+// resolved against the published versions rather than a literal version (or "latest"):
+// a value that parses as a semantic version, or doesn't parse as a constraint, is literal.
+func IsVersionConstraint(value string) bool {
+	if _, exact := ParseSemanticVersion(value); exact {
+		return false
+	}
+	_, err := ParseVersionConstraint(value)
+	return err == nil
+}
+
+// ValidateVersion rejects a package version that is meant as a constraint (it starts with an
+// operator or has alternatives) but is malformed, rather than taking it as a literal version.
+func ValidateVersion(value string) error {
+	if strings.IndexAny(value, "^~<>=") == 0 || strings.Contains(value, "||") {
+		_, err := ParseVersionConstraint(value)
+		return err
+	}
+	return nil
+}
+
+func ParseVersionConstraint(value string) (constraint VersionConstraint, err error) {
+	constraint.raw = value
+	for _, alternative := range strings.Split(value, "||") {
+		var comparators []comparator
+		fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' })
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if isOperator(field) && i+1 < len(fields) {
+				i++
+				field += fields[i] // tolerate ">= 1.0"
+			}
+			parsed, err := parseComparator(field)
+			if err != nil {
+				return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: %w", value, err)
+			}
+			constraint.prerelease = constraint.prerelease || strings.Contains(field, "-")
+			comparators = append(comparators, parsed...)
+		}
+		if len(fields) == 0 {
+			return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: empty alternative", value)
+		}
+		constraint.alternatives = append(constraint.alternatives, comparators)
+	}
+	return constraint, nil
+}
+
+func isOperator(value string) bool {
+	switch value {
+	case "^", "~", ">", ">=", "<", "<=", "=":
+		return true
+	}
+	return false
+}
+
+func parseComparator(value string) ([]comparator, error) {
+	operator := value[:len(value)-len(strings.TrimLeft(value, "^~<>="))]
+	operand := value[len(operator):]
+	if !isOperator(operator) && operator != "" {
+		return nil, fmt.Errorf("unrecognized operator %q", operator)
+	}
+	if operand == "*" || operand == "x" {
+		if operator != "" {
+			return nil, fmt.Errorf("unexpected operator %q before wildcard", operator)
+		}
+		return nil, nil // matches anything
+	}
+	operand, wildcard := trimWildcard(operand)
+	version, ok := ParseSemanticVersion(operand)
+	if !ok {
+		return nil, fmt.Errorf("malformed version %q", value)
+	}
+	if wildcard && operator != "" {
+		return nil, fmt.Errorf("unexpected operator %q before wildcard", operator)
+	}
+	lower := comparator{operator: ">=", version: version}
+	switch {
+	case operator == "^":
+		return []comparator{lower, {operator: "<", version: caretUpperBound(version)}}, nil
+	case operator == "~" || operator == "" && version.components < 3:
+		return []comparator{lower, {operator: "<", version: tildeUpperBound(version)}}, nil
+	case operator == "":
+		return []comparator{{operator: "=", version: version}}, nil
+	default:
+		return []comparator{{operator: operator, version: version}}, nil
+	}
+}
+
+// trimWildcard turns "1.2.x" (or "1.2.*") into "1.2", which is then treated as a partial version.
+func trimWildcard(value string) (string, bool) {
+	for _, suffix := range []string{".x", ".*", ".X"} {
+		if strings.HasSuffix(value, suffix) {
+			trimmed, _ := trimWildcard(strings.TrimSuffix(value, suffix))
+			return trimmed, true
+		}
+	}
+	return value, false
+}
+
+func caretUpperBound(version SemanticVersion) SemanticVersion {
+	switch {
+	case version.Major > 0 || version.components == 1:
+		return SemanticVersion{Major: version.Major + 1}
+	case version.Minor > 0 || version.components == 2:
+		return SemanticVersion{Minor: version.Minor + 1}
+	default:
+		return SemanticVersion{Patch: version.Patch + 1}
+	}
+}
+
+func tildeUpperBound(version SemanticVersion) SemanticVersion {
+	if version.components == 1 {
+		return SemanticVersion{Major: version.Major + 1}
+	}
+	return SemanticVersion{Major: version.Major, Minor: version.Minor + 1}
+}
+
+// Allows reports whether the version satisfies the constraint. Pre-release versions are
+// only allowed when the constraint itself mentions a pre-release.
+func (this VersionConstraint) Allows(version SemanticVersion) bool {
+	if version.Prerelease != "" && !this.prerelease {
+		return false
+	}
+	for _, comparators := range this.alternatives {
+		if allowsAll(comparators, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowsAll(comparators []comparator, version SemanticVersion) bool {
+	for _, item := range comparators {
+		comparison := version.Compare(item.version)
+		switch item.operator {
+		case "=":
+			if comparison != 0 {
+				return false
+			}
+		case ">":
+			if comparison <= 0 {
+				return false
+			}
+		case ">=":
+			if comparison < 0 {
+				return false
+			}
+		case "<":
+			if comparison >= 0 {
+				return false
+			}
+		case "<=":
+			if comparison > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Select returns the highest of the versions that satisfies the constraint.
+func (this VersionConstraint) Select(versions []string) (string, bool) {
+	var best string
+	var bestVersion SemanticVersion
+	for _, candidate := range versions {
+		version, ok := ParseSemanticVersion(candidate)
+		if !ok || !this.Allows(version) {
+			continue
+		}
+		if best == "" || version.Compare(bestVersion) > 0 {
+			best, bestVersion = candidate, version
+		}
+	}
+	return best, best != ""
+}
+
+func (this VersionConstraint) String() string {
+	return this.raw
+}
@@ -0,0 +1,103 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestVersionConstraintFixture(t *testing.T) {
+	gunit.Run(new(VersionConstraintFixture), t)
+}
+
+type VersionConstraintFixture struct {
+	*gunit.Fixture
+}
+
+var published = []string{"0.9.0", "1.0.0", "1.3.9", "1.4.0", "1.4.7", "1.10.2", "2.0.0-beta.1", "2.0.0", "2.3.1", "2.3.5", "2.4.0", "v3.1", "nightly"}
+
+func (this *VersionConstraintFixture) assertSelected(constraint, expected string) {
+	parsed, err := ParseVersionConstraint(constraint)
+	this.So(err, should.BeNil)
+	selected, found := parsed.Select(published)
+	this.So(selected, should.Equal, expected)
+	this.So(found, should.Equal, expected != "")
+}
+
+func (this *VersionConstraintFixture) TestCaret() {
+	this.assertSelected("^1.4", "1.10.2")
+	this.assertSelected("^2", "2.4.0")
+	this.assertSelected("^0.9", "0.9.0")
+	this.assertSelected("^4", "")
+}
+
+func (this *VersionConstraintFixture) TestTilde() {
+	this.assertSelected("~2.3.1", "2.3.5")
+	this.assertSelected("~1.4", "1.4.7")
+	this.assertSelected("~1", "1.10.2")
+}
+
+func (this *VersionConstraintFixture) TestComparisons() {
+	this.assertSelected(">=1.0 <2.0", "1.10.2")
+	this.assertSelected(">= 1.0, < 1.4", "1.3.9")
+	this.assertSelected(">2.3.1 <=2.3.5", "2.3.5")
+	this.assertSelected("<1", "0.9.0")
+	this.assertSelected(">=3", "v3.1")
+}
+
+func (this *VersionConstraintFixture) TestAlternatives() {
+	this.assertSelected("~1.3 || ~2.3", "2.3.5")
+	this.assertSelected("<1 || =1.4.0", "1.4.0")
+}
+
+func (this *VersionConstraintFixture) TestWildcards() {
+	this.assertSelected("1.4.x", "1.4.7")
+	this.assertSelected("*", "v3.1")
+}
+
+func (this *VersionConstraintFixture) TestPreReleasesOnlyWhenRequested() {
+	this.assertSelected(">=2.0.0-beta <2.0.0", "2.0.0-beta.1")
+	this.assertSelected(">=1.10.2 <2.0.0", "1.10.2")
+}
+
+func (this *VersionConstraintFixture) TestMalformedConstraints() {
+	for _, constraint := range []string{"^", "^1.x", "=>1.0", ">=1.0.0.0", "^a.b", "1.0 ||", "<>1"} {
+		_, err := ParseVersionConstraint(constraint)
+		this.So(err, should.NotBeNil)
+	}
+}
+
+func (this *VersionConstraintFixture) TestIsVersionConstraint() {
+	this.So(IsVersionConstraint("^1.4"), should.BeTrue)
+	this.So(IsVersionConstraint(">=1.0 <2.0"), should.BeTrue)
+	this.So(IsVersionConstraint("1.2.x"), should.BeTrue)
+	this.So(IsVersionConstraint("1.2.3"), should.BeFalse)
+	this.So(IsVersionConstraint("latest"), should.BeFalse)
+	this.So(IsVersionConstraint("2026-04-01"), should.BeFalse)
+	this.So(IsVersionConstraint("1.2"), should.BeFalse)
+	this.So(IsVersionConstraint("* || 1.0"), should.BeTrue)
+}
+
+func (this *VersionConstraintFixture) TestVersionsThatDoNotParseAsConstraintsAreLiteral() {
+	this.So(IsVersionConstraint("1.0~rc1"), should.BeFalse)
+	this.So(IsVersionConstraint("2.x-beta"), should.BeFalse)
+	this.So(IsVersionConstraint("build 42"), should.BeFalse)
+}
+
+func (this *VersionConstraintFixture) TestValidateVersion() {
+	this.So(ValidateVersion("1.0~rc1"), should.BeNil)
+	this.So(ValidateVersion("2.x-beta"), should.BeNil)
+	this.So(ValidateVersion("^1.4"), should.BeNil)
+	this.So(ValidateVersion("^1.x"), should.NotBeNil)
+	this.So(ValidateVersion("1.0 ||"), should.NotBeNil)
+}
+
+func (this *VersionConstraintFixture) TestSemanticVersionPrecedence() {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		previous, _ := ParseSemanticVersion(ordered[i-1])
+		current, _ := ParseSemanticVersion(ordered[i])
+		this.So(previous.Compare(current), should.Equal, -1)
+	}
+}
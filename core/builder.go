@@ -41,13 +41,17 @@ func NewDirectoryPackageBuilder(storage DirectoryPackageBuilderFileSystem, archi
 }
 
 func (this *DirectoryPackageBuilder) Build() error {
-	if fileInfo, ok := this.fileOnly(); ok == true {
+	listing, err := this.storage.Listing()
+	if err != nil {
+		return err
+	}
+	if fileInfo, ok := fileOnly(listing); ok == true {
 		err := this.add(fileInfo, true)
 		if err != nil {
 			return err
 		}
 	} else {
-		for _, file := range this.storage.Listing() {
+		for _, file := range listing {
 			err := this.add(file, false)
 			if err != nil {
 				return err
@@ -68,7 +72,7 @@ func (this *DirectoryPackageBuilder) add(file contracts.FileInfo, fileOnly bool)
 	if err != nil {
 		return err
 	}
-	this.contents = append(this.contents, this.buildManifestEntry(file, header.LinkName))
+	this.contents = append(this.contents, this.buildManifestEntry(file, header.LinkName, fileOnly))
 	return err
 }
 
@@ -136,10 +140,10 @@ func (this *DirectoryPackageBuilder) symlinkOutOfBoundError(file contracts.FileI
 		this.storage.RootPath())
 }
 
-func (this *DirectoryPackageBuilder) buildManifestEntry(file contracts.FileInfo, symlinkSourcePath string) contracts.ArchiveItem {
+func (this *DirectoryPackageBuilder) buildManifestEntry(file contracts.FileInfo, symlinkSourcePath string, fileOnly bool) contracts.ArchiveItem {
 	defer this.hasher.Reset()
 	var path string
-	if _, ok := this.fileOnly(); ok == true {
+	if fileOnly {
 		path = filepath.Base(file.Path())
 	} else {
 		path = strings.TrimPrefix(file.Path(), this.storage.RootPath()+"/")
@@ -174,10 +178,10 @@ func (this *DirectoryPackageBuilder) isAbsolute(path string) bool {
 	return strings.HasPrefix(path, "/")
 }
 
-func (this *DirectoryPackageBuilder) fileOnly() (contracts.FileInfo, bool) {
-	if len(this.storage.Listing()) == 1 {
-		if this.storage.Listing()[0].Mode().IsRegular() {
-			return this.storage.Listing()[0], true
+func fileOnly(listing []contracts.FileInfo) (contracts.FileInfo, bool) {
+	if len(listing) == 1 {
+		if listing[0].Mode().IsRegular() {
+			return listing[0], true
 		}
 	}
 	return nil, false
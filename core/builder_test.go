@@ -63,6 +63,15 @@ func (this *DirectoryPackageBuilderFixture) TestSimulatedArchiveWriteError() {
 
 	this.So(err, should.Equal, writeErr)
 }
+func (this *DirectoryPackageBuilderFixture) TestListingErrorIsReturned() {
+	this.fileSystem.errListing = errors.New("permission denied")
+
+	err := this.builder.Build()
+
+	this.So(err, should.Equal, this.fileSystem.errListing)
+	this.So(this.archive.items, should.BeEmpty)
+	this.So(this.builder.Contents(), should.BeEmpty)
+}
 func (this *DirectoryPackageBuilderFixture) TestSimulatedArchiveCloseError() {
 	this.archive.closedError = closeErr
 
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// PublishedVersion describes a version of a package found in remote storage.
+type PublishedVersion struct {
+	Version     string
+	ArchiveSize int64
+	Updated     time.Time
+}
+
+// PackageCatalog enumerates the versions of a package published in remote storage.
+// A version counts as published once its manifest exists (manifests are uploaded last).
+type PackageCatalog struct {
+	lister contracts.Lister
+}
+
+func NewPackageCatalog(lister contracts.Lister) *PackageCatalog {
+	return &PackageCatalog{lister: lister}
+}
+
+func (this *PackageCatalog) ListVersions(dependency contracts.Dependency) (versions []PublishedVersion, err error) {
+	prefix := dependency.ComposeRemotePackagePrefix()
+	objects, err := this.lister.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not list published versions of %q: %w", dependency.PackageName, err)
+	}
+	manifests := make(map[string]contracts.RemoteObject)
+	archives := make(map[string]int64)
+	for _, object := range objects {
+		version, fileName, found := strings.Cut(strings.TrimPrefix(object.Path, prefix.Path), "/")
+		if !found || version == "" {
+			continue
+		}
+		switch fileName {
+		case contracts.RemoteManifestFilename:
+			manifests[version] = object
+		case contracts.RemoteArchiveFilename:
+			archives[version] = object.Size
+		}
+	}
+	for version, manifest := range manifests {
+		versions = append(versions, PublishedVersion{Version: version, ArchiveSize: archives[version], Updated: manifest.Updated})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// ResolveConstraint returns the highest published version that satisfies the version constraint of the dependency.
+func (this *PackageCatalog) ResolveConstraint(dependency contracts.Dependency) (string, error) {
+	constraint, err := contracts.ParseVersionConstraint(dependency.PackageVersion)
+	if err != nil {
+		return "", err
+	}
+	published, err := this.ListVersions(dependency)
+	if err != nil {
+		return "", err
+	}
+	var versions []string
+	for _, item := range published {
+		versions = append(versions, item.Version)
+	}
+	version, found := constraint.Select(versions)
+	if !found {
+		return "", fmt.Errorf("no published version of %q satisfies %q (published versions: %s)",
+			dependency.PackageName, constraint, describeVersions(versions))
+	}
+	return version, nil
+}
+
+// ResolveLatest returns the newest published version of the package: the highest semantic
+// version or, if none of the versions is a semantic version, the most recently published one.
+func (this *PackageCatalog) ResolveLatest(dependency contracts.Dependency) (string, error) {
+	published, err := this.ListVersions(dependency)
+	if err != nil {
+		return "", err
+	}
+	if len(published) == 0 {
+		return "", fmt.Errorf("no published version of %q found", dependency.PackageName)
+	}
+	SortBySemanticVersion(published)
+	if newest := published[len(published)-1]; isSemanticVersion(newest.Version) {
+		return newest.Version, nil
+	}
+	SortByUpdated(published)
+	return published[len(published)-1].Version, nil
+}
+
+func isSemanticVersion(version string) bool {
+	_, ok := contracts.ParseSemanticVersion(version)
+	return ok
+}
+
+func describeVersions(versions []string) string {
+	if len(versions) == 0 {
+		return "none"
+	}
+	return strings.Join(versions, ", ")
+}
+
+// SortBySemanticVersion orders versions by semantic version precedence (lowest first);
+// versions that cannot be parsed as semantic versions come first, in lexical order.
+func SortBySemanticVersion(versions []PublishedVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		left, leftOK := contracts.ParseSemanticVersion(versions[i].Version)
+		right, rightOK := contracts.ParseSemanticVersion(versions[j].Version)
+		switch {
+		case leftOK && rightOK:
+			if comparison := left.Compare(right); comparison != 0 {
+				return comparison < 0
+			}
+			return versions[i].Version < versions[j].Version
+		case leftOK != rightOK:
+			return rightOK
+		default:
+			return versions[i].Version < versions[j].Version
+		}
+	})
+}
+
+// SortByUpdated orders versions by the time they were published (oldest first).
+func SortByUpdated(versions []PublishedVersion) {
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Updated.Before(versions[j].Updated) })
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestPackageCatalogFixture(t *testing.T) {
+	gunit.Run(new(PackageCatalogFixture), t)
+}
+
+type PackageCatalogFixture struct {
+	*gunit.Fixture
+
+	lister     *FakeLister
+	catalog    *PackageCatalog
+	dependency contracts.Dependency
+}
+
+func (this *PackageCatalogFixture) Setup() {
+	this.lister = &FakeLister{objects: []contracts.RemoteObject{
+		{Path: "/prefix/package/manifest.json"},
+		{Path: "/prefix/package/1.4.0/archive", Size: 140},
+		{Path: "/prefix/package/1.4.0/manifest.json", Updated: time.Unix(140, 0)},
+		{Path: "/prefix/package/1.10.1/archive", Size: 1101},
+		{Path: "/prefix/package/1.10.1/manifest.json", Updated: time.Unix(1101, 0)},
+		{Path: "/prefix/package/2.0.0/archive", Size: 200}, // upload in progress (no manifest yet)
+		{Path: "/prefix/package/nested/1.0.0/manifest.json"},
+	}}
+	this.catalog = NewPackageCatalog(this.lister)
+	this.dependency = contracts.Dependency{
+		PackageName:    "package",
+		PackageVersion: "^1",
+		RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"},
+	}
+}
+
+func (this *PackageCatalogFixture) TestVersionsWithManifestAreListed() {
+	versions, err := this.catalog.ListVersions(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.lister.prefix, should.Resemble, url.URL{Scheme: "gcs", Host: "bucket", Path: "/prefix/package/"})
+	this.So(versions, should.Resemble, []PublishedVersion{
+		{Version: "1.10.1", ArchiveSize: 1101, Updated: time.Unix(1101, 0)},
+		{Version: "1.4.0", ArchiveSize: 140, Updated: time.Unix(140, 0)},
+	})
+}
+
+func (this *PackageCatalogFixture) TestHighestMatchingVersionIsResolved() {
+	version, err := this.catalog.ResolveConstraint(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1.10.1")
+}
+
+func (this *PackageCatalogFixture) TestLatestIsTheHighestSemanticVersion() {
+	version, err := this.catalog.ResolveLatest(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1.10.1")
+}
+
+func (this *PackageCatalogFixture) TestLatestOfNonSemanticVersionsIsTheMostRecentlyPublished() {
+	this.lister.objects = []contracts.RemoteObject{
+		{Path: "/prefix/package/build-b/manifest.json", Updated: time.Unix(300, 0)},
+		{Path: "/prefix/package/build-a/manifest.json", Updated: time.Unix(200, 0)},
+	}
+
+	version, err := this.catalog.ResolveLatest(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "build-b")
+}
+
+func (this *PackageCatalogFixture) TestLatestWithoutPublishedVersionsIsAnError() {
+	this.lister.objects = nil
+
+	version, err := this.catalog.ResolveLatest(this.dependency)
+
+	this.So(err, should.NotBeNil)
+	this.So(version, should.BeEmpty)
+}
+
+func (this *PackageCatalogFixture) TestNoMatchingVersionIsAnError() {
+	this.dependency.PackageVersion = "^2"
+
+	version, err := this.catalog.ResolveConstraint(this.dependency)
+
+	this.So(version, should.BeEmpty)
+	this.So(err.Error(), should.ContainSubstring, `no published version of "package" satisfies "^2" (published versions: 1.10.1, 1.4.0)`)
+}
+
+func (this *PackageCatalogFixture) TestListingErrorIsReturned() {
+	this.lister.err = errors.New("forbidden")
+
+	_, err := this.catalog.ResolveConstraint(this.dependency)
+
+	this.So(errors.Is(err, this.lister.err), should.BeTrue)
+}
+
+func (this *PackageCatalogFixture) TestSortBySemanticVersion() {
+	versions := []PublishedVersion{{Version: "1.10.0"}, {Version: "nightly"}, {Version: "1.9.0"}, {Version: "1.10.0-rc.1"}, {Version: "custom"}}
+
+	SortBySemanticVersion(versions)
+
+	this.So(versions, should.Resemble, []PublishedVersion{
+		{Version: "custom"}, {Version: "nightly"}, {Version: "1.9.0"}, {Version: "1.10.0-rc.1"}, {Version: "1.10.0"},
+	})
+}
+
+func (this *PackageCatalogFixture) TestSortByUpdated() {
+	versions := []PublishedVersion{{Version: "b", Updated: time.Unix(2, 0)}, {Version: "a", Updated: time.Unix(3, 0)}, {Version: "c", Updated: time.Unix(1, 0)}}
+
+	SortByUpdated(versions)
+
+	this.So(versions[0].Version, should.Equal, "c")
+	this.So(versions[1].Version, should.Equal, "b")
+	this.So(versions[2].Version, should.Equal, "a")
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////
+
+type FakeLister struct {
+	prefix  url.URL
+	objects []contracts.RemoteObject
+	err     error
+}
+
+func (this *FakeLister) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	this.prefix = prefix
+	return this.objects, this.err
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// ResolveCollisions finds the files that more than one of the packages sharing a local directory
+// would install, according to their manifests (a manifest that is missing is skipped). A file is
+// installed by the package with the highest precedence only: the other packages list it (by its
+// exact archive path) as Overridden, which leaves their selection (as installed) unchanged. Packages with the same (highest) precedence colliding is an error.
+func ResolveCollisions(dependencies []contracts.Dependency, manifests map[int]contracts.Manifest) ([]contracts.Dependency, error) {
+	owners := make(map[string][]installedItem)
+	for i, dependency := range dependencies {
+		manifest, found := manifests[i]
+		if !found {
+			continue
+		}
+		selection, remapping := dependency.Selection(), dependency.Remapping()
+		for _, item := range manifest.Archive.Contents {
+			if !selection.Selects(item.Path) || slices.Contains(dependency.Overridden, item.Path) {
+				continue
+			}
+			if localPath, installed := remapping.ComposeLocalPath(dependency.LocalDirectory, item.Path); installed {
+				owners[localPath] = append(owners[localPath], installedItem{index: i, itemPath: item.Path})
+			}
+		}
+	}
+
+	resolved := append([]contracts.Dependency(nil), dependencies...)
+	var collisions []string
+	for _, localPath := range sortedKeys(owners) {
+		items := owners[localPath]
+		if len(items) < 2 {
+			continue
+		}
+		winner, unique := highestPrecedence(dependencies, items)
+		if !unique {
+			collisions = append(collisions, describeCollision(dependencies, localPath, items))
+			continue
+		}
+		for _, item := range items {
+			if item.index != winner {
+				loser := &resolved[item.index]
+				loser.Overridden = append(loser.Overridden[:len(loser.Overridden):len(loser.Overridden)], item.itemPath)
+			}
+		}
+	}
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("packages sharing a local directory would overwrite each other's files "+
+			"(set a higher precedence on the package that should install them): %s", strings.Join(collisions, "; "))
+	}
+	return resolved, nil
+}
+
+type installedItem struct {
+	index    int
+	itemPath string
+}
+
+func highestPrecedence(dependencies []contracts.Dependency, items []installedItem) (winner int, unique bool) {
+	winner = items[0].index
+	unique = true
+	for _, item := range items[1:] {
+		switch precedence := dependencies[item.index].Precedence; {
+		case precedence > dependencies[winner].Precedence:
+			winner, unique = item.index, true
+		case precedence == dependencies[winner].Precedence:
+			unique = false
+		}
+	}
+	return winner, unique
+}
+
+func describeCollision(dependencies []contracts.Dependency, localPath string, items []installedItem) string {
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, dependencies[item.index].Title())
+	}
+	return fmt.Sprintf("%q is in %s", localPath, strings.Join(titles, " and "))
+}
+
+func sortedKeys(owners map[string][]installedItem) (keys []string) {
+	for key := range owners {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
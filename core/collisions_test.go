@@ -0,0 +1,108 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestCollisionsFixture(t *testing.T) {
+	gunit.Run(new(CollisionsFixture), t)
+}
+
+type CollisionsFixture struct {
+	*gunit.Fixture
+
+	dependencies []contracts.Dependency
+	manifests    map[int]contracts.Manifest
+}
+
+func (this *CollisionsFixture) Setup() {
+	this.dependencies = []contracts.Dependency{
+		{PackageName: "base", PackageVersion: "1", LocalDirectory: "/local"},
+		{PackageName: "overlay", PackageVersion: "2", LocalDirectory: "/local"},
+	}
+	this.manifests = map[int]contracts.Manifest{
+		0: manifestWithItems("bin/tool", "config.json", "docs/[draft].md"),
+		1: manifestWithItems("config.json", "docs/[draft].md", "extra"),
+	}
+}
+
+func manifestWithItems(paths ...string) (manifest contracts.Manifest) {
+	for _, path := range paths {
+		manifest.Archive.Contents = append(manifest.Archive.Contents, contracts.ArchiveItem{Path: path})
+	}
+	return manifest
+}
+
+func (this *CollisionsFixture) TestDisjointPackagesAreUnchanged() {
+	this.manifests[1] = manifestWithItems("extra")
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.Resemble, this.dependencies)
+}
+
+func (this *CollisionsFixture) TestCollisionWithoutPrecedenceIsAnError() {
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(resolved, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, `"/local/config.json" is in [base @ 1] and [overlay @ 2]`)
+	this.So(err.Error(), should.ContainSubstring, `"/local/docs/[draft].md"`)
+	this.So(err.Error(), should.ContainSubstring, "precedence")
+}
+
+func (this *CollisionsFixture) TestHigherPrecedenceWinsAndOtherPackageLeavesTheExactFilesToIt() {
+	this.dependencies[1].Precedence = 1
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved[0].Overridden, should.Resemble, []string{"config.json", "docs/[draft].md"})
+	this.So(resolved[1].Overridden, should.BeNil)
+	this.So(resolved[0].Selection().IsEmpty(), should.BeTrue)
+	this.So(this.dependencies[0].Overridden, should.BeNil)
+}
+
+func (this *CollisionsFixture) TestCollidingFileDoesNotOverrideTheContentsOfADirectoryOfTheSameName() {
+	this.manifests[0] = manifestWithItems("docs", "docs/guide.md")
+	this.manifests[1] = manifestWithItems("docs")
+	this.dependencies[1].Precedence = 1
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved[0].Overridden, should.Resemble, []string{"docs"})
+}
+
+func (this *CollisionsFixture) TestOverriddenItemsAreTakenIntoAccount() {
+	this.dependencies[0].Overridden = []string{"config.json", "docs/[draft].md"}
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.Resemble, this.dependencies)
+}
+
+func (this *CollisionsFixture) TestExistingSelectionAndRemappingAreTakenIntoAccount() {
+	this.dependencies[0].Exclude = []string{"config.json"}
+	this.dependencies[1].PathMappings = []contracts.PathMapping{{Source: "docs", Target: "other-docs"}}
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.Resemble, this.dependencies)
+}
+
+func (this *CollisionsFixture) TestPackagesWithoutManifestAreSkipped() {
+	delete(this.manifests, 1)
+
+	resolved, err := ResolveCollisions(this.dependencies, this.manifests)
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.Resemble, this.dependencies)
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/smarty/gcs"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// CredentialSource names one of the places the credential chain looks for credentials.
+type CredentialSource string
+
+const (
+	CredentialsFromListing     CredentialSource = "listing"      // the access token (or key file) given as credentials in the dependency listing
+	CredentialsFromAccessToken CredentialSource = "access-token" // an access token in GOOGLE_OAUTH_ACCESS_TOKEN
+	CredentialsFromEnvironment CredentialSource = "environment"  // a base64-encoded key in GOOGLE_CREDENTIALS
+	CredentialsFromFile        CredentialSource = "file"         // a key file named by GOOGLE_APPLICATION_CREDENTIALS
+	CredentialsFromVault       CredentialSource = "vault"        // an access token from Vault (VAULT_ADDR, VAULT_TOKEN and VAULT_KEY)
+)
+
+// CredentialChainVariable names the environment variable listing the sources of the chain (comma-separated, in order).
+const CredentialChainVariable = "SATISFY_CREDENTIAL_CHAIN"
+
+var DefaultCredentialChain = []CredentialSource{
+	CredentialsFromListing,
+	CredentialsFromAccessToken,
+	CredentialsFromEnvironment,
+	CredentialsFromFile,
+	CredentialsFromVault,
+}
+
+// CredentialChain reads the credentials from the first of its sources that is configured (e.g. the environment
+// variable is set) and reports which one that is. A source that is configured but fails is an error; the
+// chain doesn't move on to the next source. CredentialChain implements gcs.CredentialsReader.
+type CredentialChain struct {
+	environment contracts.Environment
+	files       contracts.FileReader
+}
+
+func NewCredentialChain(environment contracts.Environment, files contracts.FileReader) *CredentialChain {
+	return &CredentialChain{environment: environment, files: files}
+}
+
+// Sources returns the sources of the chain, in order: those named by CredentialChainVariable or else the DefaultCredentialChain.
+func (this *CredentialChain) Sources() ([]CredentialSource, error) {
+	value, found := this.environment.LookupEnv(CredentialChainVariable)
+	if !found || strings.TrimSpace(value) == "" {
+		return DefaultCredentialChain, nil
+	}
+	var sources []CredentialSource
+	for _, name := range strings.Split(value, ",") {
+		source := CredentialSource(strings.TrimSpace(name))
+		if !isCredentialSource(source) {
+			return nil, fmt.Errorf("%s names an unknown credential source: %q (known sources: %s)",
+				CredentialChainVariable, source, describeCredentialSources(DefaultCredentialChain))
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// Read uses the listing credentials (an access token or the name of a key file, possibly "") for the CredentialsFromListing source.
+func (this *CredentialChain) Read(ctx context.Context, listing string) (gcs.Credentials, error) {
+	sources, err := this.Sources()
+	if err != nil {
+		return gcs.Credentials{}, err
+	}
+	for _, source := range sources {
+		if _, configured := this.describe(source, listing); configured {
+			return this.ReadSource(ctx, source, listing)
+		}
+	}
+	return gcs.Credentials{}, fmt.Errorf("%w (looked for: %s)", gcs.ErrCredentialsFailure, describeCredentialSources(sources))
+}
+
+// ReadSource reads the credentials from the source alone, whether or not it is part of the chain; e.g. the
+// credentials of a dependency (an access token or the name of a key file) are read as CredentialsFromListing.
+func (this *CredentialChain) ReadSource(ctx context.Context, source CredentialSource, listing string) (gcs.Credentials, error) {
+	description, configured := this.describe(source, listing)
+	if !configured {
+		return gcs.Credentials{}, fmt.Errorf("%w (looked for: %s)", gcs.ErrCredentialsFailure, source)
+	}
+	credentials, err := this.read(ctx, source, listing)
+	if err != nil {
+		return gcs.Credentials{}, fmt.Errorf("could not load Google credentials from %s: %w", description, err)
+	}
+	log.Printf("Using Google credentials from %s", description)
+	return credentials, nil
+}
+
+func (this *CredentialChain) describe(source CredentialSource, listing string) (description string, configured bool) {
+	switch source {
+	case CredentialsFromListing:
+		if this.isKeyFile(listing) {
+			return fmt.Sprintf("the dependency listing (%s)", listing), true
+		}
+		return "the dependency listing", strings.TrimSpace(listing) != ""
+	case CredentialsFromAccessToken:
+		return this.describeVariable("GOOGLE_OAUTH_ACCESS_TOKEN", false)
+	case CredentialsFromEnvironment:
+		return this.describeVariable("GOOGLE_CREDENTIALS", false)
+	case CredentialsFromFile:
+		return this.describeVariable("GOOGLE_APPLICATION_CREDENTIALS", true)
+	case CredentialsFromVault:
+		address, _ := this.environment.LookupEnv("VAULT_ADDR")
+		token, _ := this.environment.LookupEnv("VAULT_TOKEN")
+		key, _ := this.environment.LookupEnv("VAULT_KEY")
+		return fmt.Sprintf("Vault (%s at %s)", key, address), address != "" && token != "" && key != ""
+	}
+	return "", false
+}
+
+func (this *CredentialChain) describeVariable(name string, showValue bool) (string, bool) {
+	value, found := this.environment.LookupEnv(name)
+	if showValue {
+		return fmt.Sprintf("%s (%s)", name, value), found
+	}
+	return name, found
+}
+
+// read has the gcs.CredentialsReader consider nothing but the source.
+func (this *CredentialChain) read(ctx context.Context, source CredentialSource, listing string) (gcs.Credentials, error) {
+	var environment restrictedEnvironment
+	var address, token, key, value string
+	switch source {
+	case CredentialsFromListing:
+		if this.isKeyFile(listing) {
+			environment = restrictedEnvironment{name: "GOOGLE_APPLICATION_CREDENTIALS", value: listing, found: true}
+		} else {
+			value = listing
+		}
+	case CredentialsFromAccessToken:
+		environment = this.restrict("GOOGLE_OAUTH_ACCESS_TOKEN")
+	case CredentialsFromEnvironment:
+		environment = this.restrict("GOOGLE_CREDENTIALS")
+	case CredentialsFromFile:
+		environment = this.restrict("GOOGLE_APPLICATION_CREDENTIALS")
+	case CredentialsFromVault:
+		address, _ = this.environment.LookupEnv("VAULT_ADDR")
+		token, _ = this.environment.LookupEnv("VAULT_TOKEN")
+		key, _ = this.environment.LookupEnv("VAULT_KEY")
+	}
+	reader := gcs.NewCredentialsReader(
+		gcs.CredentialOptions.FileReader(this.files),
+		gcs.CredentialOptions.EnvironmentReader(environment),
+		gcs.CredentialOptions.VaultServer(address, token),
+		gcs.CredentialOptions.VaultKey(key))
+	return reader.Read(ctx, value)
+}
+
+// isKeyFile tells the listing credentials naming a service account key file from an access token:
+// only a file that parses as a key counts (an access token could well be the name of some file).
+func (this *CredentialChain) isKeyFile(listing string) bool {
+	if strings.TrimSpace(listing) == "" {
+		return false
+	}
+	raw, err := this.files.ReadFile(listing)
+	if err != nil {
+		return false
+	}
+	_, err = gcs.ParseCredentialsFromJSON(raw)
+	return err == nil
+}
+
+func (this *CredentialChain) restrict(name string) restrictedEnvironment {
+	value, found := this.environment.LookupEnv(name)
+	return restrictedEnvironment{name: name, value: value, found: found}
+}
+
+// restrictedEnvironment holds (at most) one environment variable.
+type restrictedEnvironment struct {
+	name  string
+	value string
+	found bool
+}
+
+func (this restrictedEnvironment) LookupEnv(name string) (string, bool) {
+	if name != this.name {
+		return "", false
+	}
+	return this.value, this.found
+}
+
+func isCredentialSource(source CredentialSource) bool {
+	for _, known := range DefaultCredentialChain {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+func describeCredentialSources(sources []CredentialSource) string {
+	var names []string
+	for _, source := range sources {
+		names = append(names, string(source))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gcs"
+	"github.com/smarty/gunit"
+)
+
+func TestCredentialChainFixture(t *testing.T) {
+	gunit.Run(new(CredentialChainFixture), t)
+}
+
+type CredentialChainFixture struct {
+	*gunit.Fixture
+
+	environment FakeEnvironment
+	files       *inMemoryFileSystem
+	chain       *CredentialChain
+}
+
+func (this *CredentialChainFixture) Setup() {
+	this.environment = make(FakeEnvironment)
+	this.files = newInMemoryFileSystem()
+	this.files.WriteFile("/keys/google.json", []byte(googleCredentialsJSON))
+	this.chain = NewCredentialChain(this.environment, this.files)
+}
+
+func (this *CredentialChainFixture) read(listing string) (gcs.Credentials, error) {
+	return this.chain.Read(context.Background(), listing)
+}
+
+func (this *CredentialChainFixture) TestListingAccessTokenComesFirstByDefault() {
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+
+	credentials, err := this.read("listing-token")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, gcs.Credentials{BearerToken: "Bearer listing-token"})
+}
+
+func (this *CredentialChainFixture) TestListingMayNameAKeyFile() {
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+
+	credentials, err := this.read("/keys/google.json")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, parsedGoogleCredentials)
+}
+
+func (this *CredentialChainFixture) TestListingNamingAFileOtherThanAKeyIsAnAccessToken() {
+	this.files.WriteFile("token", []byte("not a key"))
+
+	credentials, err := this.read("token")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, gcs.Credentials{BearerToken: "Bearer token"})
+}
+
+func (this *CredentialChainFixture) TestFirstConfiguredSourceSuppliesTheCredentials() {
+	this.environment["GOOGLE_APPLICATION_CREDENTIALS"] = "/keys/google.json"
+	this.environment["GOOGLE_CREDENTIALS"] = base64.StdEncoding.EncodeToString([]byte(googleCredentialsJSON))
+
+	credentials, err := this.read("")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, parsedGoogleCredentials)
+}
+
+func (this *CredentialChainFixture) TestSourcesAreOrderedByTheEnvironment() {
+	this.environment[CredentialChainVariable] = "file, access-token"
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+	this.environment["GOOGLE_APPLICATION_CREDENTIALS"] = "/keys/google.json"
+
+	credentials, err := this.read("listing-token")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, parsedGoogleCredentials)
+}
+
+func (this *CredentialChainFixture) TestSourcesLeftOutOfTheChainAreIgnored() {
+	this.environment[CredentialChainVariable] = "file"
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+
+	_, err := this.read("listing-token")
+
+	this.So(errors.Is(err, gcs.ErrCredentialsFailure), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "(looked for: file)")
+}
+
+func (this *CredentialChainFixture) TestUnknownSourceIsAnError() {
+	this.environment[CredentialChainVariable] = "file,metadata-server"
+
+	_, err := this.read("")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, `unknown credential source: "metadata-server"`)
+}
+
+func (this *CredentialChainFixture) TestFailingSourceIsAnErrorRatherThanSkipped() {
+	this.environment["GOOGLE_CREDENTIALS"] = "not base64!"
+	this.environment["GOOGLE_APPLICATION_CREDENTIALS"] = "/keys/google.json"
+
+	_, err := this.read("")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, "could not load Google credentials from GOOGLE_CREDENTIALS:")
+}
+
+func (this *CredentialChainFixture) TestMissingCredentialsFileIsAnError() {
+	this.environment["GOOGLE_APPLICATION_CREDENTIALS"] = "/keys/missing.json"
+
+	_, err := this.read("")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "GOOGLE_APPLICATION_CREDENTIALS (/keys/missing.json)")
+}
+
+func (this *CredentialChainFixture) TestIncompleteVaultConfigurationIsNotConfigured() {
+	this.environment["VAULT_ADDR"] = "https://vault:8200"
+	this.environment["VAULT_TOKEN"] = "token"
+
+	_, err := this.read("")
+
+	this.So(errors.Is(err, gcs.ErrCredentialsFailure), should.BeTrue)
+}
+
+func (this *CredentialChainFixture) TestSourceLeftOutOfTheChainMayBeReadAlone() {
+	this.environment[CredentialChainVariable] = "access-token"
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+
+	credentials, err := this.chain.ReadSource(context.Background(), CredentialsFromListing, "/keys/google.json")
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, parsedGoogleCredentials)
+}
+
+func (this *CredentialChainFixture) TestSourceReadAloneMustBeConfigured() {
+	this.environment["GOOGLE_OAUTH_ACCESS_TOKEN"] = "environment-token"
+
+	_, err := this.chain.ReadSource(context.Background(), CredentialsFromListing, "")
+
+	this.So(errors.Is(err, gcs.ErrCredentialsFailure), should.BeTrue)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// RemoteAccess names the credentials used to access remote storage ("" names the default credentials)
+// or, when Anonymous, calls for unsigned requests.
+type RemoteAccess struct {
+	Credentials string
+	Anonymous   bool
+}
+
+// RemoteStorageFactory creates the remote storage with the access.
+type RemoteStorageFactory func(access RemoteAccess) (contracts.RemoteStorage, error)
+
+// CredentialRouter passes each request along to the remote storage using the credentials assigned
+// to the address: those of the dependency the address belongs to (possibly anonymous access), or
+// else those of the first matching host pattern, or else the default credentials. The remote storage
+// for each credentials is created when first needed, so credentials that aren't needed are never loaded.
+type CredentialRouter struct {
+	factory  RemoteStorageFactory
+	packages []packageCredentials
+	hosts    []contracts.RemoteCredentials
+
+	mutex    sync.Mutex
+	storages map[RemoteAccess]contracts.RemoteStorage
+	failures map[RemoteAccess]error
+}
+
+type packageCredentials struct {
+	prefix url.URL
+	access RemoteAccess
+}
+
+func NewCredentialRouter(listing contracts.DependencyListing, factory RemoteStorageFactory) *CredentialRouter {
+	router := &CredentialRouter{
+		factory:  factory,
+		hosts:    listing.RemoteCredentials,
+		storages: make(map[RemoteAccess]contracts.RemoteStorage),
+		failures: make(map[RemoteAccess]error),
+	}
+	for _, dependency := range listing.Listing {
+		if dependency.Credentials != "" || dependency.Anonymous {
+			router.packages = append(router.packages, packageCredentials{
+				prefix: dependency.ComposeRemotePackagePrefix(),
+				access: RemoteAccess{Credentials: dependency.Credentials, Anonymous: dependency.Anonymous},
+			})
+		}
+	}
+	return router
+}
+
+func (this *CredentialRouter) Upload(request contracts.UploadRequest) error {
+	storage, err := this.route(request.RemoteAddress)
+	if err != nil {
+		return err
+	}
+	return storage.Upload(request)
+}
+
+func (this *CredentialRouter) Download(request url.URL) (io.ReadCloser, error) {
+	storage, err := this.route(request)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Download(request)
+}
+
+func (this *CredentialRouter) Seek(request url.URL, start, end int64) (io.ReadCloser, error) {
+	storage, err := this.route(request)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Seek(request, start, end)
+}
+
+func (this *CredentialRouter) Size(request url.URL) (int64, error) {
+	storage, err := this.route(request)
+	if err != nil {
+		return 0, err
+	}
+	return storage.Size(request)
+}
+
+func (this *CredentialRouter) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	storage, err := this.route(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return storage.List(prefix)
+}
+
+func (this *CredentialRouter) route(address url.URL) (contracts.RemoteStorage, error) {
+	access := this.access(address)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if storage, found := this.storages[access]; found {
+		return storage, nil
+	}
+	if err, found := this.failures[access]; found {
+		return nil, err
+	}
+	storage, err := this.factory(access)
+	if err != nil {
+		this.failures[access] = err
+		return nil, err
+	}
+	this.storages[access] = storage
+	return storage, nil
+}
+
+// access prefers the dependency with the longest matching package prefix.
+func (this *CredentialRouter) access(address url.URL) RemoteAccess {
+	access, longest := RemoteAccess{}, -1
+	for _, item := range this.packages {
+		if item.prefix.Host == address.Host && strings.HasPrefix(address.Path, item.prefix.Path) && len(item.prefix.Path) > longest {
+			access, longest = item.access, len(item.prefix.Path)
+		}
+	}
+	if longest >= 0 {
+		return access
+	}
+	for _, item := range this.hosts {
+		if matched, _ := path.Match(item.Host, address.Host); matched {
+			return RemoteAccess{Credentials: item.Credentials}
+		}
+	}
+	return RemoteAccess{}
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestCredentialRouterFixture(t *testing.T) {
+	gunit.Run(new(CredentialRouterFixture), t)
+}
+
+type CredentialRouterFixture struct {
+	*gunit.Fixture
+
+	listing  contracts.DependencyListing
+	created  []RemoteAccess
+	failing  map[string]error
+	router   *CredentialRouter
+	requests map[string][]string
+}
+
+func (this *CredentialRouterFixture) Setup() {
+	this.failing = make(map[string]error)
+	this.requests = make(map[string][]string)
+	this.listing = contracts.DependencyListing{
+		Listing: []contracts.Dependency{
+			{PackageName: "shared", RemoteAddress: contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"}},
+			{PackageName: "private", RemoteAddress: contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"}, Credentials: "private.json"},
+		},
+		RemoteCredentials: []contracts.RemoteCredentials{
+			{Host: "project-a-*", Credentials: "project-a.json"},
+			{Host: "*", Credentials: "fallback.json"},
+		},
+	}
+}
+
+func (this *CredentialRouterFixture) newRouter() {
+	this.router = NewCredentialRouter(this.listing, func(access RemoteAccess) (contracts.RemoteStorage, error) {
+		this.created = append(this.created, access)
+		if err := this.failing[access.Credentials]; err != nil {
+			return nil, err
+		}
+		credentials := access.Credentials
+		if access.Anonymous {
+			credentials = "(anonymous)"
+		}
+		return &FakeRoutedStorage{credentials: credentials, requests: this.requests}, nil
+	})
+}
+
+func (this *CredentialRouterFixture) download(address string) error {
+	parsed, _ := url.Parse(address)
+	_, err := this.router.Download(*parsed)
+	return err
+}
+
+func (this *CredentialRouterFixture) TestDependencyCredentialsApplyToItsPackageOnly() {
+	this.newRouter()
+
+	this.So(this.download("gcs://bucket/packages/private/1.0.0/manifest.json"), should.BeNil)
+	this.So(this.download("gcs://bucket/packages/private-other/1.0.0/manifest.json"), should.BeNil)
+
+	this.So(this.requests["private.json"], should.Resemble, []string{"/packages/private/1.0.0/manifest.json"})
+	this.So(this.requests["fallback.json"], should.Resemble, []string{"/packages/private-other/1.0.0/manifest.json"})
+}
+
+func (this *CredentialRouterFixture) TestFirstMatchingHostPatternApplies() {
+	this.newRouter()
+
+	this.So(this.download("gcs://project-a-data/packages/shared/1.0.0/archive"), should.BeNil)
+
+	this.So(this.requests["project-a.json"], should.HaveLength, 1)
+}
+
+func (this *CredentialRouterFixture) TestDefaultCredentialsApplyWithoutMatch() {
+	this.listing.RemoteCredentials = nil
+	this.newRouter()
+
+	this.So(this.download("gcs://bucket/packages/shared/1.0.0/archive"), should.BeNil)
+
+	this.So(this.requests[""], should.HaveLength, 1)
+}
+
+func (this *CredentialRouterFixture) TestEachStorageIsCreatedOnceWhenFirstNeeded() {
+	this.newRouter()
+	this.So(this.created, should.BeEmpty)
+
+	_ = this.download("gcs://bucket/packages/private/1.0.0/manifest.json")
+	_ = this.download("gcs://bucket/packages/private/1.0.0/archive")
+	_, _ = this.router.List(url.URL{Scheme: "gcs", Host: "bucket", Path: "/packages/private/"})
+
+	this.So(this.created, should.Resemble, []RemoteAccess{{Credentials: "private.json"}})
+	this.So(this.requests["private.json"], should.HaveLength, 3)
+}
+
+func (this *CredentialRouterFixture) TestFailureToCreateStorageOnlyFailsItsRequests() {
+	this.failing["private.json"] = errors.New("no such file")
+	this.newRouter()
+
+	this.So(this.download("gcs://bucket/packages/private/1.0.0/manifest.json"), should.Resemble, this.failing["private.json"])
+	this.So(this.download("gcs://bucket/packages/private/1.0.0/archive"), should.Resemble, this.failing["private.json"])
+	this.So(this.download("gcs://bucket/packages/shared/1.0.0/archive"), should.BeNil)
+
+	this.So(this.created, should.Resemble, []RemoteAccess{{Credentials: "private.json"}, {Credentials: "fallback.json"}})
+}
+
+func (this *CredentialRouterFixture) TestAnonymousDependencyIsAccessedWithoutCredentials() {
+	this.listing.Listing = append(this.listing.Listing, contracts.Dependency{
+		PackageName:   "public",
+		RemoteAddress: contracts.URL{Scheme: "gcs", Host: "project-a-public", Path: "/packages"},
+		Anonymous:     true,
+	})
+	this.newRouter()
+
+	this.So(this.download("gcs://project-a-public/packages/public/1.0.0/archive"), should.BeNil)
+	this.So(this.download("gcs://project-a-public/packages/other/1.0.0/archive"), should.BeNil)
+
+	this.So(this.requests["(anonymous)"], should.Resemble, []string{"/packages/public/1.0.0/archive"})
+	this.So(this.requests["project-a.json"], should.Resemble, []string{"/packages/other/1.0.0/archive"})
+}
+
+//////////////////////////////////////////////////////////
+
+type FakeRoutedStorage struct {
+	credentials string
+	requests    map[string][]string
+}
+
+func (this *FakeRoutedStorage) record(address url.URL) {
+	this.requests[this.credentials] = append(this.requests[this.credentials], address.Path)
+}
+
+func (this *FakeRoutedStorage) Upload(request contracts.UploadRequest) error {
+	this.record(request.RemoteAddress)
+	return nil
+}
+func (this *FakeRoutedStorage) Download(address url.URL) (io.ReadCloser, error) {
+	this.record(address)
+	return io.NopCloser(nil), nil
+}
+func (this *FakeRoutedStorage) Seek(address url.URL, _, _ int64) (io.ReadCloser, error) {
+	this.record(address)
+	return io.NopCloser(nil), nil
+}
+func (this *FakeRoutedStorage) Size(address url.URL) (int64, error) {
+	this.record(address)
+	return 0, nil
+}
+func (this *FakeRoutedStorage) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	this.record(prefix)
+	return nil, nil
+}
@@ -0,0 +1,239 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// ManifestLoader loads the manifest of the version of a dependency that would be installed.
+type ManifestLoader interface {
+	LoadManifest(dependency contracts.Dependency) (contracts.Manifest, error)
+}
+
+// RequiredDependency is a dependency of the full graph and the packages that declared it.
+type RequiredDependency struct {
+	contracts.Dependency
+
+	// Listed reports whether the dependency is part of the listing (rather than only pulled in).
+	Listed bool
+
+	// RequiredBy names the packages whose manifests declare the dependency.
+	RequiredBy []string
+}
+
+type DependencyGraph struct {
+	manifests ManifestLoader
+}
+
+func NewDependencyGraph(manifests ManifestLoader) *DependencyGraph {
+	return &DependencyGraph{manifests: manifests}
+}
+
+// Resolve adds the packages the listed packages require (transitively) to the listing.
+// A required package that is listed (by name) is installed as listed, provided the listed version
+// satisfies the requirement; otherwise it is installed where the requiring package declares (next to it).
+// A package listed (or required) in several local directories is matched on the declared location;
+// when that doesn't single out one of them the requirement is reported as ambiguous.
+// Manifests that can't be loaded are skipped (with a warning): the problem surfaces during installation,
+// whereas the packages they would require are neither installed nor inspected.
+func (this *DependencyGraph) Resolve(listing []contracts.Dependency) ([]RequiredDependency, error) {
+	graph := &dependencyGraph{indexes: make(map[string][]int), versions: make(map[int]string)}
+	for _, dependency := range listing {
+		graph.add(RequiredDependency{Dependency: dependency, Listed: true})
+	}
+	var problems []string
+	for current := 0; current < len(graph.nodes); current++ {
+		dependent := graph.nodes[current]
+		manifest, err := this.manifests.LoadManifest(dependent.Dependency)
+		if err != nil {
+			log.Printf("[WARN] The packages required by %s are unknown, its manifest could not be loaded: %s", dependent.Title(), err)
+			continue
+		}
+		graph.versions[current] = manifest.Version
+		title := fmt.Sprintf("[%s @ %s]", dependent.PackageName, manifest.Version)
+		for _, declared := range manifest.Dependencies {
+			if err = declared.Validate(); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", title, err))
+				continue
+			}
+			candidate := requiredBy(dependent.Dependency, declared)
+			index, problem := graph.find(candidate, declared.LocalDirectory != "")
+			if problem != "" {
+				problems = append(problems, fmt.Sprintf("%s requires %q but %s", title, declared.PackageName, problem))
+				continue
+			}
+			if index < 0 {
+				index = graph.add(RequiredDependency{Dependency: candidate})
+			}
+			graph.nodes[index].RequiredBy = append(graph.nodes[index].RequiredBy, title)
+			graph.edges = append(graph.edges, requirement{from: current, to: index, version: declared.PackageVersion, by: title})
+		}
+	}
+	if cycle := graph.findCycle(); len(cycle) > 0 {
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	problems = append(problems, graph.conflicts()...)
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return graph.nodes, nil
+}
+
+// requiredBy composes the dependency declared in the manifest of the dependent package: it is
+// installed next to the dependent package and, unless declared otherwise, published alongside it.
+func requiredBy(dependent contracts.Dependency, declared contracts.ManifestDependency) contracts.Dependency {
+	dependency := contracts.Dependency{
+		PackageName:    declared.PackageName,
+		PackageVersion: declared.PackageVersion,
+		RemoteAddress:  declared.RemoteAddress,
+		LocalDirectory: filepath.Join(filepath.Dir(dependent.LocalDirectory), declared.LocalDirectory),
+	}
+	if dependency.RemoteAddress.Value().String() == "" {
+		dependency.RemoteAddress = dependent.RemoteAddress
+	}
+	return dependency
+}
+
+type dependencyGraph struct {
+	nodes    []RequiredDependency
+	indexes  map[string][]int // package name -> indexes of the nodes of the package
+	versions map[int]string   // index -> version (as loaded)
+	edges    []requirement
+}
+
+type requirement struct {
+	from    int
+	to      int
+	version string
+	by      string
+}
+
+func (this *dependencyGraph) add(dependency RequiredDependency) int {
+	index := len(this.nodes)
+	this.nodes = append(this.nodes, dependency)
+	this.indexes[dependency.PackageName] = append(this.indexes[dependency.PackageName], index)
+	return index
+}
+
+// find returns the node that satisfies the requirement for the candidate (-1 if it is to be added):
+// the node at the declared location, if any, or else the one node listed (or, without a declared
+// location, the one node required) under that name. Otherwise it describes the problem.
+func (this *dependencyGraph) find(candidate contracts.Dependency, located bool) (int, string) {
+	var listed, required []int
+	for _, index := range this.indexes[candidate.PackageName] {
+		node := this.nodes[index]
+		switch {
+		case located && node.LocalDirectory == candidate.LocalDirectory:
+			return index, ""
+		case node.Listed:
+			listed = append(listed, index)
+		default:
+			required = append(required, index)
+		}
+	}
+	if len(listed) == 0 && !located {
+		listed = required
+	}
+	switch {
+	case len(listed) == 1:
+		return listed[0], ""
+	case len(listed) > 1:
+		return -1, fmt.Sprintf("it is installed in several local directories (%s); declare which one", this.describeDirectories(listed))
+	case !located:
+		return -1, "doesn't declare where to install it (list it to choose a local directory)"
+	default:
+		return -1, ""
+	}
+}
+
+func (this *dependencyGraph) describeDirectories(indexes []int) string {
+	directories := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		directories = append(directories, this.nodes[index].LocalDirectory)
+	}
+	return strings.Join(directories, ", ")
+}
+
+func (this *dependencyGraph) conflicts() (conflicts []string) {
+	for _, edge := range this.edges {
+		version, loaded := this.versions[edge.to]
+		if !loaded || satisfiesRequirement(version, edge.version) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("version conflict: %s requires %s @ %s, but version %s is to be installed",
+			edge.by, this.nodes[edge.to].PackageName, edge.version, version))
+	}
+	return conflicts
+}
+
+func satisfiesRequirement(version, required string) bool {
+	if required == "latest" || required == version {
+		return true
+	}
+	if !contracts.IsVersionConstraint(required) {
+		return false
+	}
+	constraint, err := contracts.ParseVersionConstraint(required)
+	if err != nil {
+		return false
+	}
+	parsed, ok := contracts.ParseSemanticVersion(version)
+	return ok && constraint.Allows(parsed)
+}
+
+// findCycle returns the package names along the first cycle found (the first name repeated at the end), if any.
+func (this *dependencyGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(this.nodes))
+	var path []int
+	var visit func(int) []string
+	visit = func(node int) []string {
+		states[node] = visiting
+		path = append(path, node)
+		for _, edge := range this.edges {
+			if edge.from != node {
+				continue
+			}
+			switch states[edge.to] {
+			case visiting:
+				return this.describeCycle(path, edge.to)
+			case unvisited:
+				if cycle := visit(edge.to); len(cycle) > 0 {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		states[node] = visited
+		return nil
+	}
+	for node := range this.nodes {
+		if states[node] == unvisited {
+			if cycle := visit(node); len(cycle) > 0 {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func (this *dependencyGraph) describeCycle(path []int, start int) (names []string) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == start {
+			for _, node := range path[i:] {
+				names = append(names, this.nodes[node].PackageName)
+			}
+			break
+		}
+	}
+	return append(names, this.nodes[start].PackageName)
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestDependencyGraphFixture(t *testing.T) {
+	gunit.Run(new(DependencyGraphFixture), t)
+}
+
+type DependencyGraphFixture struct {
+	*gunit.Fixture
+
+	manifests *FakeManifestLoader
+	graph     *DependencyGraph
+	remote    contracts.URL
+}
+
+func (this *DependencyGraphFixture) Setup() {
+	this.manifests = &FakeManifestLoader{manifests: make(map[string]contracts.Manifest)}
+	this.graph = NewDependencyGraph(this.manifests)
+	this.remote = contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"}
+}
+
+func (this *DependencyGraphFixture) listed(name, version, directory string) contracts.Dependency {
+	return contracts.Dependency{PackageName: name, PackageVersion: version, RemoteAddress: this.remote, LocalDirectory: directory}
+}
+
+func (this *DependencyGraphFixture) publish(name, version string, dependencies ...contracts.ManifestDependency) {
+	this.manifests.manifests[name] = contracts.Manifest{Name: name, Version: version, Dependencies: dependencies}
+}
+
+func required(name, version, directory string) contracts.ManifestDependency {
+	return contracts.ManifestDependency{PackageName: name, PackageVersion: version, LocalDirectory: directory}
+}
+
+func (this *DependencyGraphFixture) TestListingWithoutDependenciesIsUnchanged() {
+	this.publish("app", "1.0.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.Resemble, []RequiredDependency{{Dependency: this.listed("app", "1.0.0", "/app"), Listed: true}})
+}
+
+func (this *DependencyGraphFixture) TestTransitiveDependenciesArePulledIn() {
+	this.publish("app", "1.0.0", required("lib", "^2.0", "lib"))
+	this.publish("lib", "2.3.0", required("base", "1.1.0", "base"))
+	this.publish("base", "1.1.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.HaveLength, 3)
+	this.So(resolved[1], should.Resemble, RequiredDependency{
+		Dependency: this.listed("lib", "^2.0", "/lib"),
+		RequiredBy: []string{"[app @ 1.0.0]"},
+	})
+	this.So(resolved[2], should.Resemble, RequiredDependency{
+		Dependency: this.listed("base", "1.1.0", "/base"),
+		RequiredBy: []string{"[lib @ 2.3.0]"},
+	})
+}
+
+func (this *DependencyGraphFixture) TestDeclaredRemoteAddressIsKept() {
+	elsewhere := contracts.URL{Scheme: "gcs", Host: "elsewhere", Path: "/"}
+	dependency := required("lib", "1.0.0", "lib")
+	dependency.RemoteAddress = elsewhere
+	this.publish("app", "1.0.0", dependency)
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(err, should.BeNil)
+	this.So(resolved[1].RemoteAddress, should.Resemble, elsewhere)
+}
+
+func (this *DependencyGraphFixture) TestListedPackageOverridesTheDeclaredLocation() {
+	this.publish("app", "1.0.0", required("lib", "^2.0", "declared"))
+	this.publish("other", "3.0.0", required("lib", "2.3.0", ""))
+	this.publish("lib", "2.3.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("app", "1.0.0", "/app"),
+		this.listed("other", "3.0.0", "/other"),
+		this.listed("lib", "2.3.0", "/override"),
+	})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.HaveLength, 3)
+	this.So(resolved[2], should.Resemble, RequiredDependency{
+		Dependency: this.listed("lib", "2.3.0", "/override"),
+		Listed:     true,
+		RequiredBy: []string{"[app @ 1.0.0]", "[other @ 3.0.0]"},
+	})
+}
+
+func (this *DependencyGraphFixture) TestPackageListedInSeveralDirectoriesIsMatchedOnTheDeclaredLocation() {
+	this.publish("app", "1.0.0", required("lib", "2.3.0", "lib"))
+	this.publish("lib", "2.3.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("app", "1.0.0", "/deps/app"),
+		this.listed("lib", "2.3.0", "/elsewhere/lib"),
+		this.listed("lib", "2.3.0", "/deps/lib"),
+	})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.HaveLength, 3)
+	this.So(resolved[1].RequiredBy, should.BeEmpty)
+	this.So(resolved[2].RequiredBy, should.Resemble, []string{"[app @ 1.0.0]"})
+}
+
+func (this *DependencyGraphFixture) TestPackageListedInSeveralDirectoriesIsAmbiguousWithoutTheDeclaredLocation() {
+	this.publish("app", "1.0.0", required("lib", "2.3.0", ""))
+	this.publish("lib", "2.3.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("app", "1.0.0", "/deps/app"),
+		this.listed("lib", "2.3.0", "/one/lib"),
+		this.listed("lib", "2.3.0", "/two/lib"),
+	})
+
+	this.So(resolved, should.BeNil)
+	this.So(err.Error(), should.ContainSubstring, `[app @ 1.0.0] requires "lib" but it is installed in several local directories (/one/lib, /two/lib)`)
+}
+
+func (this *DependencyGraphFixture) TestPackageRequiredInSeveralDirectoriesIsInstalledInEach() {
+	this.publish("one", "1.0.0", required("lib", "2.3.0", "lib"))
+	this.publish("two", "1.0.0", required("lib", "2.3.0", "lib"))
+	this.publish("lib", "2.3.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("one", "1.0.0", "/one/app"),
+		this.listed("two", "1.0.0", "/two/app"),
+	})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.HaveLength, 4)
+	this.So(resolved[2].LocalDirectory, should.Equal, "/one/lib")
+	this.So(resolved[3].LocalDirectory, should.Equal, "/two/lib")
+}
+
+func (this *DependencyGraphFixture) TestMissingLocationIsAnError() {
+	this.publish("app", "1.0.0", required("lib", "^2.0", ""))
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(resolved, should.BeNil)
+	this.So(err.Error(), should.ContainSubstring, `[app @ 1.0.0] requires "lib" but doesn't declare where to install it`)
+}
+
+func (this *DependencyGraphFixture) TestDeclaredLocationIsNextToTheDependent() {
+	this.publish("app", "1.0.0", required("lib", "1.0.0", "vendor/lib"))
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/opt/app")})
+
+	this.So(err, should.BeNil)
+	this.So(resolved[1].LocalDirectory, should.Equal, "/opt/vendor/lib")
+}
+
+func (this *DependencyGraphFixture) TestDeclaredLocationOutsideTheParentDirectoryIsAnError() {
+	this.publish("app", "1.0.0", required("lib", "1.0.0", "/etc"), required("base", "1.0.0", "../../etc"))
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/opt/app")})
+
+	this.So(resolved, should.BeNil)
+	this.So(err.Error(), should.ContainSubstring, `[app @ 1.0.0]: dependency "lib": local directory "/etc" must be relative`)
+	this.So(err.Error(), should.ContainSubstring, `[app @ 1.0.0]: dependency "base": local directory "../../etc" must be relative`)
+}
+
+func (this *DependencyGraphFixture) TestVersionConflictsAreReported() {
+	this.publish("app", "1.0.0", required("lib", "^2.0", "lib"))
+	this.publish("other", "3.0.0", required("lib", "~2.3", "lib"), required("base", "1.0.0", "base"))
+	this.publish("lib", "2.4.1", required("base", "1.1.0", "base"))
+	this.publish("base", "1.1.0")
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("app", "1.0.0", "/app"),
+		this.listed("other", "3.0.0", "/other"),
+	})
+
+	this.So(resolved, should.BeNil)
+	this.So(err.Error(), should.Equal,
+		"version conflict: [other @ 3.0.0] requires lib @ ~2.3, but version 2.4.1 is to be installed; "+
+			"version conflict: [other @ 3.0.0] requires base @ 1.0.0, but version 1.1.0 is to be installed")
+}
+
+func (this *DependencyGraphFixture) TestCyclesAreReported() {
+	this.publish("app", "1.0.0", required("lib", "1.0.0", "lib"))
+	this.publish("lib", "1.0.0", required("base", "1.0.0", "base"))
+	this.publish("base", "1.0.0", required("lib", "1.0.0", "lib"))
+
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(resolved, should.BeNil)
+	this.So(err, should.Resemble, errors.New("dependency cycle: lib -> base -> lib"))
+}
+
+func (this *DependencyGraphFixture) TestCyclesThroughListedPackagesAreReported() {
+	this.publish("app", "1.0.0", required("lib", "1.0.0", ""))
+	this.publish("lib", "1.0.0", required("app", "1.0.0", ""))
+
+	_, err := this.graph.Resolve([]contracts.Dependency{
+		this.listed("app", "1.0.0", "/app"),
+		this.listed("lib", "1.0.0", "/lib"),
+	})
+
+	this.So(err, should.Resemble, errors.New("dependency cycle: app -> lib -> app"))
+}
+
+func (this *DependencyGraphFixture) TestUnavailableManifestsAreSkipped() {
+	resolved, err := this.graph.Resolve([]contracts.Dependency{this.listed("app", "1.0.0", "/app")})
+
+	this.So(err, should.BeNil)
+	this.So(resolved, should.HaveLength, 1)
+}
+
+//////////////////////////////////////////////////////////
+
+type FakeManifestLoader struct {
+	manifests map[string]contracts.Manifest
+}
+
+func (this *FakeManifestLoader) LoadManifest(dependency contracts.Dependency) (contracts.Manifest, error) {
+	manifest, found := this.manifests[dependency.PackageName]
+	if !found {
+		return contracts.Manifest{}, errors.New("manifest not found")
+	}
+	return manifest, nil
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/smarty/satisfy/contracts"
 )
@@ -14,36 +13,74 @@ import (
 type DependencyResolverFileSystem interface {
 	contracts.FileChecker
 	contracts.FileReader
+	contracts.FileWriter
 	contracts.Deleter
+	VersionedLayoutFileSystem
+}
+
+type VersionCatalog interface {
+	ResolveConstraint(dependency contracts.Dependency) (string, error)
+}
+
+type Repairer interface {
+	Repair(dependency contracts.Dependency, manifest contracts.Manifest, localPath string) error
 }
 
 type DependencyResolver struct {
 	fileSystem       DependencyResolverFileSystem
 	integrityChecker contracts.IntegrityCheck
 	packageInstaller contracts.PackageInstaller
+	versionCatalog   VersionCatalog
+	repairer         Repairer
+	hooks            HookRunner
 	dependency       contracts.Dependency
+	requestedVersion string
 }
 
 func NewDependencyResolver(
 	fileSystem DependencyResolverFileSystem,
 	integrityChecker contracts.IntegrityCheck,
 	packageInstaller contracts.PackageInstaller,
+	versionCatalog VersionCatalog,
+	repairer Repairer,
+	hooks HookRunner,
 	dependency contracts.Dependency,
 ) *DependencyResolver {
 	return &DependencyResolver{
 		fileSystem:       fileSystem,
 		integrityChecker: integrityChecker,
 		packageInstaller: packageInstaller,
+		versionCatalog:   versionCatalog,
+		repairer:         repairer,
+		hooks:            hooks,
 		dependency:       dependency,
+		requestedVersion: dependency.PackageVersion,
 	}
 }
 
 func (this *DependencyResolver) Resolve() error {
 	log.Printf("Installing dependency: %s", this.dependency.Title())
 
+	if this.dependency.HasVersionConstraint() {
+		version, err := this.versionCatalog.ResolveConstraint(this.dependency)
+		if err != nil {
+			return fmt.Errorf("failed to resolve version of %s: %w", this.dependency.Title(), err)
+		}
+		log.Printf("Resolved %s to version %s", this.dependency.Title(), version)
+		this.dependency.PackageVersion = version
+	}
+
+	if this.dependency.IsVersioned() {
+		return this.resolveVersioned()
+	}
+
 	manifestPath := ComposeManifestPath(this.dependency.LocalDirectory, this.dependency.PackageName)
 	if !this.localManifestExists(manifestPath) {
-		return this.installPackage()
+		_, err := this.installPackage(this.dependency.LocalDirectory)
+		if err != nil {
+			return err
+		}
+		return this.runPostInstallHook(this.dependency.LocalDirectory)
 	}
 
 	localManifest, err := this.loadLocalManifest(manifestPath)
@@ -51,12 +88,147 @@ func (this *DependencyResolver) Resolve() error {
 		return err
 	}
 
-	if this.isInstalledCorrectly(localManifest) {
+	switch this.inspectInstallation(localManifest, this.dependency.LocalDirectory) {
+	case installationIntact:
+		return this.retryFailedPostInstallHook(this.dependency.LocalDirectory)
+	case installationRepaired:
+		return this.runPostInstallHook(this.dependency.LocalDirectory)
+	}
+
+	err = this.replacePackage(localManifest)
+	if err != nil {
+		return err
+	}
+	return this.runPostInstallHook(this.dependency.LocalDirectory)
+}
+
+// runPostInstallHook records a failure of the hook next to the local manifest (which keeps
+// accounting for the installed files), so that the hook is run once again on the next attempt.
+func (this *DependencyResolver) runPostInstallHook(localPath string) error {
+	failurePath := ComposeHookFailurePath(localPath, this.dependency.PackageName)
+	err := runHook(this.hooks, "post-install", this.dependency.PostInstall, this.dependency, localPath)
+	if err != nil {
+		this.fileSystem.WriteFile(failurePath, []byte(err.Error()))
+		return err
+	}
+	if this.hasFailedPostInstallHook(localPath) {
+		this.fileSystem.Delete(failurePath)
+	}
+	return nil
+}
+
+// retryFailedPostInstallHook runs the post-install hook of a package installed correctly only when it failed before.
+func (this *DependencyResolver) retryFailedPostInstallHook(localPath string) error {
+	if !this.hasFailedPostInstallHook(localPath) {
 		return nil
 	}
+	log.Printf("Post-install hook failed previously: %s", this.dependency.Title())
+	return this.runPostInstallHook(localPath)
+}
 
-	this.uninstallPackage(localManifest)
-	return this.installPackage()
+func (this *DependencyResolver) hasFailedPostInstallHook(localPath string) bool {
+	_, err := this.fileSystem.Stat(ComposeHookFailurePath(localPath, this.dependency.PackageName))
+	return err == nil
+}
+
+// resolveVersioned installs the specified version (or the version rolled back to, for as long as
+// the same version is requested) side-by-side with the versions already installed and, once it has
+// been verified (and its post-install hook has succeeded), makes it the current version. The hook runs only when the version was (re-)installed or repaired,
+// or when it failed before: re-activating a version that is installed correctly doesn't run it.
+func (this *DependencyResolver) resolveVersioned() error {
+	layout := NewVersionedLayout(this.fileSystem, this.dependency.LocalDirectory, this.dependency.PackageName)
+	if version := layout.RolledBack(this.requestedVersion); version != "" {
+		log.Printf("Keeping version %s of %s, rolled back to (until another version is requested)", version, this.dependency.Title())
+		this.dependency.PackageVersion = version
+	} else {
+		layout.ForgetRollback()
+	}
+	if current := layout.CurrentVersion(); current != "" {
+		switch currentPath := layout.VersionPath(current); this.inspectInstallationAt(currentPath) {
+		case installationIntact:
+			return this.retryFailedPostInstallHook(currentPath)
+		case installationRepaired:
+			return this.runPostInstallHook(currentPath)
+		}
+	}
+
+	version, err := this.resolveVersion()
+	if err != nil {
+		return err
+	}
+	if version == "" || version != filepath.Base(version) || version == "." || version == ".." {
+		return fmt.Errorf("version %q of %s cannot be installed side-by-side", version, this.dependency.Title())
+	}
+
+	versionPath := layout.VersionPath(version)
+	state := installationMissing
+	if layout.HasVersion(version) {
+		state = this.inspectInstallationAt(versionPath)
+	}
+	if state == installationMissing {
+		this.fileSystem.DeleteAll(versionPath)
+		manifest, err := this.installPackage(versionPath)
+		if err != nil {
+			return err
+		}
+		err = this.integrityChecker.Verify(manifest, versionPath)
+		if err != nil {
+			return fmt.Errorf("installed version of %s failed verification: %w", this.dependency.Title(), err)
+		}
+	}
+
+	if state == installationIntact {
+		err = this.retryFailedPostInstallHook(versionPath)
+	} else {
+		err = this.runPostInstallHook(versionPath)
+	}
+	if err != nil {
+		return err
+	}
+	err = layout.Activate(version)
+	if err != nil {
+		return err
+	}
+	log.Printf("Activated version %s of %s", version, this.dependency.Title())
+	return layout.Collect(this.dependency.ComposeRetainVersions())
+}
+
+func (this *DependencyResolver) inspectInstallationAt(localPath string) installationState {
+	manifestPath := ComposeManifestPath(localPath, this.dependency.PackageName)
+	if !this.localManifestExists(manifestPath) {
+		return installationMissing
+	}
+	localManifest, err := this.loadLocalManifest(manifestPath)
+	if err != nil {
+		log.Println("[WARN]", err)
+		return installationMissing
+	}
+	return this.inspectInstallation(localManifest, localPath)
+}
+
+// resolveVersion returns the concrete version to install, consulting the remote "latest" manifest when required.
+func (this *DependencyResolver) resolveVersion() (string, error) {
+	if this.dependency.PackageVersion != "latest" {
+		return this.dependency.PackageVersion, nil
+	}
+	remoteManifest, err := this.packageInstaller.DownloadManifest(this.dependency.ComposeRemoteManifestAddress())
+	if err != nil {
+		return "", fmt.Errorf("failed to download the latest manifest for %s: %w", this.dependency.Title(), err)
+	}
+	this.dependency.PackageVersion = remoteManifest.Version
+	return remoteManifest.Version, nil
+}
+
+// replacePackage installs the specified package over the previously installed one and only
+// then removes whatever the previous installation left behind, so a failed installation
+// leaves the previous package (and its local manifest) in place.
+func (this *DependencyResolver) replacePackage(localManifest contracts.Manifest) error {
+	manifest, err := this.installPackage(this.dependency.LocalDirectory)
+	if err != nil {
+		return err
+	}
+	this.uninstallStaleContents(localManifest, manifest)
+	return nil
 }
 
 func (this *DependencyResolver) loadLocalManifest(manifestPath string) (localManifest contracts.Manifest, err error) {
@@ -80,45 +252,54 @@ func (this *DependencyResolver) localManifestExists(manifestPath string) bool {
 	return !os.IsNotExist(err)
 }
 
-func (this *DependencyResolver) isInstalledCorrectly(localManifest contracts.Manifest) bool {
-	if localManifest.Name != this.dependency.PackageName {
-		if strings.HasSuffix(localManifest.Name, "/"+this.dependency.PackageName) {
-			// no-op
-		} else {
-			log.Printf("incorrect package installed (%s), proceeding to installation of specified package: %s",
-				localManifest.Name, this.dependency.Title())
-			return false
-		}
+// installationState tells whether an installed package has to be (re-)installed and, if not,
+// whether its files were rewritten (by a repair) along the way.
+type installationState int
+
+const (
+	installationMissing installationState = iota
+	installationIntact
+	installationRepaired
+)
+
+func (this *DependencyResolver) inspectInstallation(localManifest contracts.Manifest, localPath string) installationState {
+	status, detail := this.inspectManifest(localManifest, localPath)
+	if status == StatusIntegrityFailed && this.repairer != nil {
+		return this.repair(localManifest, localPath, detail)
 	}
-	if this.dependency.PackageVersion == "latest" && !this.localManifestIsLatest(localManifest) {
-		log.Printf("incorrect version installed (%s), proceeding to installation of specified package: %s",
-			localManifest.Version, this.dependency.Title())
-		return false
-	} else if this.dependency.PackageVersion != "latest" && localManifest.Version != this.dependency.PackageVersion {
-		log.Printf("incorrect version installed (%s), proceeding to installation of specified package: %s",
-			localManifest.Version, this.dependency.Title())
-		return false
+	if status != StatusInstalled {
+		log.Printf("%s, proceeding to installation of specified package: %s", detail, this.dependency.Title())
+		return installationMissing
 	}
+	log.Printf("Dependency already installed: %s", this.dependency.Title())
+	return installationIntact
+}
 
-	verifyErr := this.integrityChecker.Verify(localManifest, this.dependency.LocalDirectory)
-	if verifyErr != nil {
-		log.Printf("%s in %s", verifyErr.Error(), this.dependency.Title())
-		return false
+// repair restores only the items of the installed package that fail verification. When that
+// doesn't work out the package is reinstalled from scratch, as it would be without a repairer.
+func (this *DependencyResolver) repair(localManifest contracts.Manifest, localPath, detail string) installationState {
+	log.Printf("%s, proceeding to repair of installed package: %s", detail, this.dependency.Title())
+	err := this.repairer.Repair(this.dependency, localManifest, localPath)
+	if err != nil {
+		log.Printf("[WARN] %s, proceeding to installation of specified package: %s", err, this.dependency.Title())
+		return installationMissing
 	}
-
-	log.Printf("Dependency already installed: %s", this.dependency.Title())
-	return true
+	return installationRepaired
 }
 
-func (this *DependencyResolver) installPackage() error {
+func (this *DependencyResolver) installPackage(localPath string) (contracts.Manifest, error) {
 	log.Printf("Downloading manifest for %s", this.dependency.Title())
 	manifest, err := this.packageInstaller.InstallManifest(contracts.InstallationRequest{
-		RemoteAddress: this.dependency.ComposeRemoteManifestAddress(),
-		LocalPath:     this.dependency.LocalDirectory,
-		PackageName:   this.dependency.PackageName,
+		RemoteAddress:  this.dependency.ComposeRemoteManifestAddress(),
+		LocalPath:      localPath,
+		PackageName:    this.dependency.PackageName,
+		Selection:      this.dependency.Selection(),
+		Remapping:      this.dependency.Remapping(),
+		Overridden:     this.dependency.Overridden,
+		ManifestDigest: this.dependency.ManifestDigest,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to install manifest for %s: %w", this.dependency.Title(), err)
+		return contracts.Manifest{}, fmt.Errorf("failed to install manifest for %s: %w", this.dependency.Title(), err)
 	}
 	log.Printf("Downloading and extracting package contents for %s", this.dependency.Title())
 
@@ -134,28 +315,27 @@ func (this *DependencyResolver) installPackage() error {
 
 	err = this.packageInstaller.InstallPackage(manifest, contracts.InstallationRequest{
 		RemoteAddress: this.dependency.ComposeRemoteAddress(contracts.RemoteArchiveFilename),
-		LocalPath:     this.dependency.LocalDirectory,
+		LocalPath:     localPath,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to install package contents for %s: %w", this.dependency.Title(), err)
+		return contracts.Manifest{}, fmt.Errorf("failed to install package contents for %s: %w", this.dependency.Title(), err)
 	}
 
 	log.Printf("Dependency installed: %s", this.dependency.Title())
-	return nil
+	return manifest, nil
 }
 
-func (this *DependencyResolver) uninstallPackage(manifest contracts.Manifest) {
-	for _, item := range manifest.Archive.Contents {
-		this.fileSystem.Delete(filepath.Join(this.dependency.LocalDirectory, item.Path))
+func (this *DependencyResolver) uninstallStaleContents(previous, current contracts.Manifest) {
+	retained := make(map[string]struct{})
+	for _, item := range current.Archive.Contents {
+		if path, installed := current.ComposeLocalPath(this.dependency.LocalDirectory, item.Path); installed {
+			retained[path] = struct{}{}
+		}
 	}
-}
-
-func (this *DependencyResolver) localManifestIsLatest(manifest contracts.Manifest) bool {
-	remoteManifest, err := this.packageInstaller.DownloadManifest(this.dependency.ComposeRemoteManifestAddress())
-	if err != nil {
-		log.Println("Failed to download the latest manifest file:", err)
-		return false
+	for _, item := range previous.Archive.Contents {
+		path, installed := previous.ComposeLocalPath(this.dependency.LocalDirectory, item.Path)
+		if _, found := retained[path]; installed && !found {
+			this.fileSystem.Delete(path)
+		}
 	}
-	this.dependency.PackageVersion = remoteManifest.Version
-	return remoteManifest.Version == manifest.Version
 }
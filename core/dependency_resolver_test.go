@@ -22,6 +22,9 @@ type DependencyResolverFixture struct {
 	fileSystem       *inMemoryFileSystem
 	integrityChecker *FakeIntegrityCheck
 	packageInstaller *FakePackageInstaller
+	versionCatalog   *FakeVersionCatalog
+	repairer         Repairer
+	hooks            *FakeHookRunner
 	dependency       contracts.Dependency
 }
 
@@ -29,6 +32,8 @@ func (this *DependencyResolverFixture) Setup() {
 	this.integrityChecker = &FakeIntegrityCheck{}
 	this.fileSystem = newInMemoryFileSystem()
 	this.packageInstaller = &FakePackageInstaller{}
+	this.versionCatalog = &FakeVersionCatalog{}
+	this.hooks = &FakeHookRunner{}
 	this.dependency = contracts.Dependency{
 		PackageName:    "B/C",
 		PackageVersion: "D",
@@ -39,7 +44,7 @@ func (this *DependencyResolverFixture) Setup() {
 }
 
 func (this *DependencyResolverFixture) Resolve() error {
-	this.resolver = NewDependencyResolver(this.fileSystem, this.integrityChecker, this.packageInstaller, this.dependency)
+	this.resolver = NewDependencyResolver(this.fileSystem, this.integrityChecker, this.packageInstaller, this.versionCatalog, this.repairer, this.hooks, this.dependency)
 	return this.resolver.Resolve()
 }
 
@@ -57,6 +62,39 @@ func (this *DependencyResolverFixture) TestFreshInstallation() {
 	this.assertNewPackageInstalled(manifest.Name, this.dependency.PackageVersion)
 }
 
+func (this *DependencyResolverFixture) TestVersionConstraintIsResolvedBeforeInstallation() {
+	this.dependency.PackageVersion = "^1.4"
+	this.versionCatalog.version = "1.6.2"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.versionCatalog.dependency.PackageVersion, should.Equal, "^1.4")
+	this.assertNewPackageInstalled("B/C", "1.6.2")
+}
+
+func (this *DependencyResolverFixture) TestNewerVersionSatisfyingConstraintIsInstalled() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "1.4.0")
+	this.dependency.PackageVersion = ">=1.0 <2.0"
+	this.versionCatalog.version = "1.5.0"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 1)
+	this.So(this.packageInstaller.manifestRequest.RemoteAddress, should.Resemble, this.URL("gcs://A/B/C/1.5.0/manifest.json"))
+}
+
+func (this *DependencyResolverFixture) TestUnsatisfiableConstraintFailsWithoutInstallation() {
+	this.dependency.PackageVersion = "^9"
+	this.versionCatalog.err = errors.New("nothing matches")
+
+	err := this.Resolve()
+
+	this.So(errors.Is(err, this.versionCatalog.err), should.BeTrue)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+}
+
 func (this *DependencyResolverFixture) TestManifestInstallationFailure() {
 	manifestErr := errors.New("manifest failure")
 	this.packageInstaller.installManifestErr = manifestErr
@@ -122,6 +160,222 @@ func (this *DependencyResolverFixture) TestIntegrityCheckFailure() {
 	this.So(this.integrityChecker.manifest, should.Resemble, localManifest)
 }
 
+func (this *DependencyResolverFixture) TestIntegrityCheckFailureIsRepairedWithoutReinstallation() {
+	localManifest := this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.integrityChecker.err = errors.New("integrity check failure")
+	repairer := &FakeRepairer{}
+	this.repairer = repairer
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(repairer.dependency.PackageName, should.Equal, this.dependency.PackageName)
+	this.So(repairer.manifest, should.Resemble, localManifest)
+	this.So(repairer.localPath, should.Equal, this.dependency.LocalDirectory)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents1")
+}
+
+func (this *DependencyResolverFixture) TestFailedRepairFallsBackToReinstallation() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.integrityChecker.err = errors.New("integrity check failure")
+	this.repairer = &FakeRepairer{err: errors.New("repair failure")}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.assertPreviouslyInstalledPackageUninstalled()
+	this.assertNewPackageInstalled(this.dependency.PackageName, this.dependency.PackageVersion)
+}
+
+func (this *DependencyResolverFixture) TestWrongVersionIsNotRepaired() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "wrong-version")
+	repairer := &FakeRepairer{}
+	this.repairer = repairer
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(repairer.calls, should.Equal, 0)
+	this.assertNewPackageInstalled(this.dependency.PackageName, this.dependency.PackageVersion)
+}
+
+func (this *DependencyResolverFixture) TestSelectionIsPassedAlongToInstallation() {
+	this.dependency.Include = []string{"UT"}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.manifestRequest.Selection, should.Resemble, contracts.PathSelection{Include: []string{"UT"}})
+}
+
+func (this *DependencyResolverFixture) TestOverriddenItemsArePassedAlongToInstallation() {
+	this.dependency.Overridden = []string{"UT"}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.manifestRequest.Overridden, should.Resemble, []string{"UT"})
+	this.So(this.packageInstaller.manifestRequest.Selection.IsEmpty(), should.BeTrue)
+}
+
+func (this *DependencyResolverFixture) TestManifestDigestIsPassedAlongToInstallation() {
+	this.dependency.ManifestDigest = "sha256:locked"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.manifestRequest.ManifestDigest, should.Equal, "sha256:locked")
+}
+
+func (this *DependencyResolverFixture) TestInstalledManifestNotMatchingLockedDigestCausesReinstallation() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.dependency.ManifestDigest = "sha256:locked"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 1)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 1)
+}
+
+func (this *DependencyResolverFixture) TestChangedSelectionCausesReinstallation() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.dependency.Exclude = []string{"contents2"}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.assertPreviouslyInstalledPackageUninstalled()
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 1)
+}
+
+func (this *DependencyResolverFixture) TestChangedRemappingUninstallsFromPreviouslyRemappedPaths() {
+	localManifest := this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	localManifest.Remapping = &contracts.PathRemapping{Mappings: []contracts.PathMapping{{Source: "contents1", Target: "/opt/contents1"}}}
+	raw, _ := json.Marshal(localManifest)
+	this.fileSystem.WriteFile("local/manifest_B___C.json", raw)
+	this.fileSystem.WriteFile("/opt/contents1", []byte("contents1"))
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/opt/contents1")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents1")
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 1)
+}
+
+func (this *DependencyResolverFixture) TestVersionedFreshInstallationActivatesVersion() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.manifestRequest.LocalPath, should.Equal, "local/.versions/D")
+	this.So(this.packageInstaller.packageRequest.LocalPath, should.Equal, "local/.versions/D")
+	this.So(this.integrityChecker.localPath, should.Equal, "local/.versions/D")
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+}
+
+func (this *DependencyResolverFixture) TestVersionedLatestResolvesVersionBeforeInstallation() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.PackageVersion = "latest"
+	this.packageInstaller.remoteLatest = contracts.Manifest{Name: "B/C", Version: "E"}
+	this.packageInstaller.remote = this.packageInstaller.remoteLatest
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.manifestRequest.LocalPath, should.Equal, "local/.versions/E")
+	this.So(this.packageInstaller.packageRequest.RemoteAddress, should.Resemble, this.URL("gcs://A/B/C/E/archive"))
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/E")
+}
+
+func (this *DependencyResolverFixture) TestVersionedCurrentVersionAlreadyInstalled() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.prepareVersionedInstallation("D")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+}
+
+func (this *DependencyResolverFixture) TestVersionedPreviouslyInstalledVersionIsReactivatedWithoutDownload() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.prepareVersionedInstallation("D")
+	this.prepareVersionedInstallation("E")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/.versions/E/manifest_B___C.json")
+}
+
+func (this *DependencyResolverFixture) TestVersionedOldVersionsAreCollected() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.RetainVersions = 1
+	this.prepareVersionedInstallation("C")
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/.versions/C/manifest_B___C.json")
+}
+
+func (this *DependencyResolverFixture) TestVersionedInstallationFailingVerificationIsNotActivated() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.integrityChecker.err = errors.New("integrity check failure")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/current")
+}
+
+func (this *DependencyResolverFixture) TestVersionedRollbackIsKeptWhileTheSameVersionIsRequested() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.prepareVersionedInstallation("C")
+	this.prepareVersionedInstallation("D")
+	_, _ = NewVersionedLayout(this.fileSystem, "local", "B/C").Rollback("D")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/C")
+}
+
+func (this *DependencyResolverFixture) TestVersionedRollbackIsForgottenOnceAnotherVersionIsRequested() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.prepareVersionedInstallation("C")
+	this.prepareVersionedInstallation("D")
+	layout := NewVersionedLayout(this.fileSystem, "local", "B/C")
+	_, _ = layout.Rollback("^1.0")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+	this.So(layout.RolledBack("^1.0"), should.BeEmpty)
+}
+
+func (this *DependencyResolverFixture) prepareVersionedInstallation(version string) {
+	raw, _ := json.Marshal(contracts.Manifest{Name: "B/C", Version: version})
+	this.fileSystem.WriteFile("local/.versions/"+version+"/manifest_B___C.json", raw)
+	_ = NewVersionedLayout(this.fileSystem, "local", "B/C").Activate(version)
+}
+
 func (this *DependencyResolverFixture) TestAlreadyInstalledCorrectly() {
 	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
 
@@ -135,6 +389,132 @@ func (this *DependencyResolverFixture) TestAlreadyInstalledCorrectly() {
 	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
 }
 
+func (this *DependencyResolverFixture) TestPostInstallHookIsSkippedWhenAlreadyInstalledCorrectly() {
+	this.dependency.PostInstall = "reindex"
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.BeEmpty)
+}
+
+func (this *DependencyResolverFixture) TestPostInstallHookRunsAfterUpdate() {
+	this.dependency.PostInstall = "reindex"
+	this.dependency.PackageVersion = "latest"
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "1")
+	this.packageInstaller.remoteLatest = contracts.Manifest{Name: "B/C", Version: "2"}
+	this.packageInstaller.remote = this.packageInstaller.remoteLatest
+	this.hooks.check = func() { this.So(this.packageInstaller.installPackageCounter, should.Equal, 1) }
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.Resemble, []FakeHookRun{{
+		command:     "reindex",
+		directory:   "local",
+		environment: []string{"SATISFY_PACKAGE_NAME=B/C", "SATISFY_PACKAGE_VERSION=2", "SATISFY_PACKAGE_PATH=local"},
+	}})
+}
+
+func (this *DependencyResolverFixture) TestFailingPostInstallHookIsRecordedAlongsideTheInstallation() {
+	this.dependency.PostInstall = "false"
+	this.hooks.err = errors.New("exit status 1")
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.Resemble, fmt.Errorf("post-install hook of [B/C @ D] failed: %w", this.hooks.err))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/manifest_B___C.json")
+	this.So(this.fileSystem.readFile("local/manifest_B___C.json.post-install-failed"), should.Resemble, []byte(err.Error()))
+}
+
+func (this *DependencyResolverFixture) TestFailedPostInstallHookIsRunAgainWithoutReinstallation() {
+	this.dependency.PostInstall = "reindex"
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.fileSystem.WriteFile("local/manifest_B___C.json.post-install-failed", []byte("exit status 1"))
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.HaveLength, 1)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/manifest_B___C.json.post-install-failed")
+}
+
+func (this *DependencyResolverFixture) TestPostInstallHookRunsAfterRepair() {
+	this.dependency.PostInstall = "reindex"
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.integrityChecker.err = errors.New("integrity check failure")
+	this.repairer = &FakeRepairer{}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.HaveLength, 1)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+}
+
+func (this *DependencyResolverFixture) TestVersionedPostInstallHookRunsInTheVersionDirectory() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.PostInstall = "reindex"
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.HaveLength, 1)
+	this.So(this.hooks.runs[0].directory, should.Equal, "local/.versions/D")
+}
+
+func (this *DependencyResolverFixture) TestVersionedFailingPostInstallHookLeavesPreviousVersionCurrent() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.PostInstall = "false"
+	this.hooks.err = errors.New("exit status 1")
+	this.prepareVersionedInstallation("C")
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/C")
+	this.So(NewVersionedLayout(this.fileSystem, "local", "B/C").History(), should.Resemble, []string{"C"})
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/.versions/D/manifest_B___C.json.post-install-failed")
+}
+
+func (this *DependencyResolverFixture) TestVersionedFailedPostInstallHookIsRunAgainBeforeActivation() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.PostInstall = "reindex"
+	this.prepareVersionedInstallation("C")
+	raw, _ := json.Marshal(contracts.Manifest{Name: "B/C", Version: "D"})
+	this.fileSystem.WriteFile("local/.versions/D/manifest_B___C.json", raw)
+	this.fileSystem.WriteFile("local/.versions/D/manifest_B___C.json.post-install-failed", nil)
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.HaveLength, 1)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+}
+
+func (this *DependencyResolverFixture) TestVersionedReactivationDoesNotRunPostInstallHook() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.dependency.PostInstall = "reindex"
+	this.prepareVersionedInstallation("D")
+	this.prepareVersionedInstallation("C")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.BeEmpty)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].symlink, should.Equal, ".versions/D")
+}
+
 func (this *DependencyResolverFixture) TestFinalInstallationFailed() {
 	installError := errors.New("install package error")
 	this.packageInstaller.installPackageErr = installError
@@ -210,7 +590,7 @@ func (this *DependencyResolverFixture) assertLatestPackageInstalled(err error, n
 	})
 }
 func (this *DependencyResolverFixture) TestLatestManifestFailsToDownload() {
-	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	localManifest := this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
 	this.dependency.PackageVersion = "latest"
 
 	this.packageInstaller.downloadError = errors.New("error")
@@ -219,9 +599,34 @@ func (this *DependencyResolverFixture) TestLatestManifestFailsToDownload() {
 	err := this.Resolve()
 
 	this.So(err, should.NotBeNil)
-	this.assertPreviouslyInstalledPackageUninstalled()
+	this.assertPreviouslyInstalledPackageRetained(localManifest)
+}
+
+func (this *DependencyResolverFixture) TestFailedReplacementRetainsPreviousPackage() {
+	localManifest := this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "D"}
+	this.packageInstaller.fileSystem = this.fileSystem
+	this.packageInstaller.installPackageErr = errors.New("checksum mismatch")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.assertPreviouslyInstalledPackageRetained(localManifest)
+}
+
+func (this *DependencyResolverFixture) TestReplacementOnlyRemovesContentsNoLongerInstalled() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = contracts.Manifest{
+		Name:    "B/C",
+		Version: "D",
+		Archive: contracts.Archive{Contents: []contracts.ArchiveItem{{Path: "contents2"}}},
+	}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
 	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents1")
-	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents2")
 	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents3")
 }
 
@@ -267,6 +672,16 @@ func (this *DependencyResolverFixture) assertPreviouslyInstalledPackageUninstall
 	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents3")
 }
 
+func (this *DependencyResolverFixture) assertPreviouslyInstalledPackageRetained(localManifest contracts.Manifest) {
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents1")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents2")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents3")
+	var restored contracts.Manifest
+	raw, _ := this.fileSystem.ReadFile("local/manifest_B___C.json")
+	this.So(json.Unmarshal(raw, &restored), should.BeNil)
+	this.So(restored, should.Resemble, localManifest)
+}
+
 func (this *DependencyResolverFixture) URL(address string) url.URL {
 	parsed, err := url.Parse(address)
 	this.So(err, should.BeNil)
@@ -286,6 +701,7 @@ type FakePackageInstaller struct {
 	installManifestCounter int
 	installPackageCounter  int
 	downloadError          error
+	fileSystem             *inMemoryFileSystem
 }
 
 func (this *FakePackageInstaller) DownloadManifest(url.URL) (manifest contracts.Manifest, err error) {
@@ -302,5 +718,34 @@ func (this *FakePackageInstaller) InstallPackage(manifest contracts.Manifest, re
 	this.installPackageCounter++
 	this.installed = manifest
 	this.packageRequest = request
+	if this.fileSystem != nil && this.installPackageErr == nil {
+		raw, _ := json.Marshal(manifest)
+		this.fileSystem.WriteFile(ComposeManifestPath(request.LocalPath, manifest.Name), raw)
+	}
 	return this.installPackageErr
 }
+
+type FakeVersionCatalog struct {
+	dependency contracts.Dependency
+	version    string
+	err        error
+}
+
+func (this *FakeVersionCatalog) ResolveConstraint(dependency contracts.Dependency) (string, error) {
+	this.dependency = dependency
+	return this.version, this.err
+}
+
+type FakeRepairer struct {
+	dependency contracts.Dependency
+	manifest   contracts.Manifest
+	localPath  string
+	calls      int
+	err        error
+}
+
+func (this *FakeRepairer) Repair(dependency contracts.Dependency, manifest contracts.Manifest, localPath string) error {
+	this.calls++
+	this.dependency, this.manifest, this.localPath = dependency, manifest, localPath
+	return this.err
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type InstallationStatus string
+
+const (
+	StatusInstalled       InstallationStatus = "installed"
+	StatusMissing         InstallationStatus = "missing"
+	StatusWrongVersion    InstallationStatus = "wrong-version"
+	StatusOutdated        InstallationStatus = "outdated"
+	StatusIntegrityFailed InstallationStatus = "integrity-failed"
+	StatusHookFailed      InstallationStatus = "hook-failed"
+	StatusUnknown         InstallationStatus = "unknown"
+)
+
+// DependencyStatus is the outcome of inspecting a dependency without changing anything.
+type DependencyStatus struct {
+	Dependency       contracts.Dependency
+	Status           InstallationStatus
+	InstalledVersion string
+	ExpectedVersion  string
+	Detail           string
+
+	// RequiredBy names the packages requiring the dependency (see DependencyGraph).
+	RequiredBy []string
+}
+
+// Inspect performs the same checks as Resolve but only reports on what Resolve would do.
+// Nothing is installed, deleted or (re-)activated.
+func (this *DependencyResolver) Inspect() DependencyStatus {
+	result := DependencyStatus{Dependency: this.dependency}
+
+	if this.dependency.HasVersionConstraint() {
+		version, err := this.versionCatalog.ResolveConstraint(this.dependency)
+		if err != nil {
+			result.Status, result.Detail = StatusUnknown, err.Error()
+			return result
+		}
+		this.dependency.PackageVersion = version
+	}
+
+	localPath := this.dependency.LocalDirectory
+	if this.dependency.IsVersioned() {
+		layout := NewVersionedLayout(this.fileSystem, localPath, this.dependency.PackageName)
+		if version := layout.RolledBack(this.requestedVersion); version != "" {
+			this.dependency.PackageVersion = version
+		}
+		current := layout.CurrentVersion()
+		if current == "" {
+			result.Status, result.Detail = StatusMissing, "no version has been activated"
+			return result
+		}
+		localPath = layout.VersionPath(current)
+	}
+
+	manifestPath := ComposeManifestPath(localPath, this.dependency.PackageName)
+	if !this.localManifestExists(manifestPath) {
+		result.Status, result.Detail = StatusMissing, "no local manifest found"
+		return result
+	}
+	localManifest, err := this.loadLocalManifest(manifestPath)
+	if err != nil {
+		result.Status, result.Detail = StatusUnknown, err.Error()
+		return result
+	}
+	result.InstalledVersion = localManifest.Version
+	result.Status, result.Detail = this.inspectManifest(localManifest, localPath)
+	result.ExpectedVersion = this.dependency.PackageVersion
+	if result.Status == StatusInstalled && this.hasFailedPostInstallHook(localPath) {
+		result.Status, result.Detail = StatusHookFailed, "post-install hook failed (it is run again on the next install)"
+	}
+	return result
+}
+
+// inspectManifest compares the locally installed package with the specified one, resolving
+// "latest" to a concrete version along the way.
+func (this *DependencyResolver) inspectManifest(localManifest contracts.Manifest, localPath string) (InstallationStatus, string) {
+	if localManifest.Name != this.dependency.PackageName && !strings.HasSuffix(localManifest.Name, "/"+this.dependency.PackageName) {
+		return StatusMissing, fmt.Sprintf("incorrect package installed (%s)", localManifest.Name)
+	}
+
+	if this.dependency.PackageVersion == "latest" {
+		remoteManifest, err := this.packageInstaller.DownloadManifest(this.dependency.ComposeRemoteManifestAddress())
+		if err != nil {
+			return StatusUnknown, fmt.Sprintf("failed to download the latest manifest file (%s)", err)
+		}
+		this.dependency.PackageVersion = remoteManifest.Version
+		if remoteManifest.Version != localManifest.Version {
+			return StatusOutdated, fmt.Sprintf("outdated version installed (%s)", localManifest.Version)
+		}
+	} else if localManifest.Version != this.dependency.PackageVersion {
+		if this.requestedVersion != this.dependency.PackageVersion && this.allowsRequested(localManifest.Version) {
+			return StatusOutdated, fmt.Sprintf("outdated version installed (%s)", localManifest.Version)
+		}
+		return StatusWrongVersion, fmt.Sprintf("incorrect version installed (%s)", localManifest.Version)
+	}
+
+	if !localManifest.InstalledSelection().Equal(this.dependency.Selection()) {
+		return StatusWrongVersion, "different selection of package contents installed"
+	}
+	if !localManifest.InstalledRemapping().Equal(this.dependency.Remapping()) {
+		return StatusWrongVersion, "package contents installed at different paths"
+	}
+	if this.dependency.ManifestDigest != "" && localManifest.Digest != this.dependency.ManifestDigest {
+		return StatusWrongVersion, "installed manifest does not match the locked digest"
+	}
+
+	if err := this.integrityChecker.Verify(localManifest, localPath); err != nil {
+		return StatusIntegrityFailed, err.Error()
+	}
+	return StatusInstalled, ""
+}
+
+// allowsRequested reports whether the version satisfies the version constraint originally requested (if any).
+func (this *DependencyResolver) allowsRequested(version string) bool {
+	constraint, err := contracts.ParseVersionConstraint(this.requestedVersion)
+	if err != nil {
+		return false
+	}
+	parsed, ok := contracts.ParseSemanticVersion(version)
+	return ok && constraint.Allows(parsed)
+}
+
+// NeedsAction reports whether Resolve would have to change anything (or the status could not be determined).
+func (this DependencyStatus) NeedsAction() bool {
+	return this.Status != StatusInstalled
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestDependencyStatusFixture(t *testing.T) {
+	gunit.Run(new(DependencyStatusFixture), t)
+}
+
+type DependencyStatusFixture struct {
+	*gunit.Fixture
+
+	fileSystem       *inMemoryFileSystem
+	integrityChecker *FakeIntegrityCheck
+	packageInstaller *FakePackageInstaller
+	versionCatalog   *FakeVersionCatalog
+	dependency       contracts.Dependency
+}
+
+func (this *DependencyStatusFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.integrityChecker = &FakeIntegrityCheck{}
+	this.packageInstaller = &FakePackageInstaller{}
+	this.versionCatalog = &FakeVersionCatalog{}
+	this.dependency = contracts.Dependency{
+		PackageName:    "B/C",
+		PackageVersion: "1.2.3",
+		RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "A"},
+		LocalDirectory: "local",
+	}
+}
+
+func (this *DependencyStatusFixture) inspect() DependencyStatus {
+	resolver := NewDependencyResolver(this.fileSystem, this.integrityChecker, this.packageInstaller, this.versionCatalog, nil, nil, this.dependency)
+	return resolver.Inspect()
+}
+
+func (this *DependencyStatusFixture) install(localPath, version string) {
+	raw, _ := json.Marshal(contracts.Manifest{Name: "B/C", Version: version})
+	this.fileSystem.WriteFile(ComposeManifestPath(localPath, "B/C"), raw)
+}
+
+func (this *DependencyStatusFixture) assertNothingChanged(files int) {
+	this.So(this.fileSystem.fileSystem, should.HaveLength, files)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+}
+
+func (this *DependencyStatusFixture) TestInstalled() {
+	this.install("local", "1.2.3")
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusInstalled)
+	this.So(status.NeedsAction(), should.BeFalse)
+	this.So(status.InstalledVersion, should.Equal, "1.2.3")
+	this.assertNothingChanged(1)
+}
+
+func (this *DependencyStatusFixture) TestMissing() {
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusMissing)
+	this.So(status.NeedsAction(), should.BeTrue)
+	this.assertNothingChanged(0)
+}
+
+func (this *DependencyStatusFixture) TestWrongVersion() {
+	this.install("local", "1.0.0")
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusWrongVersion)
+	this.So(status.InstalledVersion, should.Equal, "1.0.0")
+	this.So(status.ExpectedVersion, should.Equal, "1.2.3")
+	this.assertNothingChanged(1)
+}
+
+func (this *DependencyStatusFixture) TestOutdatedRelativeToLatest() {
+	this.install("local", "1.0.0")
+	this.dependency.PackageVersion = "latest"
+	this.packageInstaller.remoteLatest = contracts.Manifest{Version: "1.2.3"}
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusOutdated)
+	this.So(status.ExpectedVersion, should.Equal, "1.2.3")
+	this.assertNothingChanged(1)
+}
+
+func (this *DependencyStatusFixture) TestOutdatedRelativeToConstraint() {
+	this.install("local", "1.0.0")
+	this.dependency.PackageVersion = "^1"
+	this.versionCatalog.version = "1.2.3"
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusOutdated)
+	this.So(status.ExpectedVersion, should.Equal, "1.2.3")
+}
+
+func (this *DependencyStatusFixture) TestLatestCannotBeDetermined() {
+	this.install("local", "1.0.0")
+	this.dependency.PackageVersion = "latest"
+	this.packageInstaller.downloadError = errors.New("offline")
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusUnknown)
+	this.So(status.Detail, should.ContainSubstring, "offline")
+}
+
+func (this *DependencyStatusFixture) TestIntegrityFailure() {
+	this.install("local", "1.2.3")
+	this.integrityChecker.err = errors.New("checksum mismatch")
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusIntegrityFailed)
+	this.So(status.Detail, should.Equal, "checksum mismatch")
+	this.assertNothingChanged(1)
+}
+
+func (this *DependencyStatusFixture) TestFailedPostInstallHook() {
+	this.install("local", "1.2.3")
+	this.fileSystem.WriteFile(ComposeHookFailurePath("local", "B/C"), []byte("exit status 1"))
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusHookFailed)
+	this.So(status.NeedsAction(), should.BeTrue)
+	this.assertNothingChanged(2)
+}
+
+func (this *DependencyStatusFixture) TestVersionedLayoutInspectsCurrentVersion() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.install("local/.versions/1.0.0", "1.0.0")
+	_ = NewVersionedLayout(this.fileSystem, "local", "B/C").Activate("1.0.0")
+
+	status := this.inspect()
+
+	this.So(status.Status, should.Equal, StatusWrongVersion)
+	this.So(status.InstalledVersion, should.Equal, "1.0.0")
+	this.So(this.integrityChecker.localPath, should.BeEmpty)
+}
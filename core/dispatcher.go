@@ -0,0 +1,111 @@
+package core
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// Job downloads from each of its hosts (a job that installs several packages one after the
+// other may download them from different hosts).
+type Job struct {
+	Hosts []string
+	Run   func()
+}
+
+// Dispatcher runs jobs concurrently: at most parallel jobs at a time and at most perHost jobs
+// for any one host (a limit of 0 means no limit); a job counts against each of its hosts. Jobs are started in order, except that a
+// job waiting for its host doesn't hold up later jobs for other hosts.
+type Dispatcher struct {
+	parallel int
+	perHost  int
+}
+
+func NewDispatcher(parallel, perHost int) *Dispatcher {
+	return &Dispatcher{parallel: parallel, perHost: perHost}
+}
+
+// Dispatch returns once every job has finished.
+func (this *Dispatcher) Dispatch(jobs []Job) {
+	var mutex sync.Mutex
+	finished := sync.NewCond(&mutex)
+	waiter := new(sync.WaitGroup)
+	pending := slices.Clone(jobs)
+	running := 0
+	runningPerHost := make(map[string]int)
+
+	mutex.Lock()
+	for len(pending) > 0 {
+		index := this.next(pending, running, runningPerHost)
+		if index < 0 {
+			finished.Wait()
+			continue
+		}
+		job := pending[index]
+		pending = slices.Delete(pending, index, index+1)
+		running++
+		for _, host := range job.Hosts {
+			runningPerHost[host]++
+		}
+		waiter.Add(1)
+		go func() {
+			defer waiter.Done()
+			job.Run()
+			mutex.Lock()
+			running--
+			for _, host := range job.Hosts {
+				runningPerHost[host]--
+			}
+			finished.Signal()
+			mutex.Unlock()
+		}()
+	}
+	mutex.Unlock()
+	waiter.Wait()
+}
+
+// next returns the index of the first pending job that may start now (or -1 if none may).
+func (this *Dispatcher) next(pending []Job, running int, runningPerHost map[string]int) int {
+	if this.parallel > 0 && running >= this.parallel {
+		return -1
+	}
+	for i, job := range pending {
+		if this.perHost <= 0 || this.hostsAvailable(job.Hosts, runningPerHost) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (this *Dispatcher) hostsAvailable(hosts []string, runningPerHost map[string]int) bool {
+	for _, host := range hosts {
+		if runningPerHost[host] >= this.perHost {
+			return false
+		}
+	}
+	return true
+}
+
+// OrderBySize orders the dependencies so that the smallest packages come first; dependencies
+// whose size is unknown (missing from sizes) come last. Otherwise the listing order is kept.
+func OrderBySize(dependencies []contracts.Dependency, sizes map[int]int64) []contracts.Dependency {
+	indexes := make([]int, len(dependencies))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		left, leftKnown := sizes[indexes[i]]
+		right, rightKnown := sizes[indexes[j]]
+		if leftKnown != rightKnown {
+			return leftKnown
+		}
+		return left < right
+	})
+	ordered := make([]contracts.Dependency, 0, len(dependencies))
+	for _, index := range indexes {
+		ordered = append(ordered, dependencies[index])
+	}
+	return ordered
+}
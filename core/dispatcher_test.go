@@ -0,0 +1,123 @@
+package core
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestDispatcherFixture(t *testing.T) {
+	gunit.Run(new(DispatcherFixture), t)
+}
+
+type DispatcherFixture struct {
+	*gunit.Fixture
+
+	mutex          sync.Mutex
+	running        map[string]int
+	maxRunning     int
+	maxRunningHost map[string]int
+	started        []int
+}
+
+func (this *DispatcherFixture) Setup() {
+	this.running = make(map[string]int)
+	this.maxRunningHost = make(map[string]int)
+}
+
+func (this *DispatcherFixture) jobs(hosts ...string) (jobs []Job) {
+	for i, host := range hosts {
+		jobs = append(jobs, Job{Hosts: []string{host}, Run: func() { this.run(i, host) }})
+	}
+	return jobs
+}
+
+func (this *DispatcherFixture) run(id int, host string) {
+	this.mutex.Lock()
+	this.started = append(this.started, id)
+	this.running[host]++
+	this.maxRunningHost[host] = max(this.maxRunningHost[host], this.running[host])
+	total := 0
+	for _, count := range this.running {
+		total += count
+	}
+	this.maxRunning = max(this.maxRunning, total)
+	this.mutex.Unlock()
+
+	time.Sleep(time.Millisecond * 5)
+
+	this.mutex.Lock()
+	this.running[host]--
+	this.mutex.Unlock()
+}
+
+func (this *DispatcherFixture) TestEveryJobRunsWithinTheLimit() {
+	NewDispatcher(2, 0).Dispatch(this.jobs("a", "a", "a", "a", "a"))
+
+	this.So(this.started, should.HaveLength, 5)
+	this.So(this.maxRunning, should.Equal, 2)
+}
+
+func (this *DispatcherFixture) TestSingleWorkerRunsJobsInOrder() {
+	NewDispatcher(1, 0).Dispatch(this.jobs("a", "b", "a", "b"))
+
+	this.So(this.started, should.Resemble, []int{0, 1, 2, 3})
+	this.So(this.maxRunning, should.Equal, 1)
+}
+
+func (this *DispatcherFixture) TestPerHostLimit() {
+	NewDispatcher(0, 1).Dispatch(this.jobs("a", "a", "a", "b", "b", "c"))
+
+	this.So(this.started, should.HaveLength, 6)
+	this.So(this.maxRunningHost, should.Resemble, map[string]int{"a": 1, "b": 1, "c": 1})
+	this.So(this.maxRunning, should.BeGreaterThan, 1)
+}
+
+func (this *DispatcherFixture) TestJobWaitingForItsHostDoesNotHoldUpOtherHosts() {
+	NewDispatcher(2, 1).Dispatch(this.jobs("a", "a", "b"))
+
+	first := this.started[:2]
+	slices.Sort(first)
+	this.So(first, should.Resemble, []int{0, 2})
+}
+
+func (this *DispatcherFixture) TestJobCountsAgainstEachOfItsHosts() {
+	jobs := []Job{
+		{Hosts: []string{"a", "b"}, Run: func() { this.run(0, "b") }},
+		{Hosts: []string{"b"}, Run: func() { this.run(1, "b") }},
+	}
+
+	NewDispatcher(0, 1).Dispatch(jobs)
+
+	this.So(this.started, should.Resemble, []int{0, 1})
+	this.So(this.maxRunningHost, should.Resemble, map[string]int{"b": 1})
+}
+
+func (this *DispatcherFixture) TestNoJobs() {
+	NewDispatcher(2, 1).Dispatch(nil)
+
+	this.So(this.started, should.BeEmpty)
+}
+
+func (this *DispatcherFixture) TestOrderBySizeSmallestFirstAndUnknownLast() {
+	dependencies := []contracts.Dependency{
+		{PackageName: "unknown"},
+		{PackageName: "large"},
+		{PackageName: "small"},
+		{PackageName: "medium"},
+		{PackageName: "also-small"},
+	}
+
+	ordered := OrderBySize(dependencies, map[int]int64{1: 300, 2: 10, 3: 200, 4: 10})
+
+	var names []string
+	for _, dependency := range ordered {
+		names = append(names, dependency.PackageName)
+	}
+	this.So(names, should.Resemble, []string{"small", "also-small", "medium", "large", "unknown"})
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/shell"
+)
+
+// PackageExporter writes the contents of package archives as plain tar streams, whatever their compression.
+type PackageExporter struct {
+	downloader contracts.Downloader
+}
+
+func NewPackageExporter(downloader contracts.Downloader) *PackageExporter {
+	return &PackageExporter{downloader: downloader}
+}
+
+// DownloadArchive copies the archive to the spool, failing unless its checksum matches the manifest.
+// Nothing of the archive should be used until it has been verified this way.
+func (this *PackageExporter) DownloadArchive(manifest contracts.Manifest, remoteAddress url.URL, spool io.Writer) error {
+	body, err := this.downloader.Download(remoteAddress)
+	if err != nil {
+		return err
+	}
+	defer closeResource(body)
+
+	checksumReader := NewHashReader(body, md5.New())
+	if _, err = io.Copy(spool, checksumReader); err != nil {
+		return err
+	}
+	actualChecksum := checksumReader.Sum(nil)
+	if !bytes.Equal(actualChecksum, manifest.Archive.MD5Checksum) {
+		return fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actualChecksum, manifest.Archive.MD5Checksum)
+	}
+	return nil
+}
+
+// Export writes the items of the (verified) archive in source that the selection selects to the writer,
+// in the order of the archive. The entries are normalised: paths are relative, modes are 0644 or 0755,
+// ownership is dropped and only files and symlinks are written. It fails when a selected item of the
+// manifest is missing from the archive.
+func (this *PackageExporter) Export(manifest contracts.Manifest, source io.ReaderAt, size int64, selection contracts.PathSelection, writer contracts.ArchiveWriter) (exported int, err error) {
+	wanted := make(map[string]struct{})
+	for _, item := range selection.Apply(manifest.Archive.Contents) {
+		wanted[item.Path] = struct{}{}
+	}
+	reader, err := this.openArchive(manifest, source, size, wanted)
+	if err != nil {
+		return 0, err
+	}
+	defer closeResource(reader)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return exported, err
+		}
+		if _, found := wanted[header.Name]; !found {
+			continue
+		}
+		delete(wanted, header.Name)
+		mode := header.FileInfo().Mode()
+		if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+			continue
+		}
+		writer.WriteHeader(contracts.ArchiveHeader{
+			Name:       strings.TrimLeft(strings.TrimPrefix(header.Name, "./"), "/"),
+			Size:       header.Size,
+			ModTime:    header.ModTime,
+			LinkName:   header.Linkname,
+			Executable: contracts.IsExecutable(os.FileMode(header.Mode)),
+		})
+		if header.Typeflag != tar.TypeSymlink {
+			if _, err = io.Copy(writer, reader); err != nil {
+				return exported, err
+			}
+		}
+		exported++
+	}
+	if len(wanted) > 0 {
+		var missing []string
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return exported, fmt.Errorf("%w: %s", errArchiveItemMissing, strings.Join(missing, ", "))
+	}
+	return exported, nil
+}
+
+// openArchive reads zip archives (which can't be streamed) through their central directory.
+func (this *PackageExporter) openArchive(manifest contracts.Manifest, source io.ReaderAt, size int64, wanted map[string]struct{}) (ArchiveReadCloser, error) {
+	if manifest.Archive.CompressionAlgorithm == "zip" {
+		return shell.NewRangedZipArchiveReader(source, size, wanted)
+	}
+	factory, found := decompressors[manifest.Archive.CompressionAlgorithm]
+	if !found {
+		return nil, errors.New("invalid compression algorithm")
+	}
+	decompressor, err := factory(io.NewSectionReader(source, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	return streamedArchive{ArchiveReader: archiveFormats[""](decompressor), closers: []io.Closer{decompressor}}, nil
+}
+
+var errArchiveItemMissing = errors.New("archive lacks items listed in its manifest")
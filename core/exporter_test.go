@@ -0,0 +1,157 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/shell"
+)
+
+func TestPackageExporterFixture(t *testing.T) {
+	gunit.Run(new(PackageExporterFixture), t)
+}
+
+type PackageExporterFixture struct {
+	*gunit.Fixture
+	exporter   *PackageExporter
+	downloader *FakeDownloader
+	spool      *bytes.Buffer
+	output     *bytes.Buffer
+	address    url.URL
+}
+
+func (this *PackageExporterFixture) Setup() {
+	this.downloader = &FakeDownloader{}
+	this.exporter = NewPackageExporter(this.downloader)
+	this.spool = new(bytes.Buffer)
+	this.output = new(bytes.Buffer)
+	this.address = url.URL{Scheme: "gcs", Host: "bucket", Path: "/package/1.2.3/archive"}
+}
+
+func (this *PackageExporterFixture) buildManifest(checksum []byte, compressionAlgorithm string) contracts.Manifest {
+	return contracts.Manifest{
+		Archive: contracts.Archive{
+			MD5Checksum: checksum,
+			Contents: []contracts.ArchiveItem{
+				{Path: "Hello/World"},
+				{Path: "Goodbye/World"},
+				{Path: "Link"},
+			},
+			CompressionAlgorithm: compressionAlgorithm,
+		},
+	}
+}
+
+func (this *PackageExporterFixture) export(manifest contracts.Manifest, selection contracts.PathSelection) (int, error) {
+	err := this.exporter.DownloadArchive(manifest, this.address, this.spool)
+	if err != nil {
+		return 0, err
+	}
+	writer := shell.NewTarArchiveWriter(this.output)
+	source := bytes.NewReader(this.spool.Bytes())
+	exported, err := this.exporter.Export(manifest, source, source.Size(), selection, writer)
+	_ = writer.Close()
+	return exported, err
+}
+
+func (this *PackageExporterFixture) readOutput() (headers []*tar.Header, contents map[string]string) {
+	contents = make(map[string]string)
+	reader := tar.NewReader(this.output)
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			return headers, contents
+		}
+		raw, _ := io.ReadAll(reader)
+		headers = append(headers, header)
+		contents[header.Name] = string(raw)
+	}
+}
+
+func (this *PackageExporterFixture) TestGzipArchiveIsExportedAsPlainTar() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	exported, err := this.export(this.buildManifest(checksum, gzipAlgorithm), contracts.PathSelection{})
+
+	this.So(err, should.BeNil)
+	this.So(exported, should.Equal, 3)
+	this.So(this.downloader.request, should.Resemble, this.address)
+	headers, contents := this.readOutput()
+	this.So(headers, should.HaveLength, 3)
+	this.So(contents["Hello/World"], should.Equal, "Hello World")
+	this.So(headers[0].Mode, should.Equal, 0644)
+	this.So(headers[1].Mode, should.Equal, 0755)
+	this.So(headers[2].Typeflag, should.Equal, tar.TypeSymlink)
+	this.So(headers[2].Linkname, should.Equal, "Hello/World")
+}
+
+func (this *PackageExporterFixture) TestZipArchiveIsExportedAsPlainTar() {
+	this.downloader.prepareZipArchiveDownload()
+	checksum := md5.Sum(this.downloader.content)
+	manifest := this.buildManifest(checksum[:], "zip")
+	manifest.Archive.Contents = manifest.Archive.Contents[:2]
+
+	exported, err := this.export(manifest, contracts.PathSelection{})
+
+	this.So(err, should.BeNil)
+	this.So(exported, should.Equal, 2)
+	_, contents := this.readOutput()
+	this.So(contents, should.Resemble, map[string]string{"Hello/World": "Hello World", "Goodbye/World": "Goodbye World"})
+}
+
+func (this *PackageExporterFixture) TestOnlySelectedItemsAreExported() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	exported, err := this.export(this.buildManifest(checksum, gzipAlgorithm), contracts.PathSelection{Exclude: []string{"Goodbye"}})
+
+	this.So(err, should.BeNil)
+	this.So(exported, should.Equal, 2)
+	_, contents := this.readOutput()
+	this.So(contents, should.ContainKey, "Hello/World")
+	this.So(contents, should.NotContainKey, "Goodbye/World")
+}
+
+func (this *PackageExporterFixture) TestChecksumMismatchFailsBeforeExporting() {
+	this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	_, err := this.export(this.buildManifest([]byte("wrong"), gzipAlgorithm), contracts.PathSelection{})
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, "checksum mismatch")
+	this.So(this.output.Len(), should.Equal, 0)
+}
+
+func (this *PackageExporterFixture) TestDownloadErrorIsReturned() {
+	this.downloader.Error = errors.New("download failed")
+
+	_, err := this.export(this.buildManifest(nil, gzipAlgorithm), contracts.PathSelection{})
+
+	this.So(err, should.Equal, this.downloader.Error)
+}
+
+func (this *PackageExporterFixture) TestItemsOfManifestMissingFromArchiveAreAnError() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	manifest := this.buildManifest(checksum, gzipAlgorithm)
+	manifest.Archive.Contents = append(manifest.Archive.Contents, contracts.ArchiveItem{Path: "Missing"})
+
+	_, err := this.export(manifest, contracts.PathSelection{})
+
+	this.So(errors.Is(err, errArchiveItemMissing), should.BeTrue)
+	this.So(err.Error(), should.EndWith, ": Missing")
+}
+
+func (this *PackageExporterFixture) TestInvalidCompressionAlgorithm() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	_, err := this.export(this.buildManifest(checksum, "bogus"), contracts.PathSelection{})
+
+	this.So(err, should.NotBeNil)
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// HookRunner runs the command of a hook (see contracts.Dependency.PostInstall) in the directory,
+// with the variables (formatted as "KEY=value") added to the environment.
+type HookRunner interface {
+	RunHook(command, directory string, environment []string) error
+}
+
+// ComposeHookEnvironment describes the package to its hooks.
+func ComposeHookEnvironment(packageName, packageVersion, localPath string) []string {
+	return []string{
+		"SATISFY_PACKAGE_NAME=" + packageName,
+		"SATISFY_PACKAGE_VERSION=" + packageVersion,
+		"SATISFY_PACKAGE_PATH=" + localPath,
+	}
+}
+
+// runHook runs the command (if any) for the (installed version of the) dependency in localPath.
+func runHook(runner HookRunner, kind, command string, dependency contracts.Dependency, localPath string) error {
+	if runner == nil || command == "" {
+		return nil
+	}
+	log.Printf("Running %s hook of %s", kind, dependency.Title())
+	err := runner.RunHook(command, localPath, ComposeHookEnvironment(dependency.PackageName, dependency.PackageVersion, localPath))
+	if err != nil {
+		return fmt.Errorf("%s hook of %s failed: %w", kind, dependency.Title(), err)
+	}
+	return nil
+}
+
+// ComposeHookFailurePath returns where the failure of the post-install hook of the package installed
+// in localPath is recorded until the hook succeeds.
+func ComposeHookFailurePath(localPath, packageName string) string {
+	return ComposeManifestPath(localPath, packageName) + hookFailureSuffix
+}
+
+const hookFailureSuffix = ".post-install-failed"
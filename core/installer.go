@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +13,9 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/klauspost/compress/zstd"
@@ -30,16 +33,23 @@ type PackageInstallerFileSystem interface {
 	contracts.Deleter
 	contracts.SymlinkCreator
 	contracts.Chmod
+	contracts.Renamer
+	contracts.TreeDeleter
+	contracts.EmptyDirectoryDeleter
+	contracts.FileChecker
 }
 
 type PackageInstaller struct {
 	downloader   contracts.Downloader
 	filesystem   PackageInstallerFileSystem
+	cache        contracts.ArchiveCache
 	showProgress bool
 }
 
-func NewPackageInstaller(downloader contracts.Downloader, filesystem PackageInstallerFileSystem, showProgress bool) *PackageInstaller {
-	return &PackageInstaller{downloader: downloader, filesystem: filesystem, showProgress: showProgress}
+// NewPackageInstaller creates an installer that, when a cache is provided (it may be nil),
+// installs archives from the cache whenever possible and caches the archives it downloads.
+func NewPackageInstaller(downloader contracts.Downloader, filesystem PackageInstallerFileSystem, cache contracts.ArchiveCache, showProgress bool) *PackageInstaller {
+	return &PackageInstaller{downloader: downloader, filesystem: filesystem, cache: cache, showProgress: showProgress}
 }
 
 func (this *PackageInstaller) DownloadManifest(remoteAddress url.URL) (manifest contracts.Manifest, err error) {
@@ -51,32 +61,121 @@ func (this *PackageInstaller) DownloadManifest(remoteAddress url.URL) (manifest
 	defer closeResource(body)
 
 	rawManifest, err := io.ReadAll(body)
+	if err != nil {
+		return contracts.Manifest{}, err
+	}
 	err = json.Unmarshal(rawManifest, &manifest)
+	if err != nil {
+		return contracts.Manifest{}, err
+	}
+	manifest.Digest = contracts.ComputeManifestDigest(rawManifest)
+	if this.cache != nil {
+		cacheErr := this.cache.StoreManifest(composeVersionedManifestAddress(remoteAddress, manifest.Version), rawManifest)
+		if cacheErr != nil {
+			log.Printf("[WARN] Downloaded manifest could not be cached: %s", cacheErr)
+		}
+	}
+	return manifest, nil
+}
 
-	return manifest, err
+// composeVersionedManifestAddress returns the address of the manifest for the specific version,
+// which differs from the address of a manifest downloaded for the "latest" version.
+func composeVersionedManifestAddress(remoteAddress url.URL, version string) url.URL {
+	directory, fileName := path.Split(remoteAddress.Path)
+	if version != "" && path.Base(directory) != version {
+		remoteAddress.Path = path.Join(directory, version, fileName)
+	}
+	return remoteAddress
 }
 
+// InstallManifest downloads the manifest and prepares it for the installation request. The local manifest is
+// only written by InstallPackage, once the contents of the package are in place.
 func (this *PackageInstaller) InstallManifest(request contracts.InstallationRequest) (manifest contracts.Manifest, err error) {
 	manifest, err = this.DownloadManifest(request.RemoteAddress)
 	if err != nil {
 		return contracts.Manifest{}, err
 	}
 
+	if request.ManifestDigest != "" && manifest.Digest != request.ManifestDigest {
+		return contracts.Manifest{}, fmt.Errorf("%w: expected %s, got %s", errManifestDigestMismatch, request.ManifestDigest, manifest.Digest)
+	}
+
 	manifest.Name = request.PackageName
-	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
-	if err != nil {
-		return contracts.Manifest{}, err
+	if !request.Selection.IsEmpty() {
+		selection := request.Selection
+		manifest.Selection = &selection
+		manifest.Archive.Contents = selection.Apply(manifest.Archive.Contents)
+	}
+	if len(request.Overridden) > 0 {
+		manifest.Archive.Contents = slices.DeleteFunc(manifest.Archive.Contents, func(item contracts.ArchiveItem) bool {
+			return slices.Contains(request.Overridden, item.Path)
+		})
+	}
+	if !request.Remapping.IsEmpty() {
+		remapping := request.Remapping
+		manifest.Remapping = &remapping
+		manifest.Archive.Contents = this.retainInstalledItems(manifest, request.LocalPath)
 	}
-	this.filesystem.WriteFile(ComposeManifestPath(request.LocalPath, manifest.Name), rawManifest)
 	return manifest, nil
 }
 
+func (this *PackageInstaller) retainInstalledItems(manifest contracts.Manifest, localPath string) (retained []contracts.ArchiveItem) {
+	for _, item := range manifest.Archive.Contents {
+		if _, ok := manifest.ComposeLocalPath(localPath, item.Path); ok {
+			retained = append(retained, item)
+		}
+	}
+	return retained
+}
+
 func (this *PackageInstaller) InstallPackage(manifest contracts.Manifest, request contracts.InstallationRequest) error {
+	key := hex.EncodeToString(manifest.Archive.MD5Checksum)
+	if this.cache == nil || key == "" {
+		return this.downloadPackage(manifest, request, nil)
+	}
+
+	if body, found := this.cache.Load(key); found {
+		err := this.installArchive(body, manifest, request)
+		if err == nil {
+			log.Printf("Installed archive from cache: %s", key)
+			return nil
+		}
+		log.Printf("[WARN] Cached archive %s could not be installed (%s), downloading it instead.", key, err)
+		_ = this.cache.Evict(key)
+	}
+
+	entry, err := this.cache.Store(key)
+	if err != nil {
+		log.Printf("[WARN] Downloaded archive will not be cached: %s", err)
+		return this.downloadPackage(manifest, request, nil)
+	}
+	return this.downloadPackage(manifest, request, entry)
+}
+
+// downloadPackage installs the downloaded archive, copying it to the cache entry (if any),
+// which is only committed once the archive has been installed and its checksum verified.
+func (this *PackageInstaller) downloadPackage(manifest contracts.Manifest, request contracts.InstallationRequest, entry contracts.ArchiveCacheEntry) error {
 	body, err := this.downloader.Download(request.RemoteAddress)
+	if err == nil && entry != nil {
+		body = teeReadCloser{Reader: io.TeeReader(body, entry), Closer: body}
+	}
+	if err == nil {
+		err = this.installArchive(body, manifest, request)
+	}
+	if entry == nil {
+		return err
+	}
 	if err != nil {
+		entry.Abort()
 		return err
 	}
+	if cacheErr := entry.Commit(); cacheErr != nil {
+		log.Printf("[WARN] Downloaded archive could not be cached: %s", cacheErr)
+	}
+	return nil
+}
 
+func (this *PackageInstaller) installArchive(body io.ReadCloser, manifest contracts.Manifest, request contracts.InstallationRequest) error {
 	defer closeResource(body)
 	checksumReader := NewHashReader(body, md5.New())
 
@@ -88,21 +187,80 @@ func (this *PackageInstaller) InstallPackage(manifest contracts.Manifest, reques
 	if err != nil {
 		return err
 	}
-	paths, err := this.extractArchive(decompressor, request, len(manifest.Archive.Contents))
+	staging := NewStagingArea(this.filesystem, request.LocalPath, manifest.Name)
+	staging.Prepare()
+	err = this.extractArchive(decompressor, request, manifest, staging)
 	if err != nil {
-		this.revertFileSystem(paths)
+		staging.Discard()
 		return err
 	}
 	actualChecksum := checksumReader.Sum(nil)
 	if bytes.Compare(actualChecksum, manifest.Archive.MD5Checksum) != 0 {
-		this.revertFileSystem(paths)
+		staging.Discard()
 		return fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actualChecksum, manifest.Archive.MD5Checksum)
 	}
+	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		staging.Discard()
+		return err
+	}
+	// staged last so that it is committed last: the local manifest never describes contents not yet in place
+	this.filesystem.WriteFile(staging.Stage(ComposeManifestPath(request.LocalPath, manifest.Name)), rawManifest)
 
-	return nil
+	return staging.Commit()
 }
 
-func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request contracts.InstallationRequest, itemCount int) (paths []string, err error) {
+// InstallItems restores only the specified items of an installed package. The entries of zip
+// archives are fetched with ranged reads; other archives are streamed only until every one of
+// the items has been extracted. The archive checksum cannot be verified along the way, so
+// the restored items should be verified afterward.
+func (this *PackageInstaller) InstallItems(manifest contracts.Manifest, request contracts.InstallationRequest, items []contracts.ArchiveItem) error {
+	wanted := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		wanted[item.Path] = struct{}{}
+	}
+	reader, err := this.openArchiveItems(manifest, request.RemoteAddress, wanted)
+	if err != nil {
+		return err
+	}
+	defer closeResource(reader)
+
+	staging := NewStagingArea(this.filesystem, request.LocalPath, manifest.Name)
+	staging.Prepare()
+	err = this.extractEntries(reader, request, manifest, staging, wanted)
+	if err != nil {
+		staging.Discard()
+		return err
+	}
+	return staging.Commit()
+}
+
+func (this *PackageInstaller) openArchiveItems(manifest contracts.Manifest, remoteAddress url.URL, wanted map[string]struct{}) (ArchiveReadCloser, error) {
+	if manifest.Archive.CompressionAlgorithm == "zip" {
+		size, err := this.downloader.Size(remoteAddress)
+		if err != nil {
+			return nil, err
+		}
+		return shell.NewRangedZipArchiveReader(NewRangedReader(this.downloader, remoteAddress, size), size, wanted)
+	}
+
+	factory, found := decompressors[manifest.Archive.CompressionAlgorithm]
+	if !found {
+		return nil, errors.New("invalid compression algorithm")
+	}
+	body, err := this.downloader.Download(remoteAddress)
+	if err != nil {
+		return nil, err
+	}
+	decompressor, err := factory(body)
+	if err != nil {
+		closeResource(body)
+		return nil, err
+	}
+	return streamedArchive{ArchiveReader: archiveFormats[""](decompressor), closers: []io.Closer{decompressor, body}}, nil
+}
+
+func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request contracts.InstallationRequest, manifest contracts.Manifest, staging *StagingArea) error {
 	defer closeResource(decompressor)
 	var reader ArchiveReader
 	if archiveReader, ok := decompressor.(ArchiveReader); ok {
@@ -115,23 +273,43 @@ func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request
 		reader.(contracts.DownloadSetter).SetDownloader(request.RemoteAddress, this.downloader)
 	}
 
-	for i := 0; ; i++ {
+	return this.extractEntries(reader, request, manifest, staging, nil)
+}
+
+// extractEntries extracts the installed entries of the archive or, when wanted is not nil,
+// only the wanted entries (and stops reading as soon as all of them have been extracted).
+func (this *PackageInstaller) extractEntries(reader ArchiveReader, request contracts.InstallationRequest, manifest contracts.Manifest, staging *StagingArea, wanted map[string]struct{}) error {
+	itemCount := len(manifest.Archive.Contents)
+	if wanted != nil {
+		itemCount = len(wanted)
+	}
+	for i := 0; wanted == nil || i < itemCount; {
 		header, err := reader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return paths, err
+			return err
+		}
+		if !manifest.Selects(header.Name) {
+			continue
+		}
+		if _, found := wanted[header.Name]; wanted != nil && !found {
+			continue
+		}
+		pathItem, installed := manifest.ComposeLocalPath(request.LocalPath, header.Name)
+		if !installed {
+			continue
 		}
-		pathItem := filepath.Join(request.LocalPath, header.Name)
-		paths = append(paths, pathItem)
+		i++
+		stagedPath := staging.Stage(pathItem)
 		log.Printf("Extracting archive item [%d/%d] \"%s\" [%s] to \"%s\".",
-			i+1, itemCount, header.Name, byteCountToString(header.Size), pathItem)
+			i, itemCount, header.Name, byteCountToString(header.Size), pathItem)
 
 		if header.Typeflag == tar.TypeSymlink {
-			this.filesystem.CreateSymlink(header.Linkname, pathItem)
+			this.filesystem.CreateSymlink(header.Linkname, stagedPath)
 		} else {
-			writer := this.filesystem.Create(pathItem)
+			writer := this.filesystem.Create(stagedPath)
 			progressReader := archive_progress.NewArchiveProgressCounter(header.Size, func(archived, total string, done bool) {
 				if this.showProgress {
 					if done {
@@ -146,18 +324,18 @@ func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request
 			_ = writer.Close()
 			_ = progressReader.Close()
 			if err != nil {
-				return paths, err
+				return err
 			}
 			if !contracts.IsExecutable(os.FileMode(header.Mode)) {
 				continue
 			}
-			err := this.filesystem.Chmod(pathItem, 0755)
+			err := this.filesystem.Chmod(stagedPath, 0755)
 			if err != nil {
-				return paths, err
+				return err
 			}
 		}
 	}
-	return paths, nil
+	return nil
 }
 
 func byteCountToString(size int64) string {
@@ -176,12 +354,6 @@ func byteCountToString(size int64) string {
 	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
-func (this *PackageInstaller) revertFileSystem(paths []string) {
-	for _, path := range paths {
-		this.filesystem.Delete(path)
-	}
-}
-
 func ComposeManifestPath(localPath, packageName string) string {
 	cleanPackageName := strings.ReplaceAll(packageName, "/", "___")
 	fileName := fmt.Sprintf("manifest_%s.json", cleanPackageName)
@@ -217,6 +389,28 @@ type ArchiveReader interface {
 	io.Reader
 }
 
+type ArchiveReadCloser interface {
+	ArchiveReader
+	io.Closer
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type streamedArchive struct {
+	ArchiveReader
+	closers []io.Closer
+}
+
+func (this streamedArchive) Close() error {
+	for _, closer := range this.closers {
+		closeResource(closer)
+	}
+	return nil
+}
+
 var archiveFormats = map[string]func(reader io.Reader) ArchiveReader{
 	"": func(reader io.Reader) ArchiveReader { return tar.NewReader(reader) },
 }
@@ -231,3 +425,5 @@ func closeResource(closer io.Closer) {
 		_ = closer.Close()
 	}
 }
+
+var errManifestDigestMismatch = errors.New("remote manifest does not match the recorded digest")
@@ -2,14 +2,18 @@ package core
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
 	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -17,6 +21,7 @@ import (
 	"github.com/smarty/assertions/should"
 	"github.com/smarty/gunit"
 	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/shell"
 )
 
 func TestPackageInstallerFixture(t *testing.T) {
@@ -33,21 +38,22 @@ type PackageInstallerFixture struct {
 func (this *PackageInstallerFixture) Setup() {
 	this.downloader = &FakeDownloader{}
 	this.filesystem = newInMemoryFileSystem()
-	this.installer = NewPackageInstaller(this.downloader, this.filesystem, true)
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, nil, true)
 }
 
 func (this *PackageInstallerFixture) TestInstallManifest() {
 	originalManifest := contracts.Manifest{Name: "Package/Name", Version: "1.2.3"}
+	raw, _ := json.Marshal(originalManifest)
 	this.downloader.prepareManifestDownload(originalManifest)
 
 	request := this.installationRequest(originalManifest.Name)
 	manifest, err := this.installer.InstallManifest(request)
 
+	originalManifest.Digest = contracts.ComputeManifestDigest(raw)
 	this.So(this.downloader.request, should.Resemble, request.RemoteAddress)
 	this.So(manifest, should.Resemble, originalManifest)
 	this.So(err, should.BeNil)
-	fileName := "local/path/manifest_Package___Name.json"
-	this.So(this.loadLocalManifest(fileName), should.Resemble, originalManifest)
+	this.So(this.filesystem.fileSystem, should.BeEmpty) // written by InstallPackage
 }
 
 func (this *PackageInstallerFixture) loadLocalManifest(fileName string) contracts.Manifest {
@@ -58,6 +64,57 @@ func (this *PackageInstallerFixture) loadLocalManifest(fileName string) contract
 	return localManifest
 }
 
+func (this *PackageInstallerFixture) TestInstallManifestWithSelectionRecordsSelectedContentsOnly() {
+	originalManifest := this.buildManifest(nil, gzipAlgorithm)
+	this.downloader.prepareManifestDownload(originalManifest)
+	request := this.installationRequest("Package/Name")
+	request.Selection = contracts.PathSelection{Include: []string{"Hello"}}
+
+	manifest, err := this.installer.InstallManifest(request)
+
+	this.So(err, should.BeNil)
+	this.So(manifest.Archive.Contents, should.Resemble, []contracts.ArchiveItem{{Path: "Hello/World"}})
+	this.So(manifest.Selection, should.Resemble, &request.Selection)
+}
+
+func (this *PackageInstallerFixture) TestInstallManifestLeavesOutOverriddenItemsWithoutRecordingThem() {
+	originalManifest := this.buildManifest(nil, gzipAlgorithm)
+	this.downloader.prepareManifestDownload(originalManifest)
+	request := this.installationRequest("Package/Name")
+	request.Overridden = []string{"Hello", "Goodbye/World"}
+
+	manifest, err := this.installer.InstallManifest(request)
+
+	this.So(err, should.BeNil)
+	this.So(manifest.Archive.Contents, should.Resemble, []contracts.ArchiveItem{{Path: "Hello/World"}, {Path: "Link"}})
+	this.So(manifest.Selection, should.BeNil)
+}
+
+func (this *PackageInstallerFixture) TestInstallManifestWithMatchingDigest() {
+	originalManifest := contracts.Manifest{Name: "Package/Name", Version: "1.2.3"}
+	raw, _ := json.Marshal(originalManifest)
+	this.downloader.prepareManifestDownload(originalManifest)
+	request := this.installationRequest(originalManifest.Name)
+	request.ManifestDigest = contracts.ComputeManifestDigest(raw)
+
+	manifest, err := this.installer.InstallManifest(request)
+
+	this.So(err, should.BeNil)
+	this.So(manifest.Digest, should.Equal, request.ManifestDigest)
+}
+
+func (this *PackageInstallerFixture) TestInstallManifestWithMismatchedDigestFails() {
+	this.downloader.prepareManifestDownload(contracts.Manifest{Name: "Package/Name", Version: "1.2.3"})
+	request := this.installationRequest("Package/Name")
+	request.ManifestDigest = contracts.ComputeManifestDigest([]byte("republished"))
+
+	manifest, err := this.installer.InstallManifest(request)
+
+	this.So(errors.Is(err, errManifestDigestMismatch), should.BeTrue)
+	this.So(manifest, should.BeZeroValue)
+	this.So(this.filesystem.fileSystem, should.BeEmpty)
+}
+
 func (this *PackageInstallerFixture) TestInstallManifestDownloadError() {
 	downloadError := errors.New("something or other")
 	this.downloader.Error = downloadError
@@ -73,6 +130,15 @@ func (this *PackageInstallerFixture) TestInstallManifestJsonDecodingError() {
 	this.So(manifest, should.BeZeroValue)
 }
 
+func (this *PackageInstallerFixture) TestMalformedManifestHasNoDigest() {
+	this.downloader.prepareMalformedDownload()
+
+	manifest, err := this.installer.DownloadManifest(this.installationRequest("").RemoteAddress)
+
+	this.So(err, should.NotBeNil)
+	this.So(manifest.Digest, should.BeEmpty)
+}
+
 func (this *PackageInstallerFixture) TestInstallPackageToLocalFileSystemUsingGzipCompression() {
 	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
 
@@ -85,6 +151,91 @@ func (this *PackageInstallerFixture) TestInstallPackageToLocalFileSystemUsingGzi
 	this.So(this.filesystem.fileSystem["local/path/Goodbye/World"].Mode(), should.Equal, 0755)
 }
 
+func (this *PackageInstallerFixture) TestInstallPackageWritesLocalManifestOnceContentsAreInPlace() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	manifest := this.buildManifest(checksum, gzipAlgorithm)
+	manifest.Name = "Package/Name"
+
+	err := this.installer.InstallPackage(manifest, this.installationRequest(manifest.Name))
+
+	this.So(err, should.BeNil)
+	this.So(this.loadLocalManifest("local/path/manifest_Package___Name.json"), should.Resemble, manifest)
+}
+
+func (this *PackageInstallerFixture) TestFailedCommitRestoresPreviousContentsAndManifest() {
+	this.filesystem.WriteFile("local/path/Hello/World", []byte("previous version"))
+	this.filesystem.WriteFile("local/path/manifest_Package___Name.json", []byte(`{"name": "Package/Name"}`))
+	this.filesystem.errRename["local/path/Link"] = errors.New("rename failed")
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	manifest := this.buildManifest(checksum, gzipAlgorithm)
+	manifest.Name = "Package/Name"
+
+	err := this.installer.InstallPackage(manifest, this.installationRequest(manifest.Name))
+
+	this.So(err, should.NotBeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("previous version"))
+	this.So(this.filesystem.readFile("local/path/manifest_Package___Name.json"), should.Resemble, []byte(`{"name": "Package/Name"}`))
+	this.So(this.filesystem.files(), should.HaveLength, 2)
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageToDiskStartingWithSymlink() {
+	root, _ := os.MkdirTemp("", "satisfy-installer-")
+	defer func() { _ = os.RemoveAll(root) }()
+	checksum := this.downloader.prepareSymlinkFirstArchiveDownload()
+	this.installer = NewPackageInstaller(this.downloader, shell.NewDiskFileSystem(""), nil, false)
+	request := this.installationRequest("")
+	request.LocalPath = filepath.Join(root, "local")
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, gzipAlgorithm), request)
+
+	this.So(err, should.BeNil)
+	link, _ := os.Readlink(filepath.Join(root, "local", "Link"))
+	this.So(link, should.Equal, "Hello/World")
+	nested, _ := os.Readlink(filepath.Join(root, "local", "Goodbye", "Link"))
+	this.So(nested, should.Equal, "../Hello/World")
+	content, _ := os.ReadFile(filepath.Join(root, "local", "Link"))
+	this.So(string(content), should.Equal, "Hello World")
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageExtractsOnlySelectedContents() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	manifest := this.buildManifest(checksum, gzipAlgorithm)
+	manifest.Selection = &contracts.PathSelection{Exclude: []string{"Goodbye"}}
+
+	err := this.installer.InstallPackage(manifest, this.installationRequest(""))
+
+	this.So(err, should.BeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.readFile("local/path/Link"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.fileSystem, should.NotContainKey, "local/path/Goodbye/World")
+}
+
+func (this *PackageInstallerFixture) TestInstallManifestWithRemappingOmitsStrippedContents() {
+	originalManifest := this.buildManifest(nil, gzipAlgorithm)
+	this.downloader.prepareManifestDownload(originalManifest)
+	request := this.installationRequest("Package/Name")
+	request.Remapping = contracts.PathRemapping{StripComponents: 1}
+
+	manifest, err := this.installer.InstallManifest(request)
+
+	this.So(err, should.BeNil)
+	this.So(manifest.Remapping, should.Resemble, &request.Remapping)
+	this.So(manifest.Archive.Contents, should.Resemble, []contracts.ArchiveItem{{Path: "Hello/World"}, {Path: "Goodbye/World"}})
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageExtractsToRemappedPaths() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	manifest := this.buildManifest(checksum, gzipAlgorithm)
+	manifest.Remapping = &contracts.PathRemapping{Mappings: []contracts.PathMapping{{Source: "Goodbye", Target: "/elsewhere"}}}
+
+	err := this.installer.InstallPackage(manifest, this.installationRequest(""))
+
+	this.So(err, should.BeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.readFile("/elsewhere/World"), should.Resemble, []byte("Goodbye World"))
+	this.So(this.filesystem.fileSystem, should.NotContainKey, "local/path/Goodbye/World")
+}
+
 func (this *PackageInstallerFixture) LongTestInstallPackageToLocalFileSystemUsingZstdCompression() {
 	checksum := this.downloader.prepareArchiveDownload(zstdAlgorithm)
 
@@ -111,7 +262,7 @@ func (this *PackageInstallerFixture) TestInstallPackageInvalidArchive() {
 	err := this.installer.InstallPackage(this.buildManifest(nil, gzipAlgorithm), this.installationRequest(""))
 
 	this.So(err, should.NotBeNil)
-	this.So(this.filesystem.Listing(), should.BeEmpty)
+	this.So(this.filesystem.files(), should.BeEmpty)
 }
 
 func (this *PackageInstallerFixture) TestInstallPackageDownloadError() {
@@ -120,7 +271,7 @@ func (this *PackageInstallerFixture) TestInstallPackageDownloadError() {
 	err := this.installer.InstallPackage(this.buildManifest(nil, gzipAlgorithm), this.installationRequest(""))
 
 	this.So(err, should.NotBeNil)
-	this.So(this.filesystem.Listing(), should.BeEmpty)
+	this.So(this.filesystem.files(), should.BeEmpty)
 }
 
 func (this *PackageInstallerFixture) TestInstallPackageChecksumMismatch() {
@@ -129,7 +280,90 @@ func (this *PackageInstallerFixture) TestInstallPackageChecksumMismatch() {
 	err := this.installer.InstallPackage(this.buildManifest([]byte("mismatch"), gzipAlgorithm), this.installationRequest(""))
 
 	this.So(err, should.NotBeNil)
-	this.So(this.filesystem.Listing(), should.BeEmpty)
+	this.So(this.filesystem.files(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageChecksumMismatchLeavesPreviousContentsInPlace() {
+	this.filesystem.WriteFile("local/path/Hello/World", []byte("previous version"))
+	this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	err := this.installer.InstallPackage(this.buildManifest([]byte("mismatch"), gzipAlgorithm), this.installationRequest(""))
+
+	this.So(err, should.NotBeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("previous version"))
+	this.So(this.filesystem.files(), should.HaveLength, 1)
+}
+
+func (this *PackageInstallerFixture) TestDownloadedArchiveIsCachedOnceInstalled() {
+	cache := newFakeArchiveCache()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, cache, false)
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, gzipAlgorithm), this.installationRequest(""))
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.downloads, should.Equal, 1)
+	this.So(cache.entries[hex.EncodeToString(checksum)], should.Resemble, this.downloader.content)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+}
+
+func (this *PackageInstallerFixture) TestCachedArchiveIsInstalledWithoutDownload() {
+	cache := newFakeArchiveCache()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, cache, false)
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	cache.entries[hex.EncodeToString(checksum)] = this.downloader.content
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, gzipAlgorithm), this.installationRequest(""))
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.downloads, should.Equal, 0)
+	this.So(this.filesystem.readFile("local/path/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+}
+
+func (this *PackageInstallerFixture) TestCorruptCachedArchiveIsReplacedByDownload() {
+	cache := newFakeArchiveCache()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, cache, false)
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	key := hex.EncodeToString(checksum)
+	cache.entries[key] = []byte("corrupt")
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, gzipAlgorithm), this.installationRequest(""))
+
+	this.So(err, should.BeNil)
+	this.So(cache.evicted, should.Resemble, []string{key})
+	this.So(this.downloader.downloads, should.Equal, 1)
+	this.So(cache.entries[key], should.Resemble, this.downloader.content)
+}
+
+func (this *PackageInstallerFixture) TestArchiveFailingChecksumIsNotCached() {
+	cache := newFakeArchiveCache()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, cache, false)
+	this.downloader.prepareArchiveDownload(gzipAlgorithm)
+
+	err := this.installer.InstallPackage(this.buildManifest([]byte("mismatch"), gzipAlgorithm), this.installationRequest(""))
+
+	this.So(err, should.NotBeNil)
+	this.So(cache.entries, should.BeEmpty)
+	this.So(cache.aborted, should.Equal, 1)
+}
+
+func (this *PackageInstallerFixture) TestDownloadedManifestIsCachedAtItsVersionedAddress() {
+	cache := newFakeArchiveCache()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, cache, false)
+	manifest := contracts.Manifest{Name: "Package/Name", Version: "1.2.3"}
+	raw, _ := json.Marshal(manifest)
+
+	this.downloader.prepareManifestDownload(manifest)
+	_, err1 := this.installer.DownloadManifest(url.URL{Scheme: "gcs", Host: "bucket", Path: "/path/Package/Name/manifest.json"})
+	this.downloader.prepareManifestDownload(manifest)
+	_, err2 := this.installer.DownloadManifest(url.URL{Scheme: "gcs", Host: "bucket", Path: "/other/Package/Name/1.2.3/manifest.json"})
+
+	this.So(err1, should.BeNil)
+	this.So(err2, should.BeNil)
+	this.So(cache.manifests, should.Resemble, map[string][]byte{
+		"gcs://bucket/path/Package/Name/1.2.3/manifest.json":  raw,
+		"gcs://bucket/other/Package/Name/1.2.3/manifest.json": raw,
+	})
 }
 
 func (this *PackageInstallerFixture) buildManifest(checksum []byte, compressionAlgorithm string) contracts.Manifest {
@@ -157,32 +391,32 @@ func (this *PackageInstallerFixture) installationRequest(packageName string) con
 ///////////////////////////////////////////////////////////////////////////////////////////////
 
 type FakeDownloader struct {
-	Body    io.ReadCloser
-	Error   error
-	request url.URL
+	Body      io.ReadCloser
+	Error     error
+	request   url.URL
+	content   []byte
+	downloads int
+	seeks     int
 }
 
 func (this *FakeDownloader) Download(request url.URL) (io.ReadCloser, error) {
 	this.request = request
+	this.downloads++
 	return this.Body, this.Error
 }
 
 func (this *FakeDownloader) Seek(request url.URL, start, end int64) (io.ReadCloser, error) {
 	this.request = request
-	buff := bytes.NewBuffer([]byte{})
-	_, err := io.Copy(buff, this.Body)
-	if err != nil {
-		return nil, err
+	this.seeks++
+	if this.Error != nil {
+		return nil, this.Error
 	}
-
-	reader := bytes.NewReader(buff.Bytes()[start:end])
-	return io.NopCloser(reader), this.Error
+	return io.NopCloser(bytes.NewReader(this.content[start : end+1])), nil
 }
 
 func (this *FakeDownloader) Size(request url.URL) (int64, error) {
 	this.request = request
-	buff := bytes.NewBuffer([]byte{})
-	return io.Copy(buff, this.Body)
+	return int64(len(this.content)), this.Error
 }
 
 func (this *FakeDownloader) prepareArchiveDownload(compressionAlgorithm string) []byte {
@@ -211,11 +445,41 @@ func (this *FakeDownloader) prepareArchiveDownload(compressionAlgorithm string)
 	_ = archiveWriter.Close()
 	_ = compressor.Close()
 
-	this.Body = io.NopCloser(bytes.NewReader(writer.Bytes()))
+	this.content = writer.Bytes()
+	this.Body = io.NopCloser(bytes.NewReader(this.content))
 
 	return hasher.Sum(nil)
 }
 
+func (this *FakeDownloader) prepareSymlinkFirstArchiveDownload() []byte {
+	writer := bytes.NewBuffer(nil)
+	compressor := compression[gzipAlgorithm](writer, 4)
+	archiveWriter := tar.NewWriter(compressor)
+	_ = archiveWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "Link", Linkname: "Hello/World"})
+	_ = archiveWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "Goodbye/Link", Linkname: "../Hello/World"})
+	_ = archiveWriter.WriteHeader(&tar.Header{Name: "Hello/World", Size: int64(len("Hello World")), Mode: 0644})
+	_, _ = archiveWriter.Write([]byte("Hello World"))
+	_ = archiveWriter.Close()
+	_ = compressor.Close()
+
+	this.content = writer.Bytes()
+	this.Body = io.NopCloser(bytes.NewReader(this.content))
+	checksum := md5.Sum(this.content)
+	return checksum[:]
+}
+
+func (this *FakeDownloader) prepareZipArchiveDownload() {
+	buffer := bytes.NewBuffer(nil)
+	archiveWriter := zip.NewWriter(buffer)
+	for _, name := range []string{"Hello/World", "Goodbye/World"} {
+		writer, _ := archiveWriter.Create(name)
+		_, _ = io.WriteString(writer, strings.ReplaceAll(name, "/", " "))
+	}
+	_ = archiveWriter.Close()
+	this.content = buffer.Bytes()
+	this.Body = io.NopCloser(bytes.NewReader(this.content))
+}
+
 func (this *FakeDownloader) prepareManifestDownload(manifest contracts.Manifest) {
 	raw, _ := json.Marshal(manifest)
 	this.Body = io.NopCloser(bytes.NewReader(raw))
@@ -225,6 +489,52 @@ func (this *FakeDownloader) prepareMalformedDownload() {
 	this.Body = io.NopCloser(strings.NewReader("malformed"))
 }
 
+type FakeArchiveCache struct {
+	entries   map[string][]byte
+	manifests map[string][]byte
+	evicted   []string
+	aborted   int
+}
+
+func newFakeArchiveCache() *FakeArchiveCache {
+	return &FakeArchiveCache{entries: make(map[string][]byte), manifests: make(map[string][]byte)}
+}
+
+func (this *FakeArchiveCache) Load(key string) (io.ReadCloser, bool) {
+	content, found := this.entries[key]
+	return io.NopCloser(bytes.NewReader(content)), found
+}
+
+func (this *FakeArchiveCache) Store(key string) (contracts.ArchiveCacheEntry, error) {
+	return &fakeArchiveCacheEntry{cache: this, key: key, Buffer: new(bytes.Buffer)}, nil
+}
+
+func (this *FakeArchiveCache) Evict(key string) error {
+	this.evicted = append(this.evicted, key)
+	delete(this.entries, key)
+	return nil
+}
+
+func (this *FakeArchiveCache) StoreManifest(remoteAddress url.URL, rawManifest []byte) error {
+	this.manifests[remoteAddress.String()] = rawManifest
+	return nil
+}
+
+type fakeArchiveCacheEntry struct {
+	*bytes.Buffer
+	cache *FakeArchiveCache
+	key   string
+}
+
+func (this *fakeArchiveCacheEntry) Commit() error {
+	this.cache.entries[this.key] = this.Bytes()
+	return nil
+}
+
+func (this *fakeArchiveCacheEntry) Abort() {
+	this.cache.aborted++
+}
+
 var compression = map[string]func(_ io.Writer, level int) io.WriteCloser{
 	"zstd": func(writer io.Writer, level int) io.WriteCloser {
 		compressor, err := zstd.NewWriter(writer, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
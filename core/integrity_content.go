@@ -6,7 +6,6 @@ import (
 	"hash"
 	"io"
 	"log"
-	"path/filepath"
 
 	"github.com/smarty/satisfy/contracts"
 )
@@ -31,21 +30,37 @@ func (this *FileContentIntegrityCheck) Verify(manifest contracts.Manifest, local
 		return nil
 	}
 	for _, item := range manifest.Archive.Contents {
-		checksum, err := this.calculateChecksum(filepath.Join(localPath, item.Path))
+		err := this.VerifyItem(manifest, localPath, item)
 		if err != nil {
 			return err
 		}
-		if bytes.Compare(checksum, item.MD5Checksum) != 0 {
-			return fmt.Errorf("checksum mismatch for \"%s\"", item.Path)
-		}
 	}
 	log.Printf("Content integrity check passed: [%s @ %s]", manifest.Name, manifest.Version)
 	return nil
 }
 
+// VerifyItem compares the checksum of a single installed archive item with the manifest (whether or not the check is enabled).
+func (this *FileContentIntegrityCheck) VerifyItem(manifest contracts.Manifest, localPath string, item contracts.ArchiveItem) error {
+	fullPath, installed := manifest.ComposeLocalPath(localPath, item.Path)
+	if !installed {
+		return nil
+	}
+	checksum, err := this.calculateChecksum(fullPath)
+	if err != nil {
+		return err
+	}
+	if bytes.Compare(checksum, item.MD5Checksum) != 0 {
+		return fmt.Errorf("checksum mismatch for \"%s\"", item.Path)
+	}
+	return nil
+}
+
 func (this *FileContentIntegrityCheck) calculateChecksum(path string) ([]byte, error) {
 	hasher := this.hasher()
-	info, _ := this.fileSystem.Stat(path)
+	info, err := this.fileSystem.Stat(path)
+	if err != nil {
+		return nil, err
+	}
 	if info.Symlink() != "" {
 		_, err := io.WriteString(hasher, info.Symlink())
 		if err != nil {
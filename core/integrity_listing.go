@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/smarty/satisfy/contracts"
 )
@@ -19,7 +18,10 @@ func NewFileListingIntegrityChecker(fileSystem contracts.FileChecker) *FileListi
 
 func (this *FileListingIntegrityChecker) Verify(manifest contracts.Manifest, localPath string) error {
 	for _, item := range manifest.Archive.Contents {
-		fullPath := filepath.Join(localPath, item.Path)
+		fullPath, installed := manifest.ComposeLocalPath(localPath, item.Path)
+		if !installed {
+			continue
+		}
 		fileInfo, err := this.fileSystem.Stat(fullPath)
 		if os.IsNotExist(err) {
 			return fmt.Errorf("filename not found for \"%s\"", fullPath)
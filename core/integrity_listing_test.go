@@ -57,3 +57,11 @@ func (this *IntegrityListingFixture) TestFileSizeMismatch() {
 
 	this.So(this.checker.Verify(this.manifest, "/local"), should.NotBeNil)
 }
+
+func (this *IntegrityListingFixture) TestRemappedFilesAreFoundAtTheirInstalledLocation() {
+	this.manifest.Remapping = &contracts.PathRemapping{Mappings: []contracts.PathMapping{{Source: "cc", Target: "/elsewhere"}}}
+	this.fileSystem.Delete("/local/cc/c")
+	this.fileSystem.WriteFile("/elsewhere/c", []byte("ccc"))
+
+	this.So(this.checker.Verify(this.manifest, "/local"), should.BeNil)
+}
@@ -32,7 +32,7 @@ func (this *MemoryFixture) TestSizeIsExhastingBuffer() {
 	buffer := &bytes.Buffer{}
 	reader := this.fileSystem.Open("/file.txt")
 	_, _ = io.Copy(buffer, reader)
-	this.So(this.fileSystem.Listing()[0].Size(), should.Equal, len([]byte("Hello World")))
+	this.So(this.fileSystem.files()[0].Size(), should.Equal, len([]byte("Hello World")))
 }
 
 func (this *MemoryFixture) TestReadFileNonExistingFile() {
@@ -58,7 +58,7 @@ func (this *MemoryFixture) TestListing() {
 	this.fileSystem.WriteFile("file1.txt", []byte("1"))
 	this.fileSystem.WriteFile("sub/file0.txt", []byte("12"))
 
-	fileInfo := this.fileSystem.Listing()
+	fileInfo := this.fileSystem.files()
 
 	this.So(fileInfo, should.HaveLength, 3)
 	this.So(fileInfo[0].Path(), should.Equal, "file0.txt")
@@ -74,7 +74,7 @@ func (this *MemoryFixture) TestDelete() {
 
 	this.fileSystem.Delete("/file.txt")
 
-	this.So(this.fileSystem.Listing(), should.BeEmpty)
+	this.So(this.fileSystem.files(), should.BeEmpty)
 }
 
 func (this *MemoryFixture) TestCreateSymlink() {
@@ -82,6 +82,6 @@ func (this *MemoryFixture) TestCreateSymlink() {
 
 	this.fileSystem.CreateSymlink("/source.txt", "/target.txt")
 
-	this.So(this.fileSystem.Listing(), should.HaveLength, 2)
+	this.So(this.fileSystem.files(), should.HaveLength, 2)
 	this.So(this.fileSystem.readFile("/target.txt"), should.Resemble, []byte("Hello World"))
 }
@@ -18,6 +18,8 @@ type inMemoryFileSystem struct {
 	Root         string
 	errReadFile  map[string]error
 	errChmodFile map[string]error
+	errRename    map[string]error
+	errListing   error
 }
 
 func newInMemoryFileSystem() *inMemoryFileSystem {
@@ -25,6 +27,7 @@ func newInMemoryFileSystem() *inMemoryFileSystem {
 		fileSystem:   make(map[string]*file),
 		errReadFile:  make(map[string]error),
 		errChmodFile: make(map[string]error),
+		errRename:    make(map[string]error),
 	}
 }
 
@@ -42,7 +45,14 @@ func (this *inMemoryFileSystem) Stat(path string) (contracts.FileInfo, error) {
 	}
 }
 
-func (this *inMemoryFileSystem) Listing() (files []contracts.FileInfo) {
+func (this *inMemoryFileSystem) Listing() ([]contracts.FileInfo, error) {
+	if this.errListing != nil {
+		return nil, this.errListing
+	}
+	return this.files(), nil
+}
+
+func (this *inMemoryFileSystem) files() (files []contracts.FileInfo) {
 	for _, file := range this.fileSystem {
 		files = append(files, file)
 	}
@@ -124,6 +134,38 @@ func (this *inMemoryFileSystem) Delete(path string) {
 	delete(this.fileSystem, path)
 }
 
+func (this *inMemoryFileSystem) DeleteAll(path string) {
+	for name := range this.fileSystem {
+		if name == path || strings.HasPrefix(name, path+"/") {
+			delete(this.fileSystem, name)
+		}
+	}
+}
+
+func (this *inMemoryFileSystem) DeleteEmptyDirectory(path string) bool {
+	for name := range this.fileSystem {
+		if strings.HasPrefix(name, path+"/") {
+			return false
+		}
+	}
+	delete(this.fileSystem, path)
+	return true
+}
+
+func (this *inMemoryFileSystem) Rename(source, target string) error {
+	if err := this.errRename[target]; err != nil {
+		return err
+	}
+	file, found := this.fileSystem[source]
+	if !found {
+		return os.ErrNotExist
+	}
+	delete(this.fileSystem, source)
+	file.path = target
+	this.fileSystem[target] = file
+	return nil
+}
+
 func (this *inMemoryFileSystem) RootPath() string {
 	return this.Root
 }
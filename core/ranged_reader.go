@@ -0,0 +1,58 @@
+package core
+
+import (
+	"io"
+	"net/url"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// RangedReader provides random access to a remote object through ranged downloads. It reads
+// ahead so that the many small reads made by an archive reader don't each become a separate
+// request. It is not safe for concurrent use.
+type RangedReader struct {
+	downloader    contracts.Downloader
+	remoteAddress url.URL
+	size          int64
+	buffer        []byte
+	offset        int64
+}
+
+func NewRangedReader(downloader contracts.Downloader, remoteAddress url.URL, size int64) *RangedReader {
+	return &RangedReader{downloader: downloader, remoteAddress: remoteAddress, size: size}
+}
+
+func (this *RangedReader) ReadAt(p []byte, offset int64) (n int, err error) {
+	for n < len(p) && offset+int64(n) < this.size {
+		position := offset + int64(n)
+		if position < this.offset || position >= this.offset+int64(len(this.buffer)) {
+			err = this.fill(position, len(p)-n)
+			if err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], this.buffer[position-this.offset:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (this *RangedReader) fill(position int64, length int) error {
+	end := min(position+max(int64(length), rangedReadAhead), this.size)
+	body, err := this.downloader.Seek(this.remoteAddress, position, end-1) // the end of a range is inclusive
+	if err != nil {
+		return err
+	}
+	defer closeResource(body)
+	buffer := make([]byte, end-position)
+	_, err = io.ReadFull(body, buffer)
+	if err != nil {
+		return err
+	}
+	this.buffer, this.offset = buffer, position
+	return nil
+}
+
+const rangedReadAhead = 1024 * 1024
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type ItemInstaller interface {
+	InstallItems(manifest contracts.Manifest, request contracts.InstallationRequest, items []contracts.ArchiveItem) error
+}
+
+// PackageRepairer restores the items of an installed package that fail verification
+// (rather than reinstalling the whole package) and then verifies every item again.
+type PackageRepairer struct {
+	verifier  *ExhaustiveVerifier
+	installer ItemInstaller
+}
+
+func NewPackageRepairer(verifier *ExhaustiveVerifier, installer ItemInstaller) *PackageRepairer {
+	return &PackageRepairer{verifier: verifier, installer: installer}
+}
+
+func (this *PackageRepairer) Repair(dependency contracts.Dependency, manifest contracts.Manifest, localPath string) error {
+	items := this.failingItems(manifest, localPath)
+	if len(items) == 0 {
+		return nil
+	}
+	log.Printf("Repairing %d of %d items of %s", len(items), len(manifest.Archive.Contents), dependency.Title())
+	err := this.installer.InstallItems(manifest, contracts.InstallationRequest{
+		RemoteAddress: dependency.ComposeRemoteAddress(contracts.RemoteArchiveFilename),
+		LocalPath:     localPath,
+		PackageName:   dependency.PackageName,
+	}, items)
+	if err != nil {
+		return fmt.Errorf("failed to restore items of %s: %w", dependency.Title(), err)
+	}
+	if remaining := this.verifier.Verify(manifest, localPath); len(remaining) > 0 {
+		return fmt.Errorf("%d items of %s still fail verification after repair (%s: %s)",
+			len(remaining), dependency.Title(), remaining[0].Kind, remaining[0].Path)
+	}
+	log.Printf("Repaired %s", dependency.Title())
+	return nil
+}
+
+func (this *PackageRepairer) failingItems(manifest contracts.Manifest, localPath string) (items []contracts.ArchiveItem) {
+	failing := make(map[string]struct{})
+	for _, problem := range this.verifier.Verify(manifest, localPath) {
+		failing[problem.Path] = struct{}{}
+	}
+	for _, item := range manifest.Archive.Contents {
+		fullPath, installed := manifest.ComposeLocalPath(localPath, item.Path)
+		if _, found := failing[fullPath]; installed && found {
+			items = append(items, item)
+		}
+	}
+	return items
+}
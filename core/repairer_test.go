@@ -0,0 +1,118 @@
+package core
+
+import (
+	"crypto/md5"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestPackageRepairerFixture(t *testing.T) {
+	gunit.Run(new(PackageRepairerFixture), t)
+}
+
+type PackageRepairerFixture struct {
+	*gunit.Fixture
+	fileSystem *inMemoryFileSystem
+	downloader *FakeDownloader
+	repairer   *PackageRepairer
+	dependency contracts.Dependency
+	manifest   contracts.Manifest
+}
+
+func (this *PackageRepairerFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.downloader = &FakeDownloader{}
+	installer := NewPackageInstaller(this.downloader, this.fileSystem, nil, false)
+	verifier := NewExhaustiveVerifier(this.fileSystem, NewFileContentIntegrityCheck(md5.New, this.fileSystem, true))
+	this.repairer = NewPackageRepairer(verifier, installer)
+	this.dependency = contracts.Dependency{
+		PackageName:    "package",
+		PackageVersion: "1.2.3",
+		RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/path"},
+		LocalDirectory: "local",
+	}
+	this.manifest = contracts.Manifest{
+		Name:    "package",
+		Version: "1.2.3",
+		Archive: contracts.Archive{
+			CompressionAlgorithm: gzipAlgorithm,
+			Contents: []contracts.ArchiveItem{
+				this.item("Hello/World", "Hello World"),
+				this.item("Goodbye/World", "Goodbye World"),
+			},
+		},
+	}
+	this.fileSystem.WriteFile("local/Hello/World", []byte("Hello World"))
+	this.fileSystem.WriteFile("local/Goodbye/World", []byte("Goodbye World"))
+}
+
+func (this *PackageRepairerFixture) item(path, content string) contracts.ArchiveItem {
+	checksum := md5.Sum([]byte(content))
+	return contracts.ArchiveItem{Path: path, Size: int64(len(content)), MD5Checksum: checksum[:]}
+}
+
+func (this *PackageRepairerFixture) Repair() error {
+	return this.repairer.Repair(this.dependency, this.manifest, "local")
+}
+
+func (this *PackageRepairerFixture) TestIntactPackageIsLeftAlone() {
+	err := this.Repair()
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.downloads, should.Equal, 0)
+	this.So(this.downloader.seeks, should.Equal, 0)
+}
+
+func (this *PackageRepairerFixture) TestOnlyCorruptedItemIsRestoredFromStreamedArchive() {
+	this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	this.fileSystem.WriteFile("local/Goodbye/World", []byte("Goodbye Wrld!"))
+	intact := this.fileSystem.fileSystem["local/Hello/World"]
+
+	err := this.Repair()
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.request, should.Resemble, url.URL{Scheme: "gcs", Host: "bucket", Path: "/path/package/1.2.3/archive"})
+	this.So(this.fileSystem.readFile("local/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+	this.So(this.fileSystem.fileSystem["local/Hello/World"], should.Equal, intact)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/Link")
+}
+
+func (this *PackageRepairerFixture) TestMissingItemIsRestoredFromZipArchiveWithRangedReads() {
+	this.downloader.prepareZipArchiveDownload()
+	this.manifest.Archive.CompressionAlgorithm = "zip"
+	this.fileSystem.Delete("local/Hello/World")
+
+	err := this.Repair()
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.downloads, should.Equal, 0)
+	this.So(this.downloader.seeks, should.BeGreaterThan, 0)
+	this.So(this.fileSystem.readFile("local/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.fileSystem.readFile("local/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+}
+
+func (this *PackageRepairerFixture) TestItemsStillFailingVerificationAfterRepairIsAnError() {
+	this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	this.manifest.Archive.Contents[1] = this.item("Goodbye/World", "Something else")
+
+	err := this.Repair()
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "1 items of [package @ 1.2.3] still fail verification")
+}
+
+func (this *PackageRepairerFixture) TestRestorationFailure() {
+	this.fileSystem.Delete("local/Hello/World")
+	this.downloader.Error = errors.New("download failure")
+
+	err := this.Repair()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/Hello/World")
+	this.So(this.fileSystem.readFile("local/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+}
@@ -87,3 +87,20 @@ func (this *RetryClient) Size(request url.URL) (size int64, err error) {
 	}
 	return 0, err
 }
+
+func (this *RetryClient) List(prefix url.URL) (objects []contracts.RemoteObject, err error) {
+	for x := 0; x <= this.maxRetry; x++ {
+		objects, err = this.inner.List(prefix)
+		if err == nil {
+			return objects, nil
+		}
+		if !errors.Is(err, contracts.RetryErr) {
+			return nil, err
+		}
+		if x < this.maxRetry {
+			log.Println("[WARN] list failed, retry imminent.")
+			this.sleep(time.Second * 3)
+		}
+	}
+	return nil, err
+}
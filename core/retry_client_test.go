@@ -103,6 +103,28 @@ func (this *RetryFixture) TestDownloadNoRetryOnRegularErrors() {
 	this.So(this.naps, should.BeEmpty)
 }
 
+func (this *RetryFixture) TestListRetryOnError() {
+	this.fakeClient.error = aRetryError
+
+	objects, err := this.client.List(url.URL{})
+
+	this.So(objects, should.BeNil)
+	this.So(err, should.Equal, aRetryError)
+	this.So(this.fakeClient.listAttempts, should.Equal, 5)
+	this.So(this.naps, should.HaveLength, 4)
+}
+
+func (this *RetryFixture) TestListCallsInner() {
+	this.fakeClient.listObjects = []contracts.RemoteObject{{Path: "/a"}}
+	request := url.URL{Host: "bucket", Path: "/prefix/"}
+
+	objects, err := this.client.List(request)
+
+	this.So(err, should.BeNil)
+	this.So(objects, should.Resemble, this.fakeClient.listObjects)
+	this.So(this.fakeClient.listRequest, should.Resemble, request)
+}
+
 var (
 	aRetryError   = fmt.Errorf("this is a retry error %w", contracts.RetryErr)
 	aRegularError = errors.New("this is a regular error")
@@ -118,6 +140,10 @@ type FakeClient struct {
 	downloadContent  string
 	downloadAttempts int
 
+	listRequest  url.URL
+	listObjects  []contracts.RemoteObject
+	listAttempts int
+
 	error error
 }
 
@@ -144,3 +170,9 @@ func (this *FakeClient) Upload(request contracts.UploadRequest) error {
 	this.uploadAttempts++
 	return this.error
 }
+
+func (this *FakeClient) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	this.listRequest = prefix
+	this.listAttempts++
+	return this.listObjects, this.error
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type StagingFileSystem interface {
+	contracts.Deleter
+	contracts.TreeDeleter
+	contracts.EmptyDirectoryDeleter
+	contracts.Renamer
+	contracts.FileChecker
+}
+
+// StagingArea collects extracted files in a hidden directory within the local directory (one
+// for each package, as packages sharing a local directory may be installed at the same time)
+// so that nothing else in the local directory is touched until the whole archive has been
+// extracted and verified: a failed download or checksum leaves the previous installation as
+// it was. Staging within the local directory (rather than next to it) keeps the renames on
+// the same file system and requires no more than write access to the local directory itself.
+//
+// Committing moves each staged file over its final location with a rename, so each file is
+// replaced atomically and a file that is in use is never truncated. The package as a whole is
+// not replaced atomically: while a commit is in progress, readers may see old and new files
+// side by side. The replaced files are kept as backups until every staged file is in place;
+// when one of them can't be moved into place, the previous files are restored (a restore that
+// fails as well is logged). Switching between complete versions at once is what the versioned
+// layout is for (see VersionedLayout).
+type StagingArea struct {
+	fileSystem StagingFileSystem
+	localPath  string
+	root       string
+	backupRoot string
+	staged     []stagedFile
+}
+
+type stagedFile struct {
+	staged string
+	final  string
+	backup string // the replaced file, while the commit is in progress
+}
+
+func NewStagingArea(fileSystem StagingFileSystem, localPath, packageName string) *StagingArea {
+	root := ComposeStagingDirectory(localPath, packageName)
+	return &StagingArea{
+		fileSystem: fileSystem,
+		localPath:  filepath.Clean(localPath),
+		root:       root,
+		backupRoot: root + backupSuffix,
+	}
+}
+
+// Prepare removes anything left behind by an interrupted installation.
+func (this *StagingArea) Prepare() {
+	this.fileSystem.DeleteAll(this.root)
+	this.fileSystem.DeleteAll(this.backupRoot)
+}
+
+// Stage returns the path at which the file destined for finalPath should be written.
+// Files are committed in the order they were first staged.
+func (this *StagingArea) Stage(finalPath string) string {
+	for _, file := range this.staged {
+		if file.final == finalPath {
+			return file.staged
+		}
+	}
+	staged := this.composePath(this.root, finalPath, stagingSuffix)
+	this.staged = append(this.staged, stagedFile{staged: staged, final: finalPath})
+	return staged
+}
+
+func (this *StagingArea) composePath(root, finalPath, suffix string) string {
+	relative, err := filepath.Rel(this.localPath, finalPath)
+	if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return filepath.Join(root, relative)
+	}
+	// remapped outside of the local directory: stage right next to the final location instead
+	return filepath.Join(filepath.Dir(finalPath), "."+filepath.Base(finalPath)+suffix)
+}
+
+func (this *StagingArea) Commit() error {
+	for i := range this.staged {
+		err := this.replace(&this.staged[i])
+		if err != nil {
+			this.rollback(i)
+			this.discard(this.staged[i:])
+			return fmt.Errorf("could not move staged file into place at %q: %w", this.staged[i].final, err)
+		}
+	}
+	for _, file := range this.staged {
+		if file.backup != "" && !strings.HasPrefix(file.backup, this.backupRoot+string(filepath.Separator)) {
+			this.fileSystem.Delete(file.backup)
+		}
+	}
+	this.fileSystem.DeleteAll(this.backupRoot)
+	this.discard(nil)
+	return nil
+}
+
+func (this *StagingArea) replace(file *stagedFile) error {
+	if _, err := this.fileSystem.Stat(file.final); err == nil {
+		backup := this.composePath(this.backupRoot, file.final, backupSuffix)
+		err = this.fileSystem.Rename(file.final, backup)
+		if err != nil {
+			return err
+		}
+		file.backup = backup
+	}
+	return this.fileSystem.Rename(file.staged, file.final)
+}
+
+// rollback takes the files committed before the failed one back out and restores the files they replaced.
+func (this *StagingArea) rollback(failed int) {
+	for i := failed; i >= 0; i-- {
+		file := this.staged[i]
+		if i < failed {
+			this.fileSystem.Delete(file.final)
+		}
+		if file.backup == "" {
+			continue
+		}
+		err := this.fileSystem.Rename(file.backup, file.final)
+		if err != nil {
+			log.Printf("[WARN] could not restore %q from %q: %s", file.final, file.backup, err)
+		}
+	}
+	this.fileSystem.DeleteAll(this.backupRoot)
+}
+
+func (this *StagingArea) Discard() {
+	this.discard(this.staged)
+}
+
+func (this *StagingArea) discard(staged []stagedFile) {
+	for _, file := range staged {
+		if !strings.HasPrefix(file.staged, this.root+string(filepath.Separator)) {
+			this.fileSystem.Delete(file.staged)
+		}
+	}
+	this.fileSystem.DeleteAll(this.root)
+	this.fileSystem.DeleteEmptyDirectory(filepath.Dir(this.root))
+}
+
+// ComposeStagingDirectory returns the directory within the local directory in which the files of the package are staged.
+func ComposeStagingDirectory(localPath, packageName string) string {
+	return filepath.Join(localPath, StagingDirectory, strings.ReplaceAll(packageName, "/", "___"))
+}
+
+const (
+	// StagingDirectory holds the staging areas of the packages installed in a local directory.
+	StagingDirectory = stagingSuffix
+
+	stagingSuffix = ".satisfy-staging"
+	backupSuffix  = ".backup" + stagingSuffix
+)
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestStagingAreaFixture(t *testing.T) {
+	gunit.Run(new(StagingAreaFixture), t)
+}
+
+type StagingAreaFixture struct {
+	*gunit.Fixture
+
+	fileSystem *inMemoryFileSystem
+	staging    *StagingArea
+}
+
+func (this *StagingAreaFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.fileSystem.WriteFile("/opt/local/a", []byte("old a"))
+	this.staging = NewStagingArea(this.fileSystem, "/opt/local/", "B/C")
+}
+
+func (this *StagingAreaFixture) TestFilesAreStagedWithinLocalDirectory() {
+	this.So(this.staging.Stage("/opt/local/a"), should.Equal, "/opt/local/.satisfy-staging/B___C/a")
+	this.So(this.staging.Stage("/opt/local/b/c"), should.Equal, "/opt/local/.satisfy-staging/B___C/b/c")
+}
+
+func (this *StagingAreaFixture) TestPackagesSharingLocalDirectoryAreStagedApart() {
+	other := NewStagingArea(this.fileSystem, "/opt/local", "D")
+	this.fileSystem.WriteFile(other.Stage("/opt/local/d"), []byte("d"))
+
+	this.staging.Prepare()
+
+	this.So(other.Stage("/opt/local/a"), should.Equal, "/opt/local/.satisfy-staging/D/a")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/opt/local/.satisfy-staging/D/d")
+}
+
+func (this *StagingAreaFixture) TestFilesOutsideLocalDirectoryAreStagedNextToFinalLocation() {
+	this.So(this.staging.Stage("/usr/local/bin/tool"), should.Equal, "/usr/local/bin/.tool.satisfy-staging")
+	this.So(this.staging.Stage("/opt/local-other/x"), should.Equal, "/opt/local-other/.x.satisfy-staging")
+}
+
+func (this *StagingAreaFixture) TestCommitMovesStagedFilesIntoPlace() {
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/a"), []byte("new a"))
+	this.fileSystem.WriteFile(this.staging.Stage("/usr/local/bin/tool"), []byte("tool"))
+
+	err := this.staging.Commit()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.readFile("/opt/local/a"), should.Resemble, []byte("new a"))
+	this.So(this.fileSystem.readFile("/usr/local/bin/tool"), should.Resemble, []byte("tool"))
+	this.So(this.fileSystem.files(), should.HaveLength, 2)
+}
+
+func (this *StagingAreaFixture) TestFailedCommitRestoresReplacedFiles() {
+	this.fileSystem.WriteFile("/usr/local/bin/tool", []byte("old tool"))
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/a"), []byte("new a"))
+	this.fileSystem.WriteFile(this.staging.Stage("/usr/local/bin/tool"), []byte("new tool"))
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/b"), []byte("new b"))
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/manifest_B___C.json"), []byte("{}"))
+	this.fileSystem.errRename["/opt/local/b"] = errors.New("rename failed")
+
+	err := this.staging.Commit()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.readFile("/opt/local/a"), should.Resemble, []byte("old a"))
+	this.So(this.fileSystem.readFile("/usr/local/bin/tool"), should.Resemble, []byte("old tool"))
+	this.So(this.fileSystem.files(), should.HaveLength, 2)
+}
+
+func (this *StagingAreaFixture) TestSuccessfulCommitLeavesNoBackups() {
+	this.fileSystem.WriteFile("/usr/local/bin/tool", []byte("old tool"))
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/a"), []byte("new a"))
+	this.fileSystem.WriteFile(this.staging.Stage("/usr/local/bin/tool"), []byte("new tool"))
+	this.fileSystem.WriteDirectory("/opt/local/.satisfy-staging")
+
+	err := this.staging.Commit()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.readFile("/usr/local/bin/tool"), should.Resemble, []byte("new tool"))
+	this.So(this.fileSystem.files(), should.HaveLength, 2)
+}
+
+func (this *StagingAreaFixture) TestDiscardLeavesLocalDirectoryUntouched() {
+	this.fileSystem.WriteFile(this.staging.Stage("/opt/local/a"), []byte("new a"))
+	this.fileSystem.WriteFile(this.staging.Stage("/usr/local/bin/tool"), []byte("tool"))
+
+	this.staging.Discard()
+
+	this.So(this.fileSystem.readFile("/opt/local/a"), should.Resemble, []byte("old a"))
+	this.So(this.fileSystem.files(), should.HaveLength, 1)
+}
+
+func (this *StagingAreaFixture) TestPrepareRemovesLeftoversFromInterruptedInstallation() {
+	this.fileSystem.WriteFile("/opt/local/.satisfy-staging/B___C/a", []byte("partial"))
+
+	this.staging.Prepare()
+
+	this.So(this.fileSystem.files(), should.HaveLength, 1)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/shell"
+)
+
+type StrayFinderFileSystem interface {
+	contracts.PathLister
+	contracts.FileReader
+	contracts.Deleter
+	contracts.EmptyDirectoryDeleter
+}
+
+// StrayFinder lists the files under a local directory that are not owned by any of the
+// packages installed there. Every manifest found under the local directory is taken into
+// account, so packages that share a directory (or are installed in sub-directories of it)
+// never consider each other's files to be strays. Only the directories referenced by a
+// manifest (those holding a manifest or a file it owns) are considered: anything else
+// under the local directory, such as a source tree, is left alone.
+type StrayFinder struct {
+	fileSystem StrayFinderFileSystem
+}
+
+func NewStrayFinder(fileSystem StrayFinderFileSystem) *StrayFinder {
+	return &StrayFinder{fileSystem: fileSystem}
+}
+
+func (this *StrayFinder) Find(localDirectory string) (strays []string, err error) {
+	localDirectory = filepath.Clean(localDirectory)
+	var candidates []string
+	owned := make(map[string]struct{})
+	referenced := make(map[string]struct{})
+	listing, err := this.fileSystem.Listing()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range listing {
+		path := filepath.Clean(file.Path())
+		relative, err := filepath.Rel(localDirectory, path)
+		if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if file.Mode().IsDir() || isManagedBySatisfy(relative, file) {
+			continue
+		}
+		if isManifestFile(path) {
+			err = this.claimContents(path, owned, referenced)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		candidates = append(candidates, path)
+	}
+	for _, path := range candidates {
+		if _, found := referenced[filepath.Dir(path)]; !found {
+			continue
+		}
+		if _, found := owned[path]; !found {
+			strays = append(strays, path)
+		}
+	}
+	sort.Strings(strays)
+	return strays, nil
+}
+
+// Prune deletes the stray files under the local directory (and any directories left empty).
+func (this *StrayFinder) Prune(localDirectory string) (strays []string, err error) {
+	strays, err = this.Find(localDirectory)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strays {
+		this.fileSystem.Delete(path)
+		pruneEmptyDirectories(this.fileSystem, filepath.Dir(path), localDirectory)
+	}
+	return strays, nil
+}
+
+func (this *StrayFinder) claimContents(manifestPath string, owned, referenced map[string]struct{}) error {
+	raw, err := this.fileSystem.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	var manifest contracts.Manifest
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return fmt.Errorf("malformed manifest at %q: %w", manifestPath, err)
+	}
+	localPath := filepath.Dir(manifestPath)
+	referenced[localPath] = struct{}{}
+	for _, item := range manifest.Archive.Contents {
+		if path, installed := manifest.ComposeLocalPath(localPath, item.Path); installed {
+			path = filepath.Clean(path)
+			owned[path] = struct{}{}
+			referenced[filepath.Dir(path)] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func isManifestFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, "manifest_") && strings.HasSuffix(name, ".json")
+}
+
+// isManagedBySatisfy reports whether the (relative) path belongs to the bookkeeping
+// satisfy keeps within local directories: versioned layouts, staging areas, failed hooks
+// and the lock files of nested local directories.
+func isManagedBySatisfy(relative string, file contracts.FileInfo) bool {
+	for _, component := range strings.Split(relative, string(filepath.Separator)) {
+		if component == VersionsDirectory || strings.HasSuffix(component, stagingSuffix) ||
+			strings.HasSuffix(component, hookFailureSuffix) || strings.HasSuffix(component, shell.LockFileSuffix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(file.Symlink(), VersionsDirectory+string(filepath.Separator))
+}
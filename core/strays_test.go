@@ -0,0 +1,150 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestStrayFinderFixture(t *testing.T) {
+	gunit.Run(new(StrayFinderFixture), t)
+}
+
+type StrayFinderFixture struct {
+	*gunit.Fixture
+
+	fileSystem *inMemoryFileSystem
+	finder     *StrayFinder
+}
+
+func (this *StrayFinderFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.finder = NewStrayFinder(this.fileSystem)
+
+	this.install("/local/manifest_a.json", contracts.Manifest{Name: "a", Archive: contracts.Archive{
+		Contents: []contracts.ArchiveItem{{Path: "/a1"}, {Path: "/nested/a2"}},
+	}})
+	this.install("/local/manifest_b.json", contracts.Manifest{Name: "b", Archive: contracts.Archive{
+		Contents: []contracts.ArchiveItem{{Path: "/b1"}},
+	}})
+}
+
+func (this *StrayFinderFixture) install(manifestPath string, manifest contracts.Manifest) {
+	raw, _ := json.Marshal(manifest)
+	this.fileSystem.WriteFile(manifestPath, raw)
+	localPath := manifestPath[:len(manifestPath)-len("/manifest_"+manifest.Name+".json")]
+	for _, item := range manifest.Archive.Contents {
+		if path, installed := manifest.ComposeLocalPath(localPath, item.Path); installed {
+			this.fileSystem.WriteFile(path, []byte(item.Path))
+		}
+	}
+}
+
+func (this *StrayFinderFixture) TestFilesOwnedByAnyPackageInTheDirectoryAreNotStrays() {
+	strays, err := this.finder.Find("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.BeEmpty)
+}
+
+func (this *StrayFinderFixture) TestUnownedFilesAreStrays() {
+	this.fileSystem.WriteFile("/local/leftover", nil)
+	this.fileSystem.WriteFile("/local/nested/leftover", nil)
+	this.fileSystem.WriteFile("/elsewhere/file", nil)
+	this.fileSystem.WriteDirectory("/local/nested")
+
+	strays, err := this.finder.Find("/local/")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.Resemble, []string{"/local/leftover", "/local/nested/leftover"})
+}
+
+func (this *StrayFinderFixture) TestPackagesInstalledInSubDirectoriesOwnTheirContents() {
+	this.install("/local/sub/manifest_c.json", contracts.Manifest{Name: "c", Archive: contracts.Archive{
+		Contents: []contracts.ArchiveItem{{Path: "/c1"}},
+	}})
+
+	strays, err := this.finder.Find("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.BeEmpty)
+}
+
+func (this *StrayFinderFixture) TestRemappedAndDeselectedContentsAreAccountedFor() {
+	this.install("/local/manifest_d.json", contracts.Manifest{
+		Name:      "d",
+		Archive:   contracts.Archive{Contents: []contracts.ArchiveItem{{Path: "/bin/d"}, {Path: "/bin/sub/e"}, {Path: "/docs/d"}}},
+		Selection: &contracts.PathSelection{Exclude: []string{"docs"}},
+		Remapping: &contracts.PathRemapping{StripComponents: 1},
+	})
+	this.fileSystem.WriteFile("/local/sub/d", nil)
+
+	strays, err := this.finder.Find("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.Resemble, []string{"/local/sub/d"})
+}
+
+func (this *StrayFinderFixture) TestDirectoriesNoManifestReferencesAreLeftAlone() {
+	this.fileSystem.WriteFile("/local/src/main.go", nil)
+	this.fileSystem.WriteFile("/local/src/pkg/file.go", nil)
+	this.fileSystem.WriteFile("/local/nested/leftover", nil)
+
+	strays, err := this.finder.Find("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.Resemble, []string{"/local/nested/leftover"})
+}
+
+func (this *StrayFinderFixture) TestVersionedLayoutStagingAreasAndLockFilesAreIgnored() {
+	this.fileSystem.WriteFile("/local/.versions/1.0.0/file", nil)
+	this.fileSystem.WriteFile("/local/.versions/history.json", nil)
+	this.fileSystem.CreateSymlink(".versions/1.0.0", "/local/current")
+	this.fileSystem.WriteFile("/local/.satisfy-staging/package/file", nil)
+	this.fileSystem.WriteFile("/local/.satisfy-staging/package.backup.satisfy-staging/file", nil)
+	this.fileSystem.WriteFile("/local/.sub.satisfy-lock", nil)
+
+	strays, err := this.finder.Find("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.BeEmpty)
+}
+
+func (this *StrayFinderFixture) TestMalformedManifestIsAnError() {
+	this.fileSystem.WriteFile("/local/manifest_broken.json", []byte("{"))
+
+	_, err := this.finder.Find("/local")
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *StrayFinderFixture) TestListingErrorIsReturned() {
+	this.fileSystem.errListing = errors.New("permission denied")
+	this.fileSystem.WriteFile("/local/leftover", nil)
+
+	strays, err := this.finder.Prune("/local")
+
+	this.So(err, should.Equal, this.fileSystem.errListing)
+	this.So(strays, should.BeEmpty)
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/leftover")
+}
+
+func (this *StrayFinderFixture) TestPruneDeletesStraysAndDirectoriesLeftEmpty() {
+	this.fileSystem.Delete("/local/nested/a2")
+	this.fileSystem.WriteFile("/local/leftover", nil)
+	this.fileSystem.WriteFile("/local/nested/leftover", nil)
+	this.fileSystem.WriteDirectory("/local/nested")
+
+	strays, err := this.finder.Prune("/local")
+
+	this.So(err, should.BeNil)
+	this.So(strays, should.HaveLength, 2)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/leftover")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/nested")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/a1")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/manifest_b.json")
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type PackageUninstallerFileSystem interface {
+	contracts.FileChecker
+	contracts.FileReader
+	contracts.Deleter
+	contracts.EmptyDirectoryDeleter
+	VersionedLayoutFileSystem
+}
+
+type ItemIntegrityCheck interface {
+	VerifyItem(manifest contracts.Manifest, localPath string, item contracts.ArchiveItem) error
+}
+
+// PackageUninstaller removes an installed package according to its local manifest.
+type PackageUninstaller struct {
+	fileSystem PackageUninstallerFileSystem
+	integrity  ItemIntegrityCheck
+	hooks      HookRunner
+	force      bool
+}
+
+func NewPackageUninstaller(fileSystem PackageUninstallerFileSystem, integrity ItemIntegrityCheck, hooks HookRunner, force bool) *PackageUninstaller {
+	return &PackageUninstaller{fileSystem: fileSystem, integrity: integrity, hooks: hooks, force: force}
+}
+
+// Uninstall deletes every file listed in the local manifest of the dependency, prunes
+// directories left empty and finally deletes the local manifest itself. Unless forced,
+// nothing is deleted when any of the installed files has been modified locally. Nothing is
+// deleted either when the pre-uninstall hook of the dependency fails.
+func (this *PackageUninstaller) Uninstall(dependency contracts.Dependency) error {
+	if dependency.IsVersioned() {
+		return this.uninstallVersioned(dependency)
+	}
+	localPath := dependency.LocalDirectory
+	manifestPath := ComposeManifestPath(localPath, dependency.PackageName)
+	manifest, err := this.loadLocalManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	err = this.checkModifications(manifest, localPath)
+	if err != nil {
+		return err
+	}
+	err = this.runPreUninstallHook(dependency, manifest, localPath)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range manifest.Archive.Contents {
+		path, installed := manifest.ComposeLocalPath(localPath, item.Path)
+		if !installed || !this.exists(path) {
+			continue
+		}
+		this.fileSystem.Delete(path)
+		pruneEmptyDirectories(this.fileSystem, filepath.Dir(path), localPath)
+	}
+	if failurePath := ComposeHookFailurePath(localPath, dependency.PackageName); this.exists(failurePath) {
+		this.fileSystem.Delete(failurePath)
+	}
+	this.fileSystem.Delete(manifestPath)
+	log.Printf("Uninstalled [%s @ %s] from %q", dependency.PackageName, manifest.Version, localPath)
+	return nil
+}
+
+func (this *PackageUninstaller) uninstallVersioned(dependency contracts.Dependency) error {
+	layout := NewVersionedLayout(this.fileSystem, dependency.LocalDirectory, dependency.PackageName)
+	current := layout.CurrentVersion()
+	if current == "" {
+		return fmt.Errorf("%s is not installed in %q", dependency.PackageName, dependency.LocalDirectory)
+	}
+	versionPath := layout.VersionPath(current)
+	manifest, err := this.loadLocalManifest(ComposeManifestPath(versionPath, dependency.PackageName))
+	if err != nil {
+		return err
+	}
+
+	err = this.checkModifications(manifest, versionPath)
+	if err != nil {
+		return err
+	}
+	err = this.runPreUninstallHook(dependency, manifest, versionPath)
+	if err != nil {
+		return err
+	}
+
+	this.fileSystem.Delete(layout.CurrentPath())
+	this.fileSystem.DeleteAll(filepath.Join(dependency.LocalDirectory, VersionsDirectory))
+	log.Printf("Uninstalled [%s @ %s] (and all retained versions) from %q", dependency.PackageName, current, dependency.LocalDirectory)
+	return nil
+}
+
+func (this *PackageUninstaller) runPreUninstallHook(dependency contracts.Dependency, manifest contracts.Manifest, localPath string) error {
+	dependency.PackageVersion = manifest.Version
+	return runHook(this.hooks, "pre-uninstall", dependency.PreUninstall, dependency, localPath)
+}
+
+func (this *PackageUninstaller) loadLocalManifest(manifestPath string) (manifest contracts.Manifest, err error) {
+	if !this.exists(manifestPath) {
+		return manifest, fmt.Errorf("not installed: no local manifest found at %q", manifestPath)
+	}
+	raw, err := this.fileSystem.ReadFile(manifestPath)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("malformed local manifest at %q: %w", manifestPath, err)
+	}
+	return manifest, nil
+}
+
+func (this *PackageUninstaller) checkModifications(manifest contracts.Manifest, localPath string) error {
+	var modified []string
+	for _, item := range manifest.Archive.Contents {
+		path, installed := manifest.ComposeLocalPath(localPath, item.Path)
+		if !installed || !this.exists(path) {
+			continue
+		}
+		if this.integrity.VerifyItem(manifest, localPath, item) != nil {
+			modified = append(modified, path)
+		}
+	}
+	if len(modified) == 0 {
+		return nil
+	}
+	if this.force {
+		log.Printf("[WARN] removing locally modified files of [%s @ %s]: %s",
+			manifest.Name, manifest.Version, strings.Join(modified, ", "))
+		return nil
+	}
+	return fmt.Errorf("refusing to uninstall [%s @ %s] because files were modified locally (use -force to remove them anyway): %s",
+		manifest.Name, manifest.Version, strings.Join(modified, ", "))
+}
+
+// pruneEmptyDirectories deletes the directory and its parents for as long as they are empty, stopping at the local path.
+func pruneEmptyDirectories(fileSystem contracts.EmptyDirectoryDeleter, directory, localPath string) {
+	localPath = filepath.Clean(localPath)
+	for directory = filepath.Clean(directory); directory != localPath; directory = filepath.Dir(directory) {
+		relative, err := filepath.Rel(localPath, directory)
+		if err != nil || strings.HasPrefix(relative, "..") {
+			return // never prune outside of the local directory
+		}
+		if !fileSystem.DeleteEmptyDirectory(directory) {
+			return
+		}
+	}
+}
+
+func (this *PackageUninstaller) exists(path string) bool {
+	_, err := this.fileSystem.Stat(path)
+	return !os.IsNotExist(err)
+}
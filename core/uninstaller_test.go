@@ -0,0 +1,205 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestPackageUninstallerFixture(t *testing.T) {
+	gunit.Run(new(PackageUninstallerFixture), t)
+}
+
+type PackageUninstallerFixture struct {
+	*gunit.Fixture
+
+	fileSystem  *inMemoryFileSystem
+	uninstaller *PackageUninstaller
+	hooks       *FakeHookRunner
+	manifest    contracts.Manifest
+	dependency  contracts.Dependency
+}
+
+func (this *PackageUninstallerFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.fileSystem.WriteFile("/local/a", []byte("a"))
+	this.fileSystem.WriteFile("/local/nested/deeper/b", []byte("b"))
+	this.fileSystem.WriteFile("/local/shared/c", []byte("c"))
+	this.fileSystem.WriteFile("/local/shared/unrelated", []byte("unrelated"))
+
+	this.dependency = contracts.Dependency{PackageName: "package", PackageVersion: "1.2.3", LocalDirectory: "/local"}
+	this.manifest = contracts.Manifest{
+		Name:    "package",
+		Version: "1.2.3",
+		Archive: contracts.Archive{
+			Contents: []contracts.ArchiveItem{
+				{Path: "/a", MD5Checksum: []byte("a [HASHED]")},
+				{Path: "/nested/deeper/b", MD5Checksum: []byte("b [HASHED]")},
+				{Path: "/shared/c", MD5Checksum: []byte("c [HASHED]")},
+			},
+		},
+	}
+	this.writeManifest("/local/manifest_package.json", this.manifest)
+	this.hooks = &FakeHookRunner{}
+	this.uninstaller = this.newUninstaller(false)
+}
+
+func (this *PackageUninstallerFixture) newUninstaller(force bool) *PackageUninstaller {
+	hasher := NewFakeHasher()
+	newHasher := func() hash.Hash { hasher.Reset(); return hasher }
+	return NewPackageUninstaller(this.fileSystem, NewFileContentIntegrityCheck(newHasher, this.fileSystem, false), this.hooks, force)
+}
+
+func (this *PackageUninstallerFixture) writeManifest(path string, manifest contracts.Manifest) {
+	raw, _ := json.Marshal(manifest)
+	this.fileSystem.WriteFile(path, raw)
+}
+
+func (this *PackageUninstallerFixture) TestManifestContentsAndManifestAreDeleted() {
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/shared/unrelated")
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 1)
+}
+
+func (this *PackageUninstallerFixture) TestPreUninstallHookRunsBeforeDeletion() {
+	this.dependency.PackageVersion = "latest"
+	this.dependency.PreUninstall = "systemctl stop service"
+	this.hooks.check = func() { this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/a") }
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.hooks.runs, should.Resemble, []FakeHookRun{{
+		command:     "systemctl stop service",
+		directory:   "/local",
+		environment: []string{"SATISFY_PACKAGE_NAME=package", "SATISFY_PACKAGE_VERSION=1.2.3", "SATISFY_PACKAGE_PATH=/local"},
+	}})
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 1)
+}
+
+func (this *PackageUninstallerFixture) TestFailingPreUninstallHookPreventsUninstallation() {
+	this.dependency.PreUninstall = "false"
+	this.hooks.err = errors.New("exit status 1")
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.Resemble, fmt.Errorf("pre-uninstall hook of [package @ 1.2.3] failed: %w", this.hooks.err))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/a")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/manifest_package.json")
+}
+
+func (this *PackageUninstallerFixture) TestEmptyDirectoriesArePrunedWithinLocalDirectoryOnly() {
+	this.fileSystem.WriteDirectory("/local/nested")
+	this.fileSystem.WriteDirectory("/local/nested/deeper")
+	this.fileSystem.WriteDirectory("/local/shared")
+	this.fileSystem.WriteDirectory("/local")
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/nested/deeper")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/nested")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local/shared")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "/local")
+}
+
+func (this *PackageUninstallerFixture) TestFilesAlreadyMissingAreIgnored() {
+	this.fileSystem.Delete("/local/a")
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 1)
+}
+
+func (this *PackageUninstallerFixture) TestLocallyModifiedFilesPreventUninstallation() {
+	this.fileSystem.WriteFile("/local/nested/deeper/b", []byte("modified"))
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "/local/nested/deeper/b")
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 5)
+}
+
+func (this *PackageUninstallerFixture) TestLocallyModifiedFilesAreDeletedWhenForced() {
+	this.fileSystem.WriteFile("/local/nested/deeper/b", []byte("modified"))
+
+	err := this.newUninstaller(true).Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 1)
+}
+
+func (this *PackageUninstallerFixture) TestMissingManifestIsAnError() {
+	this.fileSystem.Delete("/local/manifest_package.json")
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 4)
+}
+
+func (this *PackageUninstallerFixture) TestRemappedContentsAreDeletedFromTheirInstalledLocation() {
+	this.fileSystem.Delete("/local/a")
+	this.fileSystem.WriteFile("/usr/local/bin/a", []byte("a"))
+	this.manifest.Remapping = &contracts.PathRemapping{
+		Mappings: []contracts.PathMapping{{Source: "a", Target: "/usr/local/bin/a"}},
+	}
+	this.writeManifest("/local/manifest_package.json", this.manifest)
+
+	err := this.uninstaller.Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/usr/local/bin/a")
+	this.So(this.fileSystem.fileSystem, should.HaveLength, 1)
+}
+
+func (this *PackageUninstallerFixture) TestVersionedLayoutIsRemovedEntirely() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.fileSystem = newInMemoryFileSystem()
+	layout := NewVersionedLayout(this.fileSystem, "/local", "package")
+	this.fileSystem.WriteFile("/local/.versions/1.2.2/a", []byte("old"))
+	this.fileSystem.WriteFile("/local/.versions/1.2.3/a", []byte("a"))
+	this.writeManifest("/local/.versions/1.2.3/manifest_package.json", contracts.Manifest{
+		Name: "package", Version: "1.2.3",
+		Archive: contracts.Archive{Contents: []contracts.ArchiveItem{{Path: "/a", MD5Checksum: []byte("a [HASHED]")}}},
+	})
+	this.So(layout.Activate("1.2.2"), should.BeNil)
+	this.So(layout.Activate("1.2.3"), should.BeNil)
+
+	err := this.newUninstaller(false).Uninstall(this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.BeEmpty)
+}
+
+//////////////////////////////////////////////////////////
+
+type FakeHookRunner struct {
+	runs  []FakeHookRun
+	check func()
+	err   error
+}
+
+type FakeHookRun struct {
+	command     string
+	directory   string
+	environment []string
+}
+
+func (this *FakeHookRunner) RunHook(command, directory string, environment []string) error {
+	this.runs = append(this.runs, FakeHookRun{command: command, directory: directory, environment: environment})
+	if this.check != nil {
+		this.check()
+	}
+	return this.err
+}
@@ -21,17 +21,12 @@ type UploadConfigLoader struct {
 	stderr  io.Writer
 }
 
+// NewUploadConfigLoader reads the credentials from the credential chain shared with the other commands
+// (see CredentialChain): the first configured source (in the order of SATISFY_CREDENTIAL_CHAIN, if set)
+// supplies them, and a configured source that fails is an error rather than skipped in favor of the next.
 func NewUploadConfigLoader(storage contracts.FileReader, env contracts.Environment, stdin io.Reader, stderr io.Writer) *UploadConfigLoader {
-	vaultAddress, _ := env.LookupEnv("VAULT_ADDR")
-	vaultToken, _ := env.LookupEnv("VAULT_TOKEN")
-
-	reader := gcs.NewCredentialsReader(
-		gcs.CredentialOptions.VaultServer(vaultAddress, vaultToken),
-		gcs.CredentialOptions.EnvironmentReader(env),
-		gcs.CredentialOptions.FileReader(storage))
-
 	return &UploadConfigLoader{
-		reader:  reader,
+		reader:  NewCredentialChain(env, storage),
 		storage: storage,
 		stdin:   stdin,
 		stderr:  stderr,
@@ -94,6 +89,9 @@ func (this *UploadConfigLoader) parseCLI(name string, args []string) (config con
 		_, _ = fmt.Fprintf(this.stderr, "Usage of satisfy %s:", name)
 		flags.PrintDefaults()
 		_, _ = fmt.Fprintln(this.stderr, `
+Google credentials are read from the first source configured among: `+describeCredentialSources(DefaultCredentialChain)+`
+(reorder or restrict them with `+CredentialChainVariable+`); a configured source that fails is an error.
+
 exit code 0: success
 exit code 1: general failure (see stderr for details)
 exit code 2: package has already been uploaded`)
@@ -141,6 +139,17 @@ func (this *UploadConfigLoader) validateConfigJsonValues(config contracts.Upload
 	if config.PackageConfig.RemoteAddressPrefix == nil {
 		return nilRemoteAddressPrefixErr
 	}
+	for _, dependency := range config.PackageConfig.Dependencies {
+		if dependency.PackageName == "" || dependency.PackageVersion == "" {
+			return incompleteDependencyErr
+		}
+		if err := dependency.Validate(); err != nil {
+			return err
+		}
+		if err := contracts.ValidateVersion(dependency.PackageVersion); err != nil {
+			return fmt.Errorf("dependency %q: %w", dependency.PackageName, err)
+		}
+	}
 	return nil
 }
 
@@ -152,4 +161,5 @@ var (
 	blankPackageNameErr          = errors.New("package name should not be blank")
 	blankPackageVersionErr       = errors.New("package version should not be blank")
 	nilRemoteAddressPrefixErr    = errors.New("remote address prefix should not be nil")
+	incompleteDependencyErr      = errors.New("dependencies require a package name and a package version")
 )
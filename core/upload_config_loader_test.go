@@ -149,6 +149,31 @@ func (this *UploadConfigLoaderFixture) TestGoogleCredentialsFileIsMalformed() {
 	this.So(config.GoogleCredentials, should.BeZeroValue)
 }
 
+func (this *UploadConfigLoaderFixture) TestFailingCredentialSourceIsAnErrorRatherThanSkipped() {
+	this.environment["GOOGLE_CREDENTIALS"] = "not base64!"
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "GOOGLE_CREDENTIALS")
+	this.So(config.GoogleCredentials, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestCredentialSourcesFollowTheChainVariable() {
+	this.environment["GOOGLE_CREDENTIALS"] = "not base64!"
+	this.environment[CredentialChainVariable] = "file"
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.GoogleCredentials, should.NotBeZeroValue)
+	this.So(config.CredentialReader, should.NotBeNil)
+}
+
 func (this *UploadConfigLoaderFixture) TestValidateJSONNegativeMaxRetries() {
 	_ = this.prepareValidJSONConfigFile()
 	args := []string{
@@ -221,6 +246,29 @@ func (this *UploadConfigLoaderFixture) TestValidateRemoteAddressPrefixIsNotNil()
 	this.So(err, should.Resemble, nilRemoteAddressPrefixErr)
 }
 
+func (this *UploadConfigLoaderFixture) TestValidateDependenciesAreComplete() {
+	this.pkgConfig.Dependencies = []contracts.ManifestDependency{{PackageName: "other"}}
+	raw, _ := json.Marshal(this.pkgConfig.configure())
+	this.storage.WriteFile("config.json", raw)
+	args := []string{"-json", "config.json"}
+
+	_, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Resemble, incompleteDependencyErr)
+}
+
+func (this *UploadConfigLoaderFixture) TestValidateDependencyVersionConstraint() {
+	this.pkgConfig.Dependencies = []contracts.ManifestDependency{{PackageName: "other", PackageVersion: "^x.y"}}
+	raw, _ := json.Marshal(this.pkgConfig.configure())
+	this.storage.WriteFile("config.json", raw)
+	args := []string{"-json", "config.json"}
+
+	_, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, `dependency "other": `)
+}
+
 func (this *UploadConfigLoaderFixture) prepareValidJSONConfigFile() contracts.PackageConfig {
 	packageConfig := this.pkgConfig.configure()
 	raw, _ := json.Marshal(packageConfig)
@@ -236,6 +284,7 @@ type FakePackageConfig struct {
 	PackageName          string
 	PackageVersion       string
 	RemoteAddressPrefix  *contracts.URL
+	Dependencies         []contracts.ManifestDependency
 }
 
 func NewFakePackageConfig() *FakePackageConfig {
@@ -256,6 +305,7 @@ func (this *FakePackageConfig) configure() contracts.PackageConfig {
 		PackageName:          this.PackageName,
 		PackageVersion:       this.PackageVersion,
 		RemoteAddressPrefix:  this.RemoteAddressPrefix,
+		Dependencies:         this.Dependencies,
 	}
 }
 
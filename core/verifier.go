@@ -0,0 +1,103 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type ProblemKind string
+
+const (
+	ProblemMissing          ProblemKind = "missing"
+	ProblemSizeMismatch     ProblemKind = "size-mismatch"
+	ProblemChecksumMismatch ProblemKind = "checksum-mismatch"
+	ProblemExtra            ProblemKind = "extra"
+)
+
+type VerificationProblem struct {
+	Kind         ProblemKind `json:"kind"`
+	Path         string      `json:"path"`
+	ExpectedSize int64       `json:"expected_size,omitempty"`
+	ActualSize   int64       `json:"actual_size,omitempty"`
+}
+
+// PackageVerification lists every problem found with the installation of a package
+// (or why the installation could not be verified at all).
+type PackageVerification struct {
+	PackageName string                `json:"package_name"`
+	Version     string                `json:"version,omitempty"`
+	LocalPath   string                `json:"local_path"`
+	Error       string                `json:"error,omitempty"`
+	Problems    []VerificationProblem `json:"problems,omitempty"`
+}
+
+func (this PackageVerification) Passed() bool {
+	return this.Error == "" && len(this.Problems) == 0
+}
+
+// ExhaustiveVerifier, unlike the integrity checks used during installation, does not stop
+// at the first problem: every file listed in the installed manifest is checked.
+type ExhaustiveVerifier struct {
+	fileSystem VersionedLayoutFileSystem
+	content    ItemIntegrityCheck
+}
+
+func NewExhaustiveVerifier(fileSystem VersionedLayoutFileSystem, content ItemIntegrityCheck) *ExhaustiveVerifier {
+	return &ExhaustiveVerifier{fileSystem: fileSystem, content: content}
+}
+
+func (this *ExhaustiveVerifier) VerifyDependency(dependency contracts.Dependency) (result PackageVerification) {
+	result.PackageName = dependency.PackageName
+	result.LocalPath = ComposeInstallationPath(this.fileSystem, dependency)
+
+	manifestPath := ComposeManifestPath(result.LocalPath, dependency.PackageName)
+	raw, err := this.fileSystem.ReadFile(manifestPath)
+	if err != nil {
+		result.Error = fmt.Sprintf("not installed: could not read local manifest at %q", manifestPath)
+		return result
+	}
+	var manifest contracts.Manifest
+	if err = json.Unmarshal(raw, &manifest); err != nil {
+		result.Error = fmt.Sprintf("malformed local manifest at %q: %s", manifestPath, err)
+		return result
+	}
+	result.Version = manifest.Version
+	result.Problems = this.Verify(manifest, result.LocalPath)
+	return result
+}
+
+func (this *ExhaustiveVerifier) Verify(manifest contracts.Manifest, localPath string) (problems []VerificationProblem) {
+	for _, item := range manifest.Archive.Contents {
+		fullPath, installed := manifest.ComposeLocalPath(localPath, item.Path)
+		if !installed {
+			continue
+		}
+		info, err := this.fileSystem.Stat(fullPath)
+		if err != nil {
+			problems = append(problems, VerificationProblem{Kind: ProblemMissing, Path: fullPath})
+			continue
+		}
+		if info.Size() != item.Size {
+			problems = append(problems, VerificationProblem{
+				Kind: ProblemSizeMismatch, Path: fullPath, ExpectedSize: item.Size, ActualSize: info.Size(),
+			})
+			continue
+		}
+		if this.content.VerifyItem(manifest, localPath, item) != nil {
+			problems = append(problems, VerificationProblem{Kind: ProblemChecksumMismatch, Path: fullPath})
+		}
+	}
+	return problems
+}
+
+// ComposeInstallationPath returns the directory the dependency's files are installed
+// in, which (for the versioned layout) is the directory of its current version.
+func ComposeInstallationPath(fileSystem VersionedLayoutFileSystem, dependency contracts.Dependency) string {
+	if !dependency.IsVersioned() {
+		return dependency.LocalDirectory
+	}
+	layout := NewVersionedLayout(fileSystem, dependency.LocalDirectory, dependency.PackageName)
+	return layout.VersionPath(layout.CurrentVersion())
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"hash"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestExhaustiveVerifierFixture(t *testing.T) {
+	gunit.Run(new(ExhaustiveVerifierFixture), t)
+}
+
+type ExhaustiveVerifierFixture struct {
+	*gunit.Fixture
+
+	fileSystem *inMemoryFileSystem
+	verifier   *ExhaustiveVerifier
+	dependency contracts.Dependency
+	manifest   contracts.Manifest
+}
+
+func (this *ExhaustiveVerifierFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	hasher := NewFakeHasher()
+	newHasher := func() hash.Hash { hasher.Reset(); return hasher }
+	this.verifier = NewExhaustiveVerifier(this.fileSystem, NewFileContentIntegrityCheck(newHasher, this.fileSystem, false))
+	this.dependency = contracts.Dependency{PackageName: "package", LocalDirectory: "/local"}
+	this.manifest = contracts.Manifest{
+		Name:    "package",
+		Version: "1.2.3",
+		Archive: contracts.Archive{Contents: []contracts.ArchiveItem{
+			{Path: "/a", Size: 1, MD5Checksum: []byte("a [HASHED]")},
+			{Path: "/b", Size: 1, MD5Checksum: []byte("b [HASHED]")},
+			{Path: "/c", Size: 1, MD5Checksum: []byte("c [HASHED]")},
+			{Path: "/d", Size: 1, MD5Checksum: []byte("d [HASHED]")},
+		}},
+	}
+	this.fileSystem.WriteFile("/local/a", []byte("a"))
+	this.fileSystem.WriteFile("/local/b", []byte("x"))
+	this.fileSystem.WriteFile("/local/c", []byte("cc"))
+	this.writeManifest("/local/manifest_package.json")
+}
+
+func (this *ExhaustiveVerifierFixture) writeManifest(path string) {
+	raw, _ := json.Marshal(this.manifest)
+	this.fileSystem.WriteFile(path, raw)
+}
+
+func (this *ExhaustiveVerifierFixture) TestEveryProblemIsReported() {
+	result := this.verifier.VerifyDependency(this.dependency)
+
+	this.So(result.Passed(), should.BeFalse)
+	this.So(result.Version, should.Equal, "1.2.3")
+	this.So(result.LocalPath, should.Equal, "/local")
+	this.So(result.Problems, should.Resemble, []VerificationProblem{
+		{Kind: ProblemChecksumMismatch, Path: "/local/b"},
+		{Kind: ProblemSizeMismatch, Path: "/local/c", ExpectedSize: 1, ActualSize: 2},
+		{Kind: ProblemMissing, Path: "/local/d"},
+	})
+}
+
+func (this *ExhaustiveVerifierFixture) TestIntactInstallationPasses() {
+	this.manifest.Archive.Contents = this.manifest.Archive.Contents[:1]
+	this.writeManifest("/local/manifest_package.json")
+
+	result := this.verifier.VerifyDependency(this.dependency)
+
+	this.So(result.Passed(), should.BeTrue)
+}
+
+func (this *ExhaustiveVerifierFixture) TestMissingManifestIsReported() {
+	this.fileSystem.Delete("/local/manifest_package.json")
+
+	result := this.verifier.VerifyDependency(this.dependency)
+
+	this.So(result.Passed(), should.BeFalse)
+	this.So(result.Error, should.StartWith, "not installed")
+}
+
+func (this *ExhaustiveVerifierFixture) TestVersionedLayoutVerifiesCurrentVersion() {
+	this.dependency.Layout = contracts.VersionedLayout
+	this.manifest.Archive.Contents = this.manifest.Archive.Contents[:1]
+	this.writeManifest("/local/.versions/1.2.3/manifest_package.json")
+	_ = NewVersionedLayout(this.fileSystem, "/local", "package").Activate("1.2.3")
+
+	result := this.verifier.VerifyDependency(this.dependency)
+
+	this.So(result.LocalPath, should.Equal, "/local/.versions/1.2.3")
+	this.So(result.Problems, should.Resemble, []VerificationProblem{{Kind: ProblemMissing, Path: "/local/.versions/1.2.3/a"}})
+}
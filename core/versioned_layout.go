@@ -0,0 +1,171 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+type VersionedLayoutFileSystem interface {
+	contracts.FileReader
+	contracts.FileWriter
+	contracts.FileChecker
+	contracts.SymlinkCreator
+	contracts.Renamer
+	contracts.TreeDeleter
+}
+
+// VersionedLayout installs each version of a package into its own directory
+// (LocalDirectory/.versions/<version>) and refers to the active version through
+// a "current" symlink. Versions are recorded in a history file in the order they
+// were activated, most recent last, which drives rollback and garbage collection.
+// A rollback is recorded as well, along with the version requested at the time,
+// so that installations keep the version rolled back to until another is requested.
+type VersionedLayout struct {
+	fileSystem     VersionedLayoutFileSystem
+	localDirectory string
+	packageName    string
+}
+
+func NewVersionedLayout(fileSystem VersionedLayoutFileSystem, localDirectory, packageName string) *VersionedLayout {
+	return &VersionedLayout{fileSystem: fileSystem, localDirectory: localDirectory, packageName: packageName}
+}
+
+func (this *VersionedLayout) CurrentPath() string {
+	return filepath.Join(this.localDirectory, CurrentVersionLink)
+}
+
+func (this *VersionedLayout) VersionPath(version string) string {
+	return filepath.Join(this.localDirectory, VersionsDirectory, version)
+}
+
+func (this *VersionedLayout) historyPath() string {
+	return filepath.Join(this.localDirectory, VersionsDirectory, "history.json")
+}
+
+func (this *VersionedLayout) rollbackPath() string {
+	return filepath.Join(this.localDirectory, VersionsDirectory, "rollback.json")
+}
+
+// CurrentVersion returns the version the "current" symlink refers to, or "" when nothing has been activated yet.
+func (this *VersionedLayout) CurrentVersion() string {
+	info, err := this.fileSystem.Stat(this.CurrentPath())
+	if err != nil || filepath.Dir(info.Symlink()) != VersionsDirectory {
+		return ""
+	}
+	return filepath.Base(info.Symlink())
+}
+
+func (this *VersionedLayout) History() (history []string) {
+	raw, err := this.fileSystem.ReadFile(this.historyPath())
+	if err != nil {
+		return nil
+	}
+	_ = json.Unmarshal(raw, &history)
+	return history
+}
+
+func (this *VersionedLayout) HasVersion(version string) bool {
+	_, err := this.fileSystem.Stat(ComposeManifestPath(this.VersionPath(version), this.packageName))
+	return err == nil
+}
+
+// Activate atomically re-points the "current" symlink at the specified version.
+func (this *VersionedLayout) Activate(version string) error {
+	history := slices.DeleteFunc(this.History(), func(item string) bool { return item == version })
+	return this.activate(version, append(history, version))
+}
+
+// Rollback re-activates the previously active version. The version rolled back from
+// is moved to the oldest position of the history so it is the first to be collected.
+// The version rolled back to is kept for as long as the requested version (as listed)
+// stays the same (see RolledBack).
+func (this *VersionedLayout) Rollback(requested string) (version string, err error) {
+	current := this.CurrentVersion()
+	history := slices.DeleteFunc(this.History(), func(item string) bool { return item == current })
+	if current == "" || len(history) == 0 {
+		return "", errNoPreviousVersion
+	}
+	previous := history[len(history)-1]
+	if !this.HasVersion(previous) {
+		return "", fmt.Errorf("previous version %q is no longer installed at %q", previous, this.VersionPath(previous))
+	}
+	err = this.activate(previous, append([]string{current}, history...))
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.MarshalIndent(rollback{Requested: requested, Version: previous}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	this.fileSystem.WriteFile(this.rollbackPath(), raw)
+	return previous, nil
+}
+
+// RolledBack returns the version rolled back to while the requested version was the same, or "".
+func (this *VersionedLayout) RolledBack(requested string) string {
+	raw, err := this.fileSystem.ReadFile(this.rollbackPath())
+	if err != nil {
+		return ""
+	}
+	var recorded rollback
+	if json.Unmarshal(raw, &recorded) != nil || recorded.Requested != requested {
+		return ""
+	}
+	return recorded.Version
+}
+
+// ForgetRollback lets installations activate the requested version again.
+func (this *VersionedLayout) ForgetRollback() {
+	if _, err := this.fileSystem.Stat(this.rollbackPath()); err == nil {
+		this.fileSystem.DeleteAll(this.rollbackPath())
+	}
+}
+
+type rollback struct {
+	Requested string `json:"requested"`
+	Version   string `json:"version"`
+}
+
+func (this *VersionedLayout) activate(version string, history []string) error {
+	temporary := this.CurrentPath() + ".next"
+	this.fileSystem.CreateSymlink(filepath.Join(VersionsDirectory, version), temporary)
+	err := this.fileSystem.Rename(temporary, this.CurrentPath())
+	if err != nil {
+		return fmt.Errorf("could not activate version %q: %w", version, err)
+	}
+	return this.writeHistory(history)
+}
+
+// Collect removes all but the most recently activated versions.
+func (this *VersionedLayout) Collect(retain int) error {
+	history := this.History()
+	if len(history) <= retain {
+		return nil
+	}
+	collected := history[:len(history)-retain]
+	for _, version := range collected {
+		this.fileSystem.DeleteAll(this.VersionPath(version))
+	}
+	return this.writeHistory(history[len(collected):])
+}
+
+func (this *VersionedLayout) writeHistory(history []string) error {
+	raw, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	this.fileSystem.WriteFile(this.historyPath(), raw)
+	return nil
+}
+
+var errNoPreviousVersion = errors.New("there is no previous version to roll back to")
+
+const (
+	VersionsDirectory  = ".versions"
+	CurrentVersionLink = "current"
+)
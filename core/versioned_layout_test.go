@@ -0,0 +1,134 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestVersionedLayoutFixture(t *testing.T) {
+	gunit.Run(new(VersionedLayoutFixture), t)
+}
+
+type VersionedLayoutFixture struct {
+	*gunit.Fixture
+
+	fileSystem *inMemoryFileSystem
+	layout     *VersionedLayout
+}
+
+func (this *VersionedLayoutFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.layout = NewVersionedLayout(this.fileSystem, "/local", "package")
+}
+
+func (this *VersionedLayoutFixture) install(version string) {
+	this.fileSystem.WriteFile(ComposeManifestPath(this.layout.VersionPath(version), "package"), []byte("{}"))
+	this.fileSystem.WriteFile(this.layout.VersionPath(version)+"/file", []byte(version))
+}
+
+func (this *VersionedLayoutFixture) TestNothingActivated() {
+	this.So(this.layout.CurrentVersion(), should.BeEmpty)
+	this.So(this.layout.History(), should.BeEmpty)
+}
+
+func (this *VersionedLayoutFixture) TestActivatePointsCurrentAtVersion() {
+	this.install("1.0.0")
+
+	err := this.layout.Activate("1.0.0")
+
+	this.So(err, should.BeNil)
+	this.So(this.layout.CurrentVersion(), should.Equal, "1.0.0")
+	this.So(this.fileSystem.fileSystem["/local/current"].symlink, should.Equal, ".versions/1.0.0")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/current.next")
+}
+
+func (this *VersionedLayoutFixture) TestReactivatingMovesVersionToMostRecent() {
+	this.install("1.0.0")
+	this.install("2.0.0")
+	_ = this.layout.Activate("1.0.0")
+	_ = this.layout.Activate("2.0.0")
+
+	_ = this.layout.Activate("1.0.0")
+
+	this.So(this.layout.History(), should.Resemble, []string{"2.0.0", "1.0.0"})
+}
+
+func (this *VersionedLayoutFixture) TestRollbackReactivatesPreviousVersion() {
+	this.install("1.0.0")
+	this.install("2.0.0")
+	this.install("3.0.0")
+	_ = this.layout.Activate("1.0.0")
+	_ = this.layout.Activate("2.0.0")
+	_ = this.layout.Activate("3.0.0")
+
+	version, err := this.layout.Rollback("^3.0")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "2.0.0")
+	this.So(this.fileSystem.fileSystem["/local/current"].symlink, should.Equal, ".versions/2.0.0")
+	this.So(this.layout.History(), should.Resemble, []string{"3.0.0", "1.0.0", "2.0.0"})
+	this.So(this.layout.RolledBack("^3.0"), should.Equal, "2.0.0")
+	this.So(this.layout.RolledBack("^4.0"), should.BeEmpty)
+}
+
+func (this *VersionedLayoutFixture) TestCurrentVersionIsTheOneTheSymlinkRefersTo() {
+	this.install("1.0.0")
+	this.install("2.0.0")
+	_ = this.layout.Activate("1.0.0")
+	_ = this.layout.Activate("2.0.0")
+
+	this.fileSystem.CreateSymlink(".versions/1.0.0", "/local/current") // e.g. the history was left behind by an interrupted activation
+
+	this.So(this.layout.CurrentVersion(), should.Equal, "1.0.0")
+}
+
+func (this *VersionedLayoutFixture) TestForgottenRollback() {
+	this.install("1.0.0")
+	this.install("2.0.0")
+	_ = this.layout.Activate("1.0.0")
+	_ = this.layout.Activate("2.0.0")
+	_, _ = this.layout.Rollback("2.0.0")
+
+	this.layout.ForgetRollback()
+
+	this.So(this.layout.RolledBack("2.0.0"), should.BeEmpty)
+}
+
+func (this *VersionedLayoutFixture) TestRollbackWithoutPreviousVersion() {
+	this.install("1.0.0")
+	_ = this.layout.Activate("1.0.0")
+
+	_, err := this.layout.Rollback("^3.0")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.layout.CurrentVersion(), should.Equal, "1.0.0")
+}
+
+func (this *VersionedLayoutFixture) TestRollbackToVersionNoLongerInstalled() {
+	this.install("2.0.0")
+	_ = this.layout.Activate("1.0.0")
+	_ = this.layout.Activate("2.0.0")
+
+	_, err := this.layout.Rollback("^3.0")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.layout.CurrentVersion(), should.Equal, "2.0.0")
+}
+
+func (this *VersionedLayoutFixture) TestCollectRemovesAllButRetainedVersions() {
+	for _, version := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		this.install(version)
+		_ = this.layout.Activate(version)
+	}
+
+	err := this.layout.Collect(2)
+
+	this.So(err, should.BeNil)
+	this.So(this.layout.History(), should.Resemble, []string{"2.0.0", "3.0.0"})
+	this.So(this.layout.HasVersion("1.0.0"), should.BeFalse)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "/local/.versions/1.0.0/file")
+	this.So(this.layout.HasVersion("2.0.0"), should.BeTrue)
+	this.So(this.layout.HasVersion("3.0.0"), should.BeTrue)
+}
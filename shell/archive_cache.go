@@ -0,0 +1,251 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// DiskArchiveCache keeps cached archives as files named after their keys in a single
+// directory, which may be shared by any number of processes: entries are written to a
+// temporary file and renamed into place, so readers only ever see complete archives,
+// and an entry evicted while it is being read stays readable until it is closed.
+// Whenever an entry is committed the least recently used entries are evicted until
+// the total size of the cache is within the maximum size (if any).
+type DiskArchiveCache struct {
+	directory string
+	maxSize   int64
+}
+
+func NewDiskArchiveCache(directory string, maxSize int64) *DiskArchiveCache {
+	return &DiskArchiveCache{directory: directory, maxSize: maxSize}
+}
+
+// DefaultArchiveCacheDirectory is $SATISFY_CACHE_DIR or, if that isn't set, the satisfy
+// directory within the user's cache directory (e.g. ~/.cache/satisfy).
+func DefaultArchiveCacheDirectory() string {
+	if directory, set := os.LookupEnv("SATISFY_CACHE_DIR"); set && directory != "" {
+		return directory
+	}
+	directory, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "satisfy-cache")
+	}
+	return filepath.Join(directory, "satisfy")
+}
+
+func (this *DiskArchiveCache) Directory() string {
+	return this.directory
+}
+
+// ManifestDirectory is where manifests are kept, at paths mirroring <bucket>/<path> of their remote addresses.
+func (this *DiskArchiveCache) ManifestDirectory() string {
+	return filepath.Join(this.directory, cachedManifestDirectory)
+}
+
+func (this *DiskArchiveCache) Load(key string) (io.ReadCloser, bool) {
+	if !isArchiveCacheKey(key) {
+		return nil, false
+	}
+	path := this.path(key)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now) // the modification time records when the entry was last used
+	return file, true
+}
+
+func (this *DiskArchiveCache) Store(key string) (contracts.ArchiveCacheEntry, error) {
+	if !isArchiveCacheKey(key) {
+		return nil, fmt.Errorf("invalid archive cache key: %q", key)
+	}
+	err := os.MkdirAll(this.directory, 0755)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(this.directory, key+"-*"+partialArchiveSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return &diskArchiveCacheEntry{cache: this, file: file, final: this.path(key)}, nil
+}
+
+func (this *DiskArchiveCache) Evict(key string) error {
+	if !isArchiveCacheKey(key) {
+		return nil
+	}
+	err := os.Remove(this.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (this *DiskArchiveCache) StoreManifest(remoteAddress url.URL, rawManifest []byte) error {
+	path := composeMirrorPath(this.ManifestDirectory(), remoteAddress)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*"+partialArchiveSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(rawManifest)
+	err = errors.Join(err, file.Close())
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+// HasArchive reports whether the archive is cached (without marking it as used).
+func (this *DiskArchiveCache) HasArchive(key string) bool {
+	if !isArchiveCacheKey(key) {
+		return false
+	}
+	_, err := os.Stat(this.path(key))
+	return err == nil
+}
+
+// Clear evicts every cached archive and removes every cached manifest.
+func (this *DiskArchiveCache) Clear() (evicted []CachedArchive, err error) {
+	evicted, err = this.Prune(0)
+	if err != nil {
+		return evicted, err
+	}
+	return evicted, os.RemoveAll(this.ManifestDirectory())
+}
+
+// Entries returns every cached archive, most recently used first.
+func (this *DiskArchiveCache) Entries() (entries []CachedArchive, err error) {
+	items, err := os.ReadDir(this.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		key, found := strings.CutSuffix(item.Name(), cachedArchiveSuffix)
+		if !found || !item.Type().IsRegular() {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			continue // evicted by another process in the meantime
+		}
+		entries = append(entries, CachedArchive{Key: key, Size: info.Size(), LastUsed: info.ModTime()})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	return entries, nil
+}
+
+// Prune evicts the least recently used archives until the total size of the cache is at
+// most maxSize (so a maxSize of 0 evicts everything). Temporary files abandoned by
+// interrupted downloads are removed as well.
+func (this *DiskArchiveCache) Prune(maxSize int64) (evicted []CachedArchive, err error) {
+	entries, err := this.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		err = this.Evict(entries[i].Key)
+		if err != nil {
+			return evicted, err
+		}
+		total -= entries[i].Size
+		evicted = append(evicted, entries[i])
+	}
+	this.removeAbandonedFiles()
+	return evicted, nil
+}
+
+func (this *DiskArchiveCache) removeAbandonedFiles() {
+	paths, _ := filepath.Glob(filepath.Join(this.directory, "*"+partialArchiveSuffix))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > abandonedArchiveAge {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+func (this *DiskArchiveCache) path(key string) string {
+	return filepath.Join(this.directory, key+cachedArchiveSuffix)
+}
+
+func isArchiveCacheKey(key string) bool {
+	return key != "" && strings.Trim(key, "0123456789abcdef") == ""
+}
+
+type CachedArchive struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type diskArchiveCacheEntry struct {
+	cache *DiskArchiveCache
+	file  *os.File
+	final string
+	err   error
+}
+
+func (this *diskArchiveCacheEntry) Write(p []byte) (int, error) {
+	if this.err == nil {
+		_, this.err = this.file.Write(p)
+	}
+	return len(p), nil
+}
+
+func (this *diskArchiveCacheEntry) Commit() error {
+	err := errors.Join(this.err, this.file.Close())
+	if err == nil {
+		err = os.Rename(this.file.Name(), this.final)
+	}
+	if err != nil {
+		_ = os.Remove(this.file.Name())
+		return err
+	}
+	if this.cache.maxSize > 0 {
+		_, err = this.cache.Prune(this.cache.maxSize)
+	}
+	return err
+}
+
+func (this *diskArchiveCacheEntry) Abort() {
+	_ = this.file.Close()
+	_ = os.Remove(this.file.Name())
+}
+
+// composeMirrorPath returns the path below the directory that mirrors <bucket>/<path> of the remote address.
+func composeMirrorPath(directory string, remoteAddress url.URL) string {
+	return filepath.Join(directory, filepath.FromSlash(path.Join(remoteAddress.Host, path.Clean("/"+remoteAddress.Path))))
+}
+
+const (
+	cachedManifestDirectory = "manifests"
+	cachedArchiveSuffix     = ".archive"
+	partialArchiveSuffix    = ".partial"
+	abandonedArchiveAge     = time.Hour
+)
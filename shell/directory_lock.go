@@ -0,0 +1,191 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DirectoryLocker takes advisory locks on install directories so that concurrent installations
+// (by other processes or within this one) into the same directory wait for each other. The lock
+// of a directory is a file next to it, created exclusively and holding a description of its
+// holder. A lock left behind by a process that no longer runs (on this host) is stale and is
+// taken over.
+type DirectoryLocker struct {
+	timeout time.Duration
+	poll    time.Duration
+
+	mutex   sync.Mutex
+	holders map[string]chan struct{}
+}
+
+func NewDirectoryLocker(timeout time.Duration) *DirectoryLocker {
+	return &DirectoryLocker{timeout: timeout, poll: time.Second, holders: make(map[string]chan struct{})}
+}
+
+type LockHolder struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+func (this LockHolder) String() string {
+	return fmt.Sprintf("process %d on %s (since %s)", this.PID, this.Host, this.Acquired.Format(time.RFC3339))
+}
+
+// Lock waits (up to the timeout) for the lock of the directory and returns the function that releases it.
+func (this *DirectoryLocker) Lock(directory string) (release func(), err error) {
+	path, err := ComposeLockPath(directory)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(this.timeout)
+	held := this.holder(path)
+	select {
+	case held <- struct{}{}:
+	default:
+		log.Printf("Waiting for the lock of %s held by another installation within this process...", directory)
+		select {
+		case held <- struct{}{}:
+		case <-time.After(time.Until(deadline)):
+			return nil, fmt.Errorf("%s is locked by another installation within this process; gave up after %s", directory, this.timeout)
+		}
+	}
+	err = this.acquire(path, directory, deadline)
+	if err != nil {
+		<-held
+		return nil, err
+	}
+	return func() {
+		_ = os.Remove(path)
+		<-held
+	}, nil
+}
+
+func (this *DirectoryLocker) holder(path string) chan struct{} {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	held, found := this.holders[path]
+	if !found {
+		held = make(chan struct{}, 1)
+		this.holders[path] = held
+	}
+	return held
+}
+
+func (this *DirectoryLocker) acquire(path, directory string, deadline time.Time) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	waiting := false
+	for {
+		err = this.create(path)
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		holder, inspected, stale := inspectLock(path)
+		if stale && takeOver(path, inspected) {
+			log.Printf("[WARN] Removed stale lock of %s held by %s.", directory, holder)
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%s is locked by %s (lock file: %s); gave up after %s", directory, holder, path, this.timeout)
+		}
+		if !waiting {
+			log.Printf("Waiting for the lock of %s held by %s...", directory, holder)
+			waiting = true
+		}
+		time.Sleep(min(this.poll, time.Until(deadline)))
+	}
+}
+
+func (this *DirectoryLocker) create(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	err = json.NewEncoder(file).Encode(LockHolder{PID: os.Getpid(), Host: host, Acquired: time.Now()})
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// inspectLock describes the holder of the lock (and returns the lock file as read) and whether the lock is
+// stale: held by a process that no longer runs on this host or (when its holder is unknown) not written
+// completely long ago.
+func inspectLock(path string) (holder LockHolder, raw []byte, stale bool) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return holder, nil, false // released in the meantime
+	}
+	if err != nil || json.Unmarshal(raw, &holder) != nil || holder.PID == 0 {
+		info, statErr := os.Stat(path)
+		holder.Host = "an unknown host"
+		return holder, raw, statErr == nil && time.Since(info.ModTime()) > incompleteLockAge
+	}
+	host, _ := os.Hostname()
+	return holder, raw, holder.Host == host && !processExists(holder.PID)
+}
+
+// takeOver removes the stale lock unless another waiter has taken it over (and replaced it by a lock
+// of its own) since it was inspected. Only the waiter that creates the takeover guard (exclusively, like
+// the lock itself) may remove the lock, once it is sure that it still is the stale lock it inspected; the
+// other waiters keep waiting. A guard left behind by a waiter that didn't finish is removed after a while.
+func takeOver(path string, inspected []byte) bool {
+	guard := strings.TrimSuffix(path, LockFileSuffix) + "-takeover" + LockFileSuffix // ignored like the lock itself
+	file, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if info, statErr := os.Stat(guard); statErr == nil && time.Since(info.ModTime()) > incompleteLockAge {
+			_ = os.Remove(guard)
+		}
+		return false
+	}
+	_ = file.Close()
+	defer func() { _ = os.Remove(guard) }()
+
+	raw, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(raw, inspected) {
+		return false // released, or taken over by another waiter, in the meantime
+	}
+	return os.Remove(path) == nil
+}
+
+func processExists(pid int) bool {
+	if runtime.GOOS == "windows" {
+		return true // signal 0 isn't supported; never take over the lock of a process that may still run
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// ComposeLockPath returns the path of the lock file of the directory, which is kept next to
+// the directory (rather than in it) so that it is never taken for one of its contents.
+func ComposeLockPath(directory string) (string, error) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(directory), "."+filepath.Base(directory)+LockFileSuffix), nil
+}
+
+const LockFileSuffix = ".satisfy-lock"
+
+const incompleteLockAge = time.Minute
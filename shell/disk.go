@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -24,7 +25,7 @@ func (this *DiskFileSystem) RootPath() string {
 	return this.root
 }
 
-func (this *DiskFileSystem) Listing() (listing []contracts.FileInfo) {
+func (this *DiskFileSystem) Listing() (listing []contracts.FileInfo, err error) {
 	listingFunc := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -49,20 +50,17 @@ func (this *DiskFileSystem) Listing() (listing []contracts.FileInfo) {
 	}
 	stat, err := os.Stat(this.root)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	if stat.IsDir() == false {
-		err = listingFunc(this.root, stat, err)
-		if err != nil {
-			log.Panic(err)
-		}
+		err = listingFunc(this.root, stat, nil)
 	} else {
 		err = filepath.Walk(this.root, listingFunc)
-		if err != nil {
-			log.Panic(err)
-		}
 	}
-	return listing
+	if err != nil {
+		return nil, fmt.Errorf("could not list the contents of %q: %w", this.root, err)
+	}
+	return listing, nil
 }
 
 func (this *DiskFileSystem) Stat(path string) (contracts.FileInfo, error) {
@@ -82,8 +80,12 @@ func (this *DiskFileSystem) Stat(path string) (contracts.FileInfo, error) {
 }
 
 func (this *DiskFileSystem) CreateSymlink(source, target string) {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		log.Panic(err)
+	}
 	_ = os.Remove(target)
-	err := os.Symlink(source, target)
+	err = os.Symlink(source, target)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -131,6 +133,29 @@ func (this *DiskFileSystem) Delete(path string) {
 	}
 }
 
+func (this *DiskFileSystem) Rename(source, target string) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(source, target)
+}
+
+func (this *DiskFileSystem) DeleteAll(path string) {
+	err := os.RemoveAll(path)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (this *DiskFileSystem) DeleteEmptyDirectory(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
 ////////////////////////////////////////
 
 type FileInfo struct {
@@ -1,10 +1,17 @@
 package shell
 
 import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/smarty/gcs"
 	"github.com/smarty/satisfy/contracts"
@@ -13,6 +20,7 @@ import (
 type GoogleCloudStorageClient struct {
 	client         *http.Client
 	credentials    gcs.Credentials
+	anonymous      bool
 	expectedStatus []int
 }
 
@@ -20,7 +28,15 @@ func NewGoogleCloudStorageClient(client *http.Client, credentials gcs.Credential
 	return &GoogleCloudStorageClient{client: client, credentials: credentials, expectedStatus: expectedStatus}
 }
 
+// NewAnonymousGoogleCloudStorageClient sends unsigned requests, which public buckets allow for reading.
+func NewAnonymousGoogleCloudStorageClient(client *http.Client, expectedStatus []int) *GoogleCloudStorageClient {
+	return &GoogleCloudStorageClient{client: client, anonymous: true, expectedStatus: expectedStatus}
+}
+
 func (this *GoogleCloudStorageClient) Upload(request contracts.UploadRequest) error {
+	if this.anonymous {
+		return errors.New("uploading requires credentials (not anonymous access)")
+	}
 	gcsRequest, err := gcs.NewRequest("PUT",
 		gcs.WithCredentials(this.credentials),
 		gcs.WithBucket(request.RemoteAddress.Host),
@@ -45,17 +61,13 @@ func (this *GoogleCloudStorageClient) Upload(request contracts.UploadRequest) er
 		if this.isSafeRetryStatus(response.StatusCode) {
 			return fmt.Errorf("http error: %d (%w)", response.StatusCode, contracts.RetryErr)
 		}
-		return contracts.NewStatusCodeError(response.StatusCode, this.expectedStatus, request.RemoteAddress)
+		return this.statusError(response.StatusCode, this.expectedStatus, request.RemoteAddress)
 	}
 	return nil
 }
 
 func (this *GoogleCloudStorageClient) Download(request url.URL) (io.ReadCloser, error) {
-	gcsRequest, err := gcs.NewRequest("GET",
-		gcs.WithCredentials(this.credentials),
-		gcs.WithBucket(request.Host),
-		gcs.WithResource(request.Path),
-	)
+	gcsRequest, err := this.newRequest("GET", request)
 	if err != nil {
 		return nil, err
 	}
@@ -64,17 +76,13 @@ func (this *GoogleCloudStorageClient) Download(request url.URL) (io.ReadCloser,
 		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
 	}
 	if this.isExpectedStatus(response.StatusCode) == false {
-		return nil, contracts.NewStatusCodeError(response.StatusCode, this.expectedStatus, request)
+		return nil, this.statusError(response.StatusCode, this.expectedStatus, request)
 	}
 	return response.Body, nil
 }
 
 func (this *GoogleCloudStorageClient) Seek(request url.URL, start, end int64) (io.ReadCloser, error) {
-	gcsRequest, err := gcs.NewRequest("GET",
-		gcs.WithCredentials(this.credentials),
-		gcs.WithBucket(request.Host),
-		gcs.WithResource(request.Path),
-	)
+	gcsRequest, err := this.newRequest("GET", request)
 	if err != nil {
 		return nil, err
 	}
@@ -84,18 +92,14 @@ func (this *GoogleCloudStorageClient) Seek(request url.URL, start, end int64) (i
 		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
 	}
 	if this.isExpectedStatus(response.StatusCode) == false {
-		return nil, contracts.NewStatusCodeError(response.StatusCode, this.expectedStatus, request)
+		return nil, this.statusError(response.StatusCode, this.expectedStatus, request)
 	}
 	return response.Body, nil
 }
 
 // Size uses an HTTP HEAD to find out how many bytes are available in total.
 func (this *GoogleCloudStorageClient) Size(request url.URL) (int64, error) {
-	gcsRequest, err := gcs.NewRequest("HEAD",
-		gcs.WithCredentials(this.credentials),
-		gcs.WithBucket(request.Host),
-		gcs.WithResource(request.Path),
-	)
+	gcsRequest, err := this.newRequest("HEAD", request)
 	if err != nil {
 		return 0, err
 	}
@@ -104,15 +108,136 @@ func (this *GoogleCloudStorageClient) Size(request url.URL) (int64, error) {
 		return 0, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
 	}
 	if this.isExpectedStatus(response.StatusCode) == false {
-		return 0, contracts.NewStatusCodeError(response.StatusCode, this.expectedStatus, request)
+		return 0, this.statusError(response.StatusCode, this.expectedStatus, request)
 	}
 	return response.ContentLength, nil
 }
 
+// List enumerates the objects beneath the prefix using the XML API's "list objects"
+// request (following continuation markers), which the gcs package cannot build itself.
+func (this *GoogleCloudStorageClient) List(prefix url.URL) (objects []contracts.RemoteObject, err error) {
+	marker := ""
+	for {
+		page, err := this.listPage(prefix, marker)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Contents {
+			objects = append(objects, contracts.RemoteObject{
+				Path:    "/" + item.Key,
+				Size:    item.Size,
+				Updated: item.LastModified,
+			})
+		}
+		if !page.IsTruncated {
+			return objects, nil
+		}
+		marker = page.NextMarker
+		if marker == "" && len(page.Contents) > 0 {
+			marker = page.Contents[len(page.Contents)-1].Key
+		}
+	}
+}
+
+func (this *GoogleCloudStorageClient) listPage(prefix url.URL, marker string) (page listBucketResult, err error) {
+	request, err := this.newListRequest(prefix, marker)
+	if err != nil {
+		return page, err
+	}
+	response, err := this.client.Do(request)
+	if err != nil {
+		return page, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, response.Body)
+		if this.isSafeRetryStatus(response.StatusCode) {
+			return page, fmt.Errorf("http error: %d (%w)", response.StatusCode, contracts.RetryErr)
+		}
+		return page, this.statusError(response.StatusCode, []int{http.StatusOK}, prefix)
+	}
+	err = xml.NewDecoder(response.Body).Decode(&page)
+	if err != nil {
+		return page, fmt.Errorf("malformed object listing: %w", err)
+	}
+	return page, nil
+}
+
+func (this *GoogleCloudStorageClient) newListRequest(prefix url.URL, marker string) (*http.Request, error) {
+	query := url.Values{}
+	query.Set("prefix", strings.TrimPrefix(prefix.Path, "/"))
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if len(this.credentials.BearerToken) == 0 && !this.anonymous {
+		// https://cloud.google.com/storage/docs/access-control/signing-urls-manually
+		expires := strconv.FormatInt(time.Now().UTC().Add(time.Second*30).Unix(), 10)
+		signature, err := this.credentials.PrivateKey.Sign([]byte("GET\n\n\n" + expires + "\n/" + prefix.Host + "/"))
+		if err != nil {
+			return nil, err
+		}
+		query.Set("GoogleAccessId", this.credentials.AccessID)
+		query.Set("Expires", expires)
+		query.Set("Signature", base64.StdEncoding.EncodeToString(signature))
+	}
+	target := url.URL{Scheme: "https", Host: storageHost, Path: "/" + prefix.Host, RawQuery: query.Encode()}
+	request, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(this.credentials.BearerToken) > 0 {
+		request.Header.Set("Authorization", this.credentials.BearerToken)
+	}
+	return request, nil
+}
+
+// newRequest signs the request for the object at the address (unless anonymous).
+func (this *GoogleCloudStorageClient) newRequest(method string, address url.URL) (*http.Request, error) {
+	if !this.anonymous {
+		return gcs.NewRequest(method,
+			gcs.WithCredentials(this.credentials),
+			gcs.WithBucket(address.Host),
+			gcs.WithResource(address.Path),
+		)
+	}
+	if address.Host == "" {
+		return nil, gcs.ErrBucketMissing
+	}
+	target := url.URL{Scheme: "https", Host: storageHost, Path: path.Join("/", address.Host, address.Path)}
+	return http.NewRequest(method, target.String(), nil)
+}
+
+const storageHost = "storage.googleapis.com"
+
+// statusError explains the status codes that public buckets respond with, which otherwise look like authentication failures.
+func (this *GoogleCloudStorageClient) statusError(actual int, expected []int, address url.URL) error {
+	err := contracts.NewStatusCodeError(actual, expected, address)
+	switch {
+	case this.anonymous && (actual == http.StatusUnauthorized || actual == http.StatusForbidden):
+		return err.WithHint("anonymous access denied: either the bucket isn't public (it must grant allUsers the Storage Object Viewer role) or the object doesn't exist and listing the bucket isn't public; otherwise use credentials instead")
+	case this.anonymous && actual == http.StatusNotFound:
+		return err.WithHint("no such object in the public bucket: check the bucket, path, package name and version")
+	case actual == http.StatusForbidden:
+		return err.WithHint("the credentials don't grant access to the bucket or the object doesn't exist; for a public bucket, use anonymous access instead")
+	}
+	return err
+}
+
+type listBucketResult struct {
+	IsTruncated bool   `xml:"IsTruncated"`
+	NextMarker  string `xml:"NextMarker"`
+	Contents    []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
 func (this *GoogleCloudStorageClient) isSafeRetryStatus(statusCode int) bool {
 	switch statusCode {
-	case http.StatusUnauthorized,
-		http.StatusRequestTimeout,
+	case http.StatusUnauthorized:
+		return !this.anonymous // no credentials to refresh
+	case http.StatusRequestTimeout,
 		http.StatusInternalServerError,
 		http.StatusBadGateway,
 		http.StatusServiceUnavailable,
@@ -0,0 +1,22 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// CommandHookRunner runs hooks with "sh -c", passing the output of the command along.
+type CommandHookRunner struct{}
+
+func NewCommandHookRunner() *CommandHookRunner {
+	return &CommandHookRunner{}
+}
+
+func (this *CommandHookRunner) RunHook(command, directory string, environment []string) error {
+	hook := exec.Command("sh", "-c", command)
+	hook.Dir = directory
+	hook.Env = append(os.Environ(), environment...)
+	hook.Stdout = os.Stdout
+	hook.Stderr = os.Stderr
+	return hook.Run()
+}
@@ -0,0 +1,147 @@
+package shell
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// OfflineStorage serves manifests and archives without ever touching the network: from a
+// bundle directory (which mirrors <bucket>/<path> of remote addresses, as a copy of the
+// bucket would) and from the manifests kept by the archive cache. Archives found only in
+// the archive cache are installed from there by the installer (keyed by checksum), so
+// a version counts as available as long as its archive is in the bundle or the cache.
+type OfflineStorage struct {
+	bundle string
+	cache  *DiskArchiveCache
+}
+
+func NewOfflineStorage(bundle string, cache *DiskArchiveCache) *OfflineStorage {
+	return &OfflineStorage{bundle: bundle, cache: cache}
+}
+
+func (this *OfflineStorage) Download(request url.URL) (io.ReadCloser, error) {
+	path, err := this.locate(request)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (this *OfflineStorage) Seek(request url.URL, start, end int64) (io.ReadCloser, error) {
+	path, err := this.locate(request)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return offlineRange{Reader: io.NewSectionReader(file, start, end-start+1), Closer: file}, nil
+}
+
+func (this *OfflineStorage) Size(request url.URL) (int64, error) {
+	path, err := this.locate(request)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// List lists the objects available offline below the prefix. A manifest is only listed when
+// the corresponding archive is available too, so that only installable versions are listed.
+func (this *OfflineStorage) List(prefix url.URL) (objects []contracts.RemoteObject, err error) {
+	found := make(map[string]contracts.RemoteObject)
+	locations := make(map[string]string)
+	for _, directory := range this.directories() {
+		root := composeMirrorPath(directory, url.URL{Host: prefix.Host})
+		err = filepath.WalkDir(composeMirrorPath(directory, prefix), func(path string, entry fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil || !entry.Type().IsRegular() || strings.HasSuffix(path, partialArchiveSuffix) {
+				return err
+			}
+			relative, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			remotePath := "/" + filepath.ToSlash(relative)
+			if _, exists := found[remotePath]; exists || !strings.HasPrefix(remotePath, prefix.Path) {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			found[remotePath] = contracts.RemoteObject{Path: remotePath, Size: info.Size(), Updated: info.ModTime()}
+			locations[remotePath] = path
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for remotePath, object := range found {
+		if path.Base(remotePath) == contracts.RemoteManifestFilename && !this.hasArchive(found, remotePath, locations[remotePath]) {
+			continue
+		}
+		objects = append(objects, object)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+	return objects, nil
+}
+
+func (this *OfflineStorage) hasArchive(found map[string]contracts.RemoteObject, manifestPath, manifestLocation string) bool {
+	if _, bundled := found[path.Join(path.Dir(manifestPath), contracts.RemoteArchiveFilename)]; bundled {
+		return true
+	}
+	raw, err := os.ReadFile(manifestLocation)
+	if err != nil {
+		return false
+	}
+	var manifest contracts.Manifest
+	if json.Unmarshal(raw, &manifest) != nil {
+		return false
+	}
+	return this.cache.HasArchive(hex.EncodeToString(manifest.Archive.MD5Checksum))
+}
+
+func (this *OfflineStorage) locate(request url.URL) (string, error) {
+	directories := this.directories()
+	for _, directory := range directories {
+		path := composeMirrorPath(directory, request)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s (looked in %s)", contracts.ErrNotAvailableOffline, request.String(), strings.Join(directories, " and "))
+}
+
+// directories returns the bundle directory (if any) followed by the directory of cached manifests.
+func (this *OfflineStorage) directories() (directories []string) {
+	if this.bundle != "" {
+		directories = append(directories, this.bundle)
+	}
+	return append(directories, this.cache.ManifestDirectory())
+}
+
+type offlineRange struct {
+	io.Reader
+	io.Closer
+}
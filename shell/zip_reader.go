@@ -133,3 +133,20 @@ func NewZipArchiveReader(reader io.Reader) io.ReadCloser {
 	}
 	return zipArchiveReader
 }
+
+// NewRangedZipArchiveReader reads only the named entries of a zip archive, directly from the
+// source (which may be remote) rather than from a downloaded copy of the whole archive.
+func NewRangedZipArchiveReader(source io.ReaderAt, size int64, names map[string]struct{}) (*ZipArchiveReader, error) {
+	reader, err := zip.NewReader(source, size)
+	if err != nil {
+		return nil, err
+	}
+	var files []*zip.File
+	for _, file := range reader.File {
+		if _, found := names[file.Name]; found {
+			files = append(files, file)
+		}
+	}
+	reader.File = files
+	return &ZipArchiveReader{zipReader: reader, size: size}, nil
+}
@@ -0,0 +1,150 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/smarty/satisfy/shell"
+)
+
+type CacheConfig struct {
+	Action    string
+	Directory string
+	MaxSize   int64
+	JSON      bool
+}
+
+func ParseCacheConfig(args []string) (config CacheConfig, err error) {
+	flags := flag.NewFlagSet("satisfy cache", flag.ContinueOnError)
+	flags.StringVar(&config.Directory,
+		"dir",
+		shell.DefaultArchiveCacheDirectory(),
+		"The directory of the local archive cache (defaults to $SATISFY_CACHE_DIR, if set).",
+	)
+	flags.Int64Var(&config.MaxSize,
+		"max-size",
+		DefaultCacheMaxSize,
+		"For 'prune': the size, in MiB, to which the cache is reduced by evicting the least recently used archives.",
+	)
+	flags.BoolVar(&config.JSON,
+		"json",
+		false,
+		"For 'ls': when set, print the cached archives as a JSON array instead of a table.",
+	)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s cache ls|prune|clear [-dir <directory>] [-max-size <MiB>] [-json]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  ls     List the cached archives, most recently used first.")
+		_, _ = fmt.Fprintln(output, "  prune  Evict the least recently used archives until the cache is within -max-size.")
+		_, _ = fmt.Fprintln(output, "  clear  Evict every cached archive (and remove every cached manifest).")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return CacheConfig{}, errors.New("a cache action (ls, prune or clear) is required")
+	}
+	config.Action = args[0]
+	if err = flags.Parse(args[1:]); err != nil {
+		return CacheConfig{}, err
+	}
+	switch config.Action {
+	case cacheList, cachePrune, cacheClear:
+	default:
+		flags.Usage()
+		return CacheConfig{}, fmt.Errorf("unrecognized cache action: %q", config.Action)
+	}
+	if config.MaxSize < 0 {
+		return CacheConfig{}, errors.New("-max-size must not be negative")
+	}
+	return config, nil
+}
+
+type CacheApp struct {
+	config CacheConfig
+	cache  *shell.DiskArchiveCache
+	output io.Writer
+}
+
+func NewCacheApp(config CacheConfig) *CacheApp {
+	return &CacheApp{config: config, cache: shell.NewDiskArchiveCache(config.Directory, 0), output: os.Stdout}
+}
+
+func (this *CacheApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *CacheApp) TryRun() error {
+	switch this.config.Action {
+	case cachePrune:
+		return this.prune(this.config.MaxSize * mebibyte)
+	case cacheClear:
+		return this.report(this.cache.Clear())
+	default:
+		return this.list()
+	}
+}
+
+func (this *CacheApp) list() error {
+	entries, err := this.cache.Entries()
+	if err != nil {
+		return fmt.Errorf("could not list the archive cache at %q: %w", this.cache.Directory(), err)
+	}
+	if this.config.JSON {
+		if entries == nil {
+			entries = []shell.CachedArchive{}
+		}
+		encoder := json.NewEncoder(this.output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	var total int64
+	writer := tabwriter.NewWriter(this.output, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "ARCHIVE\tSIZE\tLAST USED")
+	for _, entry := range entries {
+		total += entry.Size
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%s\n", entry.Key, entry.Size, entry.LastUsed.UTC().Format(time.RFC3339))
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(this.output, "%d archive(s), %d bytes in %s\n", len(entries), total, this.cache.Directory())
+	return err
+}
+
+func (this *CacheApp) prune(maxSize int64) error {
+	return this.report(this.cache.Prune(maxSize))
+}
+
+func (this *CacheApp) report(evicted []shell.CachedArchive, err error) error {
+	var freed int64
+	for _, entry := range evicted {
+		freed += entry.Size
+		log.Printf("Evicted cached archive: %s", entry.Key)
+	}
+	log.Printf("Evicted %d archive(s), freeing %d bytes.", len(evicted), freed)
+	if err != nil {
+		return fmt.Errorf("could not evict from the archive cache at %q: %w", this.cache.Directory(), err)
+	}
+	return nil
+}
+
+const (
+	cacheList  = "ls"
+	cachePrune = "prune"
+	cacheClear = "clear"
+
+	DefaultCacheMaxSize = 10 * 1024 // MiB
+	mebibyte            = 1024 * 1024
+)
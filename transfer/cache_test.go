@@ -0,0 +1,128 @@
+package transfer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/shell"
+)
+
+func TestCacheFixture(t *testing.T) {
+	gunit.Run(new(CacheFixture), t)
+}
+
+type CacheFixture struct {
+	*gunit.Fixture
+
+	directory string
+	output    *bytes.Buffer
+}
+
+func (this *CacheFixture) Setup() {
+	this.directory, _ = os.MkdirTemp("", "satisfy-cache-")
+	this.output = new(bytes.Buffer)
+	this.store("aaaa", "oldest", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	this.store("bbbb", "newest", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	this.store("cccc", "middle", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (this *CacheFixture) Teardown() {
+	_ = os.RemoveAll(this.directory)
+}
+
+func (this *CacheFixture) store(key, content string, lastUsed time.Time) {
+	entry, err := shell.NewDiskArchiveCache(this.directory, 0).Store(key)
+	this.So(err, should.BeNil)
+	_, _ = entry.Write([]byte(content))
+	this.So(entry.Commit(), should.BeNil)
+	this.So(os.Chtimes(filepath.Join(this.directory, key+".archive"), lastUsed, lastUsed), should.BeNil)
+}
+
+func (this *CacheFixture) app(action string) *CacheApp {
+	config := CacheConfig{Action: action, Directory: this.directory}
+	return &CacheApp{config: config, cache: shell.NewDiskArchiveCache(this.directory, 0), output: this.output}
+}
+
+func (this *CacheFixture) keys() (keys []string) {
+	entries, err := shell.NewDiskArchiveCache(this.directory, 0).Entries()
+	this.So(err, should.BeNil)
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func (this *CacheFixture) TestListMostRecentlyUsedFirst() {
+	this.So(this.app(cacheList).TryRun(), should.BeNil)
+
+	this.So(this.output.String(), should.Equal, ""+
+		"ARCHIVE  SIZE  LAST USED\n"+
+		"bbbb     6     2026-03-01T00:00:00Z\n"+
+		"cccc     6     2026-02-01T00:00:00Z\n"+
+		"aaaa     6     2026-01-01T00:00:00Z\n"+
+		"3 archive(s), 18 bytes in "+this.directory+"\n")
+}
+
+func (this *CacheFixture) TestLoadingAnArchiveMakesItMostRecentlyUsed() {
+	body, found := shell.NewDiskArchiveCache(this.directory, 0).Load("aaaa")
+	this.So(found, should.BeTrue)
+	_ = body.Close()
+
+	this.So(this.keys(), should.Resemble, []string{"aaaa", "bbbb", "cccc"})
+}
+
+func (this *CacheFixture) TestPruneEvictsLeastRecentlyUsed() {
+	app := this.app(cachePrune)
+
+	this.So(app.prune(12), should.BeNil)
+
+	this.So(this.keys(), should.Resemble, []string{"bbbb", "cccc"})
+}
+
+func (this *CacheFixture) TestCommittingBeyondMaxSizeEvictsLeastRecentlyUsed() {
+	cache := shell.NewDiskArchiveCache(this.directory, 18)
+	entry, _ := cache.Store("dddd")
+	_, _ = entry.Write([]byte("fresh"))
+
+	this.So(entry.Commit(), should.BeNil)
+
+	this.So(this.keys(), should.Resemble, []string{"dddd", "bbbb", "cccc"})
+}
+
+func (this *CacheFixture) TestAbortedEntryIsNeverVisible() {
+	entry, _ := shell.NewDiskArchiveCache(this.directory, 0).Store("dddd")
+	_, _ = entry.Write([]byte("partial"))
+
+	entry.Abort()
+
+	_, found := shell.NewDiskArchiveCache(this.directory, 0).Load("dddd")
+	this.So(found, should.BeFalse)
+	files, _ := os.ReadDir(this.directory)
+	this.So(files, should.HaveLength, 3)
+}
+
+func (this *CacheFixture) TestClearEvictsEverything() {
+	this.So(this.app(cacheClear).TryRun(), should.BeNil)
+
+	this.So(this.keys(), should.BeEmpty)
+}
+
+func (this *CacheFixture) TestUnrecognizedActionIsRejected() {
+	_, err := ParseCacheConfig([]string{"purge"})
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "purge")
+}
+
+func (this *CacheFixture) TestFlagsFollowTheAction() {
+	config, err := ParseCacheConfig([]string{"prune", "-dir", this.directory, "-max-size", "5"})
+
+	this.So(err, should.BeNil)
+	this.So(config, should.Resemble, CacheConfig{Action: cachePrune, Directory: this.directory, MaxSize: 5})
+}
@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"fmt"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+// collisionResolver keeps packages that share a local directory from overwriting each other's files.
+type collisionResolver struct {
+	manifests *manifestLoader
+}
+
+// Resolve downloads the manifests of the packages sharing a local directory and leaves the files
+// they have in common to the package with the highest precedence (see core.ResolveCollisions).
+// The packages are returned in listing order; those in a directory with unresolved collisions are
+// returned as listed and the problem is reported by directory.
+func (this collisionResolver) Resolve(listing []contracts.Dependency) (resolved []contracts.Dependency, failures map[string]error) {
+	resolved = append([]contracts.Dependency(nil), listing...)
+	failures = make(map[string]error)
+	for _, shared := range groupByLocalDirectory(listing) {
+		if len(shared) == 1 {
+			continue
+		}
+		directory := listing[shared[0]].LocalDirectory
+		var dependencies []contracts.Dependency
+		for _, i := range shared {
+			dependencies = append(dependencies, listing[i])
+		}
+		accepted, err := core.ResolveCollisions(dependencies, this.downloadManifests(dependencies))
+		if err != nil {
+			failures[directory] = fmt.Errorf("could not install the packages in %q: %w", directory, err)
+			continue
+		}
+		for j, i := range shared {
+			resolved[i] = accepted[j]
+		}
+	}
+	return resolved, failures
+}
+
+// downloadManifests leaves out the manifests that can't be downloaded; the problem surfaces during installation.
+func (this collisionResolver) downloadManifests(dependencies []contracts.Dependency) map[int]contracts.Manifest {
+	manifests := make(map[int]contracts.Manifest)
+	for i, dependency := range dependencies {
+		if manifest, err := this.manifests.DownloadManifest(dependency); err == nil {
+			manifests[i] = manifest
+		}
+	}
+	return manifests
+}
+
+// groupByLocalDirectory groups the indexes of the dependencies by local directory, in order of first appearance.
+func groupByLocalDirectory(listing []contracts.Dependency) (groups [][]int) {
+	positions := make(map[string]int)
+	for i, dependency := range listing {
+		position, found := positions[dependency.LocalDirectory]
+		if !found {
+			position = len(groups)
+			positions[dependency.LocalDirectory] = position
+			groups = append(groups, nil)
+		}
+		groups[position] = append(groups[position], i)
+	}
+	return groups
+}
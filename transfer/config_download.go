@@ -1,27 +1,41 @@
 package transfer
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
-
-	"github.com/smarty/gcs"
+	"time"
 
 	"github.com/smarty/satisfy/contracts"
 	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
 )
 
 type DownloadConfig struct {
 	MaxRetry          int
+	Parallel          int
+	PerHost           int
+	LockTimeout       time.Duration
 	QuickVerification bool
 	ShowProgress      bool
-	GoogleCredentials gcs.Credentials
+	Clean             bool
+	CleanConfirmed    bool
+	Repair            bool
+	Cache             bool
+	CacheDirectory    string
+	CacheMaxSize      int64
+	Offline           bool
+	BundleDirectory   string
+	LockPath          string
+	Anonymous         bool
 	Dependencies      contracts.DependencyListing
 	jsonPath          string
+	profile           string
+	pins              *lockPins
 }
 
 func ParseDownloadConfig(args []string) (config DownloadConfig, err error) {
@@ -31,6 +45,17 @@ func ParseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		5,
 		"How many times to retry attempts to download packages.",
 	)
+	flags.IntVar(&config.Parallel,
+		"parallel",
+		DefaultParallelInstallations,
+		"How many packages to install at a time, smallest first (0, the default, means all at once as before).",
+	)
+	flags.IntVar(&config.PerHost,
+		"per-host",
+		0,
+		"How many packages to install at a time from any one remote host, i.e. bucket (0 means no limit beyond -parallel).",
+	)
+	addLockTimeoutFlag(flags, &config.LockTimeout)
 	flags.BoolVar(&config.QuickVerification,
 		"quick",
 		true,
@@ -41,11 +66,57 @@ func ParseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		true,
 		"Displays progress stats as files are extracted from the archive.",
 	)
-	flags.StringVar(&config.jsonPath,
-		"json",
-		"_STDIN_",
-		"Path to file with dependency listing or, if equal to _STDIN_, read from stdin.",
+	flags.BoolVar(&config.Clean,
+		"clean",
+		false,
+		"When set, list the files in each local directory not owned by any package installed there (see 'strays') and, with -clean-confirm, remove them.",
+	)
+	flags.BoolVar(&config.CleanConfirmed,
+		"clean-confirm",
+		false,
+		"Confirms that -clean is to remove the files it lists (without it, -clean is a dry run).",
+	)
+	flags.BoolVar(&config.Repair,
+		"repair",
+		false,
+		"When set, restore only the files of an installed package that fail verification instead of reinstalling it.",
+	)
+	flags.BoolVar(&config.Cache,
+		"cache",
+		false,
+		"When set, install archives from (and add downloaded archives to) the local archive cache (see 'cache').",
+	)
+	flags.StringVar(&config.CacheDirectory,
+		"cache-dir",
+		shell.DefaultArchiveCacheDirectory(),
+		"The directory of the local archive cache (defaults to $SATISFY_CACHE_DIR, if set).",
 	)
+	flags.Int64Var(&config.CacheMaxSize,
+		"cache-max-size",
+		DefaultCacheMaxSize,
+		"The size, in MiB, beyond which the least recently used archives are evicted from the cache (0 means unlimited).",
+	)
+	flags.BoolVar(&config.Offline,
+		"offline",
+		false,
+		"When set, never touch the network: install only from the archive cache and the -bundle directory (\"latest\" is the newest version available there).",
+	)
+	flags.StringVar(&config.BundleDirectory,
+		"bundle",
+		"",
+		"With -offline, a directory mirroring <bucket>/<path> of remote addresses (e.g. a copy of the bucket) to install from.",
+	)
+	flags.StringVar(&config.LockPath,
+		"lock",
+		DefaultLockPath,
+		"Path to the lock file (see 'lock') whose pinned versions are installed, if it exists (defaults to "+DefaultLockPath+" next to the -json listing). Set to empty to ignore it.",
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access public buckets with unsigned requests instead of the default Google credentials (dependencies with credentials of their own still use them).",
+	)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
 
 	flags.Usage = func() {
 		output := flags.Output()
@@ -55,11 +126,20 @@ func ParseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		_, _ = fmt.Fprintln(output, "  Package names may be passed as non-flag arguments and will serve as a filter "+
 			"against the provided dependency listing.")
 		_, _ = fmt.Fprintln(output)
-		_, _ = fmt.Fprintln(output, "  The satisfy tool also provides 4 additional subcommands:")
+		_, _ = fmt.Fprintln(output, "  The satisfy tool also provides the following subcommands:")
+		_, _ = fmt.Fprintln(output, "	cache	List (ls), prune or clear the local archive cache used with -cache and -offline.")
 		_, _ = fmt.Fprintln(output, "	check	Has package@version already been uploaded according to json config?")
+		_, _ = fmt.Fprintln(output, "	export	Write the contents of package@version as a tar stream to stdout or a file.")
 		_, _ = fmt.Fprintln(output, "	latest	Print the latest published version of a package to stdout.")
+		_, _ = fmt.Fprintln(output, "	lock	Pin the versions and manifest digests of the dependency listing in a lock file.")
+		_, _ = fmt.Fprintln(output, "	rollback	Re-activate the previous version of packages installed with the versioned layout.")
+		_, _ = fmt.Fprintln(output, "	status	Report the install state of each dependency without changing anything.")
+		_, _ = fmt.Fprintln(output, "	strays	List files in local directories not owned by any installed package.")
+		_, _ = fmt.Fprintln(output, "	uninstall	Remove installed packages according to their local manifests.")
 		_, _ = fmt.Fprintln(output, "	upload	Upload package contents according to json config.")
+		_, _ = fmt.Fprintln(output, "	verify	Check every installed file and report all missing, mismatched and extra files.")
 		_, _ = fmt.Fprintln(output, "	version	Print the satisfy tool version to stdout.")
+		_, _ = fmt.Fprintln(output, "	versions	List every published version of a package with its size and upload time.")
 		_, _ = fmt.Fprintln(output)
 	}
 
@@ -68,20 +148,63 @@ func ParseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		log.Println("[WARN] Unable to parse command line flags:", err)
 		return DownloadConfig{}, err
 	}
+	if config.Parallel < 0 || config.PerHost < 0 {
+		return DownloadConfig{}, errors.New("-parallel and -per-host must not be negative")
+	}
+	if config.BundleDirectory != "" && !config.Offline {
+		return DownloadConfig{}, errors.New("-bundle requires -offline")
+	}
+	if config.CleanConfirmed && !config.Clean {
+		return DownloadConfig{}, errors.New("-clean-confirm requires -clean")
+	}
 
-	config.Dependencies, err = loadDependencyListing(config.jsonPath, flags.Args())
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
 	if err != nil {
 		log.Println("[WARN] Unable to load dependency listing:", err)
 		return DownloadConfig{}, err
 	}
 
-	reader := gcs.NewCredentialsReader()
-	config.GoogleCredentials, err = reader.Read(context.Background(), config.Dependencies.Credentials)
+	config.LockPath = resolveLockPath(flags, config.LockPath, config.jsonPath)
+	config.pins, err = loadLockPins(config.LockPath)
+	if err == nil {
+		err = config.pins.pinAll(config.Dependencies.Listing)
+	}
+	if err != nil {
+		return DownloadConfig{}, err
+	}
+
 	return config, nil
 }
 
-func loadDependencyListing(path string, filter []string) (contracts.DependencyListing, error) {
-	dependencies, err := readDependencyListing(path)
+const (
+	DefaultParallelInstallations = 0 // unbounded: every package is installed at once unless -parallel says otherwise
+	DefaultLockTimeout           = 30 * time.Minute
+)
+
+func addListingFlags(flags *flag.FlagSet, jsonPath, profile *string) {
+	flags.StringVar(profile,
+		"profile",
+		os.Getenv(ListingProfileVariable),
+		"The profile of the dependency listing to apply, e.g. prod (defaults to $SATISFY_PROFILE, if set).",
+	)
+	flags.StringVar(jsonPath,
+		"json",
+		"_STDIN_",
+		"Path to file with dependency listing or, if equal to _STDIN_, read from stdin.",
+	)
+}
+
+func addLockTimeoutFlag(flags *flag.FlagSet, lockTimeout *time.Duration) {
+	flags.DurationVar(lockTimeout,
+		"lock-timeout",
+		DefaultLockTimeout,
+		"How long to wait for another installation into the same local directory (e.g. by another satisfy process) to finish.",
+	)
+}
+
+// loadDependencyListing validates the listing once the profile (if any) is applied.
+func loadDependencyListing(path, profile string, filter []string) (contracts.DependencyListing, error) {
+	dependencies, err := readDependencyListing(path, profile)
 	if err != nil {
 		return contracts.DependencyListing{}, err
 	}
@@ -100,15 +223,16 @@ func loadDependencyListing(path string, filter []string) (contracts.DependencyLi
 	return dependencies, nil
 }
 
-func readDependencyListing(path string) (contracts.DependencyListing, error) {
+func readDependencyListing(path, profile string) (contracts.DependencyListing, error) {
 	if path == "_STDIN_" {
-		return readFromReader(os.Stdin)
+		return readFromReader(os.Stdin, profile)
 	} else {
-		return readFromFile(path)
+		return readFromFile(path, profile)
 	}
 }
 
-func readFromFile(fileName string) (listing contracts.DependencyListing, err error) {
+// readFromFile composes the listing with the listings it includes (see composeDependencyListing).
+func readFromFile(fileName, profile string) (listing contracts.DependencyListing, err error) {
 	file, err := os.Open(fileName)
 	if os.IsNotExist(err) {
 		emitExampleDependenciesFile()
@@ -118,7 +242,11 @@ func readFromFile(fileName string) (listing contracts.DependencyListing, err err
 		return listing, fmt.Errorf("could not open specified dependency file (%q): %w", fileName, err)
 	}
 	defer func() { _ = file.Close() }()
-	return readFromReader(file)
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return listing, err
+	}
+	return composeDependencyListing(raw, fileName, profile)
 }
 
 func emitExampleDependenciesFile() {
@@ -136,8 +264,10 @@ func emitExampleDependenciesFile() {
 	log.Print("Example json file:\n", string(raw))
 }
 
-func readFromReader(reader io.Reader) (listing contracts.DependencyListing, err error) {
-	decoder := json.NewDecoder(reader)
-	err = decoder.Decode(&listing)
-	return listing, err
+func readFromReader(reader io.Reader, profile string) (listing contracts.DependencyListing, err error) {
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return listing, err
+	}
+	return composeDependencyListing(raw, "", profile)
 }
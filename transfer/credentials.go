@@ -0,0 +1,41 @@
+package transfer
+
+import (
+	"context"
+	"time"
+
+	"github.com/smarty/gcs"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+// newRemoteStorage sends the requests for the dependencies of the listing with the credentials
+// assigned to them (see core.CredentialRouter); every remote storage is built here. When anonymous
+// is set, requests without credentials of their own are sent unsigned instead of with the default
+// credentials. Commands without a listing pass an empty one: they use the default credentials.
+func newRemoteStorage(listing contracts.DependencyListing, expectedStatus []int, maxRetry int, anonymous bool) *core.RetryClient {
+	router := core.NewCredentialRouter(listing, func(access core.RemoteAccess) (contracts.RemoteStorage, error) {
+		if access.Anonymous || (access.Credentials == "" && anonymous) {
+			return shell.NewAnonymousGoogleCloudStorageClient(shell.NewHTTPClient(), expectedStatus), nil
+		}
+		credentials, err := readCredentials(listing.Credentials, access.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		return shell.NewGoogleCloudStorageClient(shell.NewHTTPClient(), credentials, expectedStatus), nil
+	})
+	return core.NewRetryClient(router, maxRetry, time.Sleep)
+}
+
+// readCredentials reads the credentials of a dependency (or host) like those of the listing: an access
+// token or the name of a key file. Without credentials of its own the credential chain (see
+// core.CredentialChain) applies, which is given the credentials from the dependency listing (if any).
+func readCredentials(listing, own string) (gcs.Credentials, error) {
+	chain := core.NewCredentialChain(shell.NewEnvironment(), shell.NewDiskFileSystem(""))
+	if own != "" {
+		return chain.ReadSource(context.Background(), core.CredentialsFromListing, own)
+	}
+	return chain.Read(context.Background(), listing)
+}
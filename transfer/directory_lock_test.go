@@ -0,0 +1,143 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/shell"
+)
+
+func TestDirectoryLockFixture(t *testing.T) {
+	gunit.Run(new(DirectoryLockFixture), t)
+}
+
+type DirectoryLockFixture struct {
+	*gunit.Fixture
+
+	root      string
+	directory string
+	lockPath  string
+}
+
+func (this *DirectoryLockFixture) Setup() {
+	this.root, _ = os.MkdirTemp("", "satisfy-lock-")
+	this.directory = filepath.Join(this.root, "local")
+	this.lockPath = filepath.Join(this.root, ".local.satisfy-lock")
+}
+
+func (this *DirectoryLockFixture) Teardown() {
+	_ = os.RemoveAll(this.root)
+}
+
+func (this *DirectoryLockFixture) writeLock(holder shell.LockHolder) {
+	raw, _ := json.Marshal(holder)
+	this.So(os.WriteFile(this.lockPath, raw, 0644), should.BeNil)
+}
+
+func (this *DirectoryLockFixture) TestLockFileDescribesHolderUntilReleased() {
+	release, err := shell.NewDirectoryLocker(0).Lock(this.directory)
+	this.So(err, should.BeNil)
+
+	raw, err := os.ReadFile(this.lockPath)
+	this.So(err, should.BeNil)
+	var holder shell.LockHolder
+	this.So(json.Unmarshal(raw, &holder), should.BeNil)
+	this.So(holder.PID, should.Equal, os.Getpid())
+
+	release()
+	_, err = os.Stat(this.lockPath)
+	this.So(os.IsNotExist(err), should.BeTrue)
+}
+
+func (this *DirectoryLockFixture) TestLockHeldByLiveProcessNamesTheHolder() {
+	host, _ := os.Hostname()
+	this.writeLock(shell.LockHolder{PID: os.Getpid(), Host: host, Acquired: time.Now()})
+
+	release, err := shell.NewDirectoryLocker(0).Lock(this.directory)
+
+	this.So(release, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "process "+strconv.Itoa(os.Getpid())+" on "+host)
+}
+
+func (this *DirectoryLockFixture) TestStaleLockOfDeadProcessIsTakenOver() {
+	host, _ := os.Hostname()
+	this.writeLock(shell.LockHolder{PID: 1 << 30, Host: host, Acquired: time.Now().Add(-time.Hour)})
+
+	release, err := shell.NewDirectoryLocker(0).Lock(this.directory)
+
+	this.So(err, should.BeNil)
+	release()
+}
+
+func (this *DirectoryLockFixture) TestStaleLockIsTakenOverByOneWaiterOnly() {
+	for attempt := 0; attempt < 20; attempt++ {
+		host, _ := os.Hostname()
+		this.writeLock(shell.LockHolder{PID: 1 << 30, Host: host, Acquired: time.Now().Add(-time.Hour)})
+		var acquired atomic.Int32
+		var waiters, holding sync.WaitGroup
+		holding.Add(1)
+		for i := 0; i < 8; i++ {
+			waiters.Add(1)
+			go func() {
+				defer waiters.Done()
+				release, err := shell.NewDirectoryLocker(0).Lock(this.directory) // a locker of its own, like another process
+				if err != nil {
+					return
+				}
+				acquired.Add(1)
+				holding.Wait()
+				release()
+			}()
+		}
+		time.Sleep(time.Millisecond * 20)
+		holding.Done()
+		waiters.Wait()
+
+		this.So(acquired.Load(), should.Equal, 1)
+	}
+}
+
+func (this *DirectoryLockFixture) TestLockOfProcessOnOtherHostIsHonored() {
+	this.writeLock(shell.LockHolder{PID: 1 << 30, Host: "elsewhere", Acquired: time.Now()})
+
+	_, err := shell.NewDirectoryLocker(0).Lock(this.directory)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "on elsewhere")
+}
+
+func (this *DirectoryLockFixture) TestInstallationsWithinProcessWaitForEachOther() {
+	locker := shell.NewDirectoryLocker(time.Second)
+	release, err := locker.Lock(this.directory)
+	this.So(err, should.BeNil)
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		release()
+	}()
+
+	second, err := locker.Lock(this.directory)
+
+	this.So(err, should.BeNil)
+	second()
+}
+
+func (this *DirectoryLockFixture) TestInstallationWithinProcessTimesOut() {
+	locker := shell.NewDirectoryLocker(time.Millisecond * 10)
+	release, _ := locker.Lock(this.directory)
+	defer release()
+
+	_, err := locker.Lock(this.directory)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "within this process")
+}
@@ -2,11 +2,16 @@ package transfer
 
 import (
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/smarty/satisfy/contracts"
 	"github.com/smarty/satisfy/core"
@@ -14,30 +19,65 @@ import (
 )
 
 type DownloadApp struct {
-	listing   contracts.DependencyListing
-	installer *core.PackageInstaller
-	integrity contracts.IntegrityCheck
-	waiter    *sync.WaitGroup
-	results   chan error
+	listing    contracts.DependencyListing
+	installer  *core.PackageInstaller
+	catalog    *core.PackageCatalog
+	manifests  *manifestLoader
+	integrity  contracts.IntegrityCheck
+	repairer   core.Repairer
+	hooks      core.HookRunner
+	dispatcher *core.Dispatcher
+	locker     *shell.DirectoryLocker
+	offline    bool
+	clean      bool
+	confirmed  bool
+	protected  []string
+	pins       *lockPins
+	results    chan error
 }
 
 func NewDownloadApp(config DownloadConfig) *DownloadApp {
 	disk := shell.NewDiskFileSystem("")
-	client := shell.NewGoogleCloudStorageClient(shell.NewHTTPClient(), config.GoogleCredentials, []int{http.StatusPartialContent, http.StatusOK})
-	installer := core.NewPackageInstaller(core.NewRetryClient(client, config.MaxRetry, time.Sleep), disk, config.ShowProgress)
+	retryClient := newRemoteStorage(config.Dependencies, []int{http.StatusPartialContent, http.StatusOK}, config.MaxRetry, config.Anonymous)
+	var downloader contracts.Downloader = retryClient
+	var lister contracts.Lister = retryClient
+	var cache contracts.ArchiveCache
+	diskCache := shell.NewDiskArchiveCache(config.CacheDirectory, config.CacheMaxSize*mebibyte)
+	if config.Cache || config.Offline {
+		cache = diskCache
+	}
+	if config.Offline {
+		offline := shell.NewOfflineStorage(config.BundleDirectory, diskCache)
+		downloader, lister = offline, offline
+	}
+	storage := newRunStorage(downloader, lister)
+	installer := core.NewPackageInstaller(storage, disk, cache, config.ShowProgress)
+	catalog := core.NewPackageCatalog(storage)
 	integrity := core.NewCompoundIntegrityCheck(
 		core.NewFileListingIntegrityChecker(disk),
 		core.NewFileContentIntegrityCheck(md5.New, disk, !config.QuickVerification),
 	)
-	waiter := new(sync.WaitGroup)
-	waiter.Add(len(config.Dependencies.Listing))
-	return &DownloadApp{
-		listing:   config.Dependencies,
-		installer: installer,
-		integrity: integrity,
-		waiter:    waiter,
-		results:   make(chan error),
+	app := &DownloadApp{
+		listing:    config.Dependencies,
+		installer:  installer,
+		catalog:    catalog,
+		manifests:  newManifestLoader(installer, catalog, config.Offline),
+		integrity:  integrity,
+		dispatcher: core.NewDispatcher(config.Parallel, config.PerHost),
+		locker:     shell.NewDirectoryLocker(config.LockTimeout),
+		hooks:      shell.NewCommandHookRunner(),
+		offline:    config.Offline,
+		clean:      config.Clean,
+		confirmed:  config.CleanConfirmed,
+		protected:  []string{config.jsonPath, config.LockPath},
+		pins:       config.pins,
+		results:    make(chan error),
+	}
+	if config.Repair {
+		verifier := core.NewExhaustiveVerifier(disk, core.NewFileContentIntegrityCheck(md5.New, disk, true))
+		app.repairer = core.NewPackageRepairer(verifier, installer)
 	}
+	return app
 }
 
 func (this *DownloadApp) Run() {
@@ -48,10 +88,7 @@ func (this *DownloadApp) Run() {
 }
 
 func (this *DownloadApp) TryRun() error {
-	for _, dependency := range this.listing.Listing {
-		go this.install(dependency)
-	}
-	go this.awaitCompletion()
+	go this.installAll()
 	failed := 0
 	for err := range this.results {
 		failed++
@@ -60,19 +97,188 @@ func (this *DownloadApp) TryRun() error {
 	if failed > 0 {
 		return fmt.Errorf("[WARN] %d packages failed to install.", failed)
 	}
+	if this.clean {
+		return this.pruneStrays()
+	}
 	return nil
 }
 
-func (this *DownloadApp) awaitCompletion() {
-	this.waiter.Wait()
-	close(this.results)
+// pruneStrays runs once all packages are installed so that packages sharing a directory are all accounted for.
+// Each directory is locked while it is pruned, as another process may be installing into it by then. Without
+// confirmation, the stray files are only listed. Directories holding the listing or the lock file are never
+// pruned: those files (and whatever else lives next to them) are not owned by any package.
+func (this *DownloadApp) pruneStrays() error {
+	for _, directory := range localDirectories(this.listing) {
+		if path, found := this.protectedWithin(directory); found {
+			return fmt.Errorf("refusing to remove stray files from %q, which contains %q", directory, path)
+		}
+		if !this.confirmed {
+			strays, err := core.NewStrayFinder(shell.NewDiskFileSystem(directory)).Find(directory)
+			if err != nil {
+				return fmt.Errorf("could not inspect %q: %w", directory, err)
+			}
+			for _, path := range strays {
+				log.Printf("Would remove stray file (confirm with -clean-confirm): %s", path)
+			}
+			continue
+		}
+		strays, err := this.prune(directory)
+		if err != nil {
+			return fmt.Errorf("could not remove stray files from %q: %w", directory, err)
+		}
+		for _, path := range strays {
+			log.Printf("Removed stray file: %s", path)
+		}
+	}
+	return nil
+}
+
+// protectedWithin returns the protected file (if any) found beneath the directory.
+func (this *DownloadApp) protectedWithin(directory string) (string, bool) {
+	directory, err := filepath.Abs(directory)
+	if err != nil {
+		return "", false
+	}
+	for _, path := range this.protected {
+		if path == "" || path == "_STDIN_" {
+			continue
+		}
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		if relative, err := filepath.Rel(directory, absolute); err == nil && filepath.IsLocal(relative) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+func (this *DownloadApp) prune(directory string) ([]string, error) {
+	release, err := this.locker.Lock(directory)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return core.NewStrayFinder(shell.NewDiskFileSystem(directory)).Prune(directory)
+}
+
+// installAll installs the listed packages and the packages they require, the smallest first, as many at
+// a time as the dispatcher allows. Packages sharing a local directory are installed one after the other,
+// lowest precedence first.
+func (this *DownloadApp) installAll() {
+	defer close(this.results)
+	required, err := this.resolveGraph()
+	if err != nil {
+		this.results <- err
+		return
+	}
+	resolved, failures := collisionResolver{manifests: this.manifests}.Resolve(required)
+	var listing []contracts.Dependency
+	for _, dependency := range resolved {
+		if _, failed := failures[dependency.LocalDirectory]; !failed {
+			listing = append(listing, dependency)
+		}
+	}
+	for _, err := range failures {
+		this.results <- err
+	}
+	ordered := core.OrderBySize(listing, this.prefetchSizes(listing))
+	var jobs []core.Job
+	for _, shared := range groupByLocalDirectory(ordered) {
+		var dependencies []contracts.Dependency
+		for _, i := range shared {
+			dependencies = append(dependencies, ordered[i])
+		}
+		sort.SliceStable(dependencies, func(i, j int) bool { return dependencies[i].Precedence < dependencies[j].Precedence })
+		jobs = append(jobs, core.Job{Hosts: remoteHosts(dependencies), Run: func() {
+			for _, dependency := range dependencies {
+				this.install(dependency)
+			}
+		}})
+	}
+	this.dispatcher.Dispatch(jobs)
+}
+
+// remoteHosts returns the distinct hosts the dependencies are downloaded from.
+func remoteHosts(dependencies []contracts.Dependency) (hosts []string) {
+	for _, dependency := range dependencies {
+		if !slices.Contains(hosts, dependency.RemoteAddress.Host) {
+			hosts = append(hosts, dependency.RemoteAddress.Host)
+		}
+	}
+	return hosts
+}
+
+// resolveGraph adds the packages required by the listed packages to the listing.
+func (this *DownloadApp) resolveGraph() ([]contracts.Dependency, error) {
+	graph, err := resolveDependencyGraph(this.manifests, this.listing.Listing, this.pins)
+	if err != nil {
+		return nil, err
+	}
+	listing := make([]contracts.Dependency, 0, len(graph))
+	for _, dependency := range graph {
+		listing = append(listing, dependency.Dependency)
+		if !dependency.Listed {
+			log.Printf("Including %s, required by %s", dependency.Title(), strings.Join(dependency.RequiredBy, ", "))
+		}
+	}
+	this.listing.Listing = listing
+	return listing, nil
+}
+
+// prefetchSizes reads the local (or else downloads the remote) manifest of each dependency to learn the size of its archive.
+// Sizes that can't be determined (yet) are left out; any real problem surfaces during installation.
+func (this *DownloadApp) prefetchSizes(listing []contracts.Dependency) map[int]int64 {
+	var mutex sync.Mutex
+	sizes := make(map[int]int64)
+	var jobs []core.Job
+	for i, dependency := range listing {
+		if dependency.HasVersionConstraint() || len(listing) == 1 {
+			continue
+		}
+		jobs = append(jobs, core.Job{Hosts: []string{dependency.RemoteAddress.Host}, Run: func() {
+			manifest, err := this.manifests.LoadManifest(dependency)
+			if err != nil {
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			sizes[i] = int64(manifest.Archive.Size)
+		}})
+	}
+	this.dispatcher.Dispatch(jobs)
+	return sizes
+}
+
+func readLocalManifest(dependency contracts.Dependency) (manifest contracts.Manifest, err error) {
+	raw, err := os.ReadFile(core.ComposeManifestPath(dependency.LocalDirectory, dependency.PackageName))
+	if err == nil {
+		err = json.Unmarshal(raw, &manifest)
+	}
+	return manifest, err
 }
 
 func (this *DownloadApp) install(dependency contracts.Dependency) {
-	defer this.waiter.Done()
+	if this.offline && dependency.PackageVersion == "latest" {
+		version, err := this.catalog.ResolveLatest(dependency)
+		if err != nil {
+			this.results <- fmt.Errorf("failed to resolve the latest version of %s available offline: %w", dependency.Title(), err)
+			return
+		}
+		log.Printf("Resolved %s to version %s (the latest available offline)", dependency.Title(), version)
+		dependency.PackageVersion = version
+	}
+
+	release, err := this.locker.Lock(dependency.LocalDirectory)
+	if err != nil {
+		this.results <- fmt.Errorf("could not install %s: %w", dependency.Title(), err)
+		return
+	}
+	defer release()
 
-	resolver := core.NewDependencyResolver(shell.NewDiskFileSystem(""), this.integrity, this.installer, dependency)
-	err := resolver.Resolve()
+	resolver := core.NewDependencyResolver(shell.NewDiskFileSystem(""), this.integrity, this.installer, this.catalog, this.repairer, this.hooks, dependency)
+	err = resolver.Resolve()
 	if err != nil {
 		this.results <- err
 	}
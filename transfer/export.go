@@ -0,0 +1,230 @@
+package transfer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type ExportConfig struct {
+	PackageName    string
+	PackageVersion string
+	RemoteAddress  contracts.URL
+	Selection      contracts.PathSelection
+	OutputPath     string
+	Anonymous      bool
+	MaxRetry       int
+	Dependencies   contracts.DependencyListing
+	jsonPath       string
+	profile        string
+}
+
+func ParseExportConfig(args []string) (config ExportConfig, err error) {
+	flags := flag.NewFlagSet("satisfy export", flag.ContinueOnError)
+
+	var bucket, prefix string
+	flags.StringVar(&bucket,
+		"bucket",
+		"",
+		"GCS bucket name (required), e.g. liveaddress-downloads-dev.",
+	)
+	flags.StringVar(&prefix,
+		"path",
+		"",
+		"Optional path prefix within the bucket where packages live, e.g. /releases.",
+	)
+	flags.StringVar(&config.OutputPath,
+		"o",
+		"",
+		"Path to the tar file to write (instead of stdout).",
+	)
+	flags.Func("include",
+		"Export only the archive items matching the pattern (see path.Match; may be repeated).",
+		func(value string) error {
+			config.Selection.Include = append(config.Selection.Include, value)
+			return nil
+		},
+	)
+	flags.Func("exclude",
+		"Leave out the archive items matching the pattern (see path.Match; may be repeated).",
+		func(value string) error {
+			config.Selection.Exclude = append(config.Selection.Exclude, value)
+			return nil
+		},
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access a public bucket with unsigned requests instead of Google credentials.",
+	)
+	flags.IntVar(&config.MaxRetry,
+		"max-retry",
+		5,
+		"How many times to retry the downloads.",
+	)
+	flags.StringVar(&config.jsonPath,
+		"json",
+		"",
+		"Path to a dependency listing (or _STDIN_) whose credentials apply as they would for an installation: those of the package, if listed, or else of its bucket or of the listing.",
+	)
+	flags.StringVar(&config.profile,
+		"profile",
+		os.Getenv(ListingProfileVariable),
+		"The profile of the dependency listing to apply (defaults to $SATISFY_PROFILE, if set).",
+	)
+
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s export -bucket <name> [-path <prefix>] [-json <listing>] [-o <file>] [-include <pattern>]... [-exclude <pattern>]... <package>@<version>\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Downloads the archive of the package, verifies it against the manifest and writes its contents as")
+		_, _ = fmt.Fprintln(output, "  an uncompressed tar stream, whatever the compression of the archive. The version may also be")
+		_, _ = fmt.Fprintln(output, "  'latest' or a version constraint, e.g. ^1.4.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return ExportConfig{}, err
+	}
+	if flags.NArg() != 1 {
+		return ExportConfig{}, errors.New("exactly one <package>@<version> is required")
+	}
+	packageName, version, found := cutLast(flags.Arg(0), "@")
+	if !found || version == "" {
+		return ExportConfig{}, fmt.Errorf("%q should be <package>@<version>", flags.Arg(0))
+	}
+	config.PackageVersion = version
+	config.RemoteAddress, config.PackageName, err = parsePackageLocation(bucket, prefix, packageName)
+	if err != nil {
+		return ExportConfig{}, err
+	}
+	if err = contracts.ValidateVersion(version); err != nil {
+		return ExportConfig{}, err
+	}
+	if err = config.Selection.Validate(); err != nil {
+		return ExportConfig{}, err
+	}
+	if config.jsonPath == "" {
+		return config, nil
+	}
+	config.Dependencies, err = readDependencyListing(config.jsonPath, config.profile)
+	if err == nil {
+		err = config.Dependencies.Validate()
+	}
+	if err != nil {
+		return ExportConfig{}, err
+	}
+	return config, nil
+}
+
+func cutLast(value, separator string) (before, after string, found bool) {
+	if i := strings.LastIndex(value, separator); i >= 0 {
+		return value[:i], value[i+len(separator):], true
+	}
+	return value, "", false
+}
+
+type ExportApp struct {
+	config ExportConfig
+	stdout *os.File
+}
+
+func NewExportApp(config ExportConfig) *ExportApp {
+	return &ExportApp{config: config, stdout: os.Stdout}
+}
+
+func (this *ExportApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *ExportApp) TryRun() error {
+	if this.config.OutputPath == "" && isTerminal(this.stdout) {
+		return errors.New("refusing to write a tar stream to a terminal (use -o or redirect stdout)")
+	}
+
+	storage := newRemoteStorage(this.config.Dependencies, []int{http.StatusOK}, this.config.MaxRetry, this.config.Anonymous)
+	installer := core.NewPackageInstaller(storage, shell.NewDiskFileSystem(""), nil, false) // downloads manifests only
+	manifests := newManifestLoader(installer, core.NewPackageCatalog(storage), false)
+	dependency := contracts.Dependency{
+		PackageName:    this.config.PackageName,
+		PackageVersion: this.config.PackageVersion,
+		RemoteAddress:  this.config.RemoteAddress,
+	}
+	manifest, err := manifests.DownloadManifest(dependency)
+	if err != nil {
+		return fmt.Errorf("could not download manifest for %s: %w", dependency.Title(), err)
+	}
+	dependency.PackageVersion = manifest.Version
+
+	spool, err := os.CreateTemp("", "satisfy-export-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = spool.Close(); _ = os.Remove(spool.Name()) }()
+
+	exporter := core.NewPackageExporter(storage)
+	log.Printf("Downloading and verifying the archive of %s", dependency.Title())
+	err = exporter.DownloadArchive(manifest, dependency.ComposeRemoteAddress(contracts.RemoteArchiveFilename), spool)
+	if err != nil {
+		return fmt.Errorf("could not download the archive of %s: %w", dependency.Title(), err)
+	}
+	size, err := spool.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	return this.writeOutput(func(output io.Writer) error {
+		writer := shell.NewTarArchiveWriter(output)
+		exported, err := exporter.Export(manifest, spool, size, this.config.Selection, writer)
+		if err != nil {
+			return fmt.Errorf("could not export %s: %w", dependency.Title(), err)
+		}
+		if err = writer.Close(); err != nil {
+			return err
+		}
+		log.Printf("Exported %d items of %s", exported, dependency.Title())
+		return nil
+	})
+}
+
+// writeOutput writes to stdout or else to a temporary file that replaces the output file only once complete.
+func (this *ExportApp) writeOutput(write func(io.Writer) error) error {
+	if this.config.OutputPath == "" {
+		return write(this.stdout)
+	}
+	file, err := os.CreateTemp(filepath.Dir(this.config.OutputPath), ".satisfy-export-*")
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), this.config.OutputPath)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestParseExportConfigFixture(t *testing.T) {
+	gunit.Run(new(ParseExportConfigFixture), t)
+}
+
+type ParseExportConfigFixture struct {
+	*gunit.Fixture
+}
+
+func (this *ParseExportConfigFixture) TestPackageAndVersionAreParsed() {
+	config, err := ParseExportConfig([]string{
+		"-bucket", "public-bucket", "-path", "/releases", "-anonymous", "-o", "out.tar",
+		"-include", "data/*", "-exclude", "*.md", "-exclude", "docs",
+		"scope/package@1.2.3",
+	})
+
+	this.So(err, should.BeNil)
+	this.So(config.PackageName, should.Equal, "scope/package")
+	this.So(config.PackageVersion, should.Equal, "1.2.3")
+	this.So(config.RemoteAddress, should.Resemble, contracts.URL{Scheme: "gcs", Host: "public-bucket", Path: "/releases"})
+	this.So(config.OutputPath, should.Equal, "out.tar")
+	this.So(config.Selection, should.Resemble, contracts.PathSelection{Include: []string{"data/*"}, Exclude: []string{"*.md", "docs"}})
+}
+
+func (this *ParseExportConfigFixture) TestVersionIsRequired() {
+	_, err := ParseExportConfig([]string{"-bucket", "b", "-anonymous", "package"})
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "should be <package>@<version>")
+
+	_, err = ParseExportConfig([]string{"-bucket", "b", "-anonymous", "package@"})
+	this.So(err, should.NotBeNil)
+}
+
+func (this *ParseExportConfigFixture) TestExactlyOnePackageIsRequired() {
+	_, err := ParseExportConfig([]string{"-bucket", "b", "-anonymous"})
+	this.So(err, should.NotBeNil)
+
+	_, err = ParseExportConfig([]string{"-bucket", "b", "-anonymous", "a@1.0.0", "b@1.0.0"})
+	this.So(err, should.NotBeNil)
+}
+
+func (this *ParseExportConfigFixture) TestMalformedFiltersAreRejected() {
+	_, err := ParseExportConfig([]string{"-bucket", "b", "-anonymous", "-include", "[a", "package@1.0.0"})
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "malformed path pattern")
+}
+
+func (this *ParseExportConfigFixture) TestMalformedVersionConstraintIsRejected() {
+	_, err := ParseExportConfig([]string{"-bucket", "b", "-anonymous", "package@>=1.0 <2.x.0"})
+	this.So(err, should.NotBeNil)
+}
+
+func (this *ParseExportConfigFixture) TestListingSuppliesTheCredentials() {
+	root, _ := os.MkdirTemp("", "satisfy-export-")
+	defer func() { _ = os.RemoveAll(root) }()
+	path := filepath.Join(root, "satisfy.json")
+	_ = os.WriteFile(path, []byte(`{
+		"credentials": "default-token",
+		"dependencies": [
+			{"package_name": "package", "package_version": "1.0.0", "remote_address": "gcs://b/", "local_directory": "p", "credentials": "package-token"}
+		]
+	}`), 0644)
+
+	config, err := ParseExportConfig([]string{"-bucket", "b", "-json", path, "package@1.0.0"})
+
+	this.So(err, should.BeNil)
+	this.So(config.Dependencies.Credentials, should.Equal, "default-token")
+	this.So(config.Dependencies.Listing, should.HaveLength, 1)
+	this.So(config.Dependencies.Listing[0].Credentials, should.Equal, "package-token")
+}
+
+func (this *ParseExportConfigFixture) TestWithoutListingTheDefaultCredentialsApply() {
+	config, err := ParseExportConfig([]string{"-bucket", "b", "package@1.0.0"})
+
+	this.So(err, should.BeNil)
+	this.So(config.Dependencies, should.BeZeroValue)
+}
@@ -0,0 +1,55 @@
+package transfer
+
+import (
+	"fmt"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+// resolveDependencyGraph adds the packages required by the listed packages (see core.DependencyGraph)
+// and validates the result as a whole, as if the required packages had been listed. With a lock
+// file (the listed packages being pinned already) the required packages are pinned as well.
+func resolveDependencyGraph(manifests core.ManifestLoader, listing []contracts.Dependency, pins *lockPins) ([]core.RequiredDependency, error) {
+	if pins != nil {
+		manifests = pinnedManifests{ManifestLoader: manifests, lock: pins.lock}
+	}
+	graph, err := core.NewDependencyGraph(manifests).Resolve(listing)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the package dependencies: %w", err)
+	}
+	for i, required := range graph {
+		if required.Listed {
+			continue
+		}
+		if graph[i].Dependency, err = pins.pin(required.Dependency); err != nil {
+			return nil, err
+		}
+	}
+	pins.reportUnused()
+	all := contracts.DependencyListing{Listing: make([]contracts.Dependency, 0, len(graph))}
+	for _, dependency := range graph {
+		all.Listing = append(all.Listing, dependency.Dependency)
+	}
+	if err = all.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid package dependencies: %w", err)
+	}
+	for i := range graph {
+		graph[i].Dependency = all.Listing[i]
+	}
+	return graph, nil
+}
+
+// pinnedManifests loads the manifest of the locked version of a required package (if locked), so that
+// the packages it requires in turn are those of the version to be installed.
+type pinnedManifests struct {
+	core.ManifestLoader
+	lock contracts.LockFile
+}
+
+func (this pinnedManifests) LoadManifest(dependency contracts.Dependency) (contracts.Manifest, error) {
+	if entry, found := this.lock.Find(dependency); found {
+		dependency.PackageVersion = entry.PackageVersion
+	}
+	return this.ManifestLoader.LoadManifest(dependency)
+}
@@ -1,7 +1,6 @@
 package transfer
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -10,20 +9,15 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"time"
-
-	"github.com/smarty/gcs"
 
 	"github.com/smarty/satisfy/contracts"
-	"github.com/smarty/satisfy/core"
-	"github.com/smarty/satisfy/shell"
 )
 
 type LatestConfig struct {
-	PackageName       string
-	RemoteAddress     contracts.URL
-	GoogleCredentials gcs.Credentials
-	MaxRetry          int
+	PackageName   string
+	RemoteAddress contracts.URL
+	Anonymous     bool
+	MaxRetry      int
 }
 
 func ParseLatestConfig(args []string) (config LatestConfig, err error) {
@@ -45,6 +39,11 @@ func ParseLatestConfig(args []string) (config LatestConfig, err error) {
 		"",
 		"Package name (required), e.g. master-address-list/2026/04/premium/az.",
 	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access a public bucket with unsigned requests instead of Google credentials.",
+	)
 	flags.IntVar(&config.MaxRetry,
 		"max-retry",
 		5,
@@ -53,7 +52,7 @@ func ParseLatestConfig(args []string) (config LatestConfig, err error) {
 
 	flags.Usage = func() {
 		output := flags.Output()
-		_, _ = fmt.Fprintf(output, "Usage: %s latest -bucket <name> [-path <prefix>] -package <name>\n\n", os.Args[0])
+		_, _ = fmt.Fprintf(output, "Usage: %s latest -bucket <name> [-path <prefix>] -package <name> [-anonymous]\n\n", os.Args[0])
 		flags.PrintDefaults()
 	}
 
@@ -61,29 +60,33 @@ func ParseLatestConfig(args []string) (config LatestConfig, err error) {
 		return LatestConfig{}, err
 	}
 
+	config.RemoteAddress, config.PackageName, err = parsePackageLocation(bucket, prefix, packageName)
+	if err != nil {
+		return LatestConfig{}, err
+	}
+
+	return config, nil
+}
+
+// parsePackageLocation validates the -bucket, -path and -package flags shared by the subcommands that query remote storage.
+func parsePackageLocation(bucket, prefix, packageName string) (address contracts.URL, name string, err error) {
 	if bucket == "" {
-		return LatestConfig{}, errors.New("-bucket is required")
+		return address, "", errors.New("-bucket is required")
 	}
 	if strings.Contains(bucket, "/") {
-		return LatestConfig{}, errors.New("-bucket should be a bare bucket name (no scheme, no slashes)")
+		return address, "", errors.New("-bucket should be a bare bucket name (no scheme, no slashes)")
 	}
 
-	config.PackageName = strings.Trim(packageName, "/")
-	if config.PackageName == "" {
-		return LatestConfig{}, errors.New("-package is required")
+	name = strings.Trim(packageName, "/")
+	if name == "" {
+		return address, "", errors.New("-package is required")
 	}
 
-	config.RemoteAddress = contracts.URL{Scheme: "gcs", Host: bucket}
+	address = contracts.URL{Scheme: "gcs", Host: bucket}
 	if prefix = strings.Trim(prefix, "/"); prefix != "" {
-		config.RemoteAddress.Path = "/" + prefix
-	}
-
-	reader := gcs.NewCredentialsReader()
-	config.GoogleCredentials, err = reader.Read(context.Background(), "")
-	if err != nil {
-		return LatestConfig{}, fmt.Errorf("could not load Google credentials: %w", err)
+		address.Path = "/" + prefix
 	}
-	return config, nil
+	return address, name, nil
 }
 
 type LatestApp struct {
@@ -101,8 +104,7 @@ func (this *LatestApp) Run() {
 }
 
 func (this *LatestApp) TryRun() error {
-	gcsClient := shell.NewGoogleCloudStorageClient(shell.NewHTTPClient(), this.config.GoogleCredentials, []int{http.StatusOK})
-	client := core.NewRetryClient(gcsClient, this.config.MaxRetry, time.Sleep)
+	client := newRemoteStorage(contracts.DependencyListing{}, []int{http.StatusOK}, this.config.MaxRetry, this.config.Anonymous)
 
 	dependency := contracts.Dependency{
 		PackageName:   this.config.PackageName,
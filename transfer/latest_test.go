@@ -44,3 +44,9 @@ func (this *ParseLatestConfigFixture) TestPackageOfOnlySlashesIsRejected() {
 	this.So(err, should.NotBeNil)
 	this.So(err.Error(), should.ContainSubstring, "-package is required")
 }
+
+func (this *ParseLatestConfigFixture) TestAnonymousAccessNeedsNoCredentials() {
+	config, err := ParseLatestConfig([]string{"-bucket", "public-bucket", "-package", "p", "-anonymous"})
+	this.So(err, should.BeNil)
+	this.So(config.Anonymous, should.BeTrue)
+}
@@ -0,0 +1,328 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+// listingDocument is a dependency listing as written, before it is composed with the listings it includes.
+type listingDocument struct {
+	Credentials       string                        `json:"credentials,omitempty"`
+	RemoteCredentials []contracts.RemoteCredentials `json:"remote_credentials,omitempty"`
+	Include           []string                      `json:"include,omitempty"`
+	Profiles          map[string]listingProfile     `json:"profiles,omitempty"`
+	Dependencies      []map[string]any              `json:"dependencies"`
+}
+
+// listingProfile overrides the dependency listing when selected (e.g. the buckets and versions of an environment).
+type listingProfile struct {
+	RemoteAddress     string                        `json:"remote_address,omitempty"`
+	Credentials       string                        `json:"credentials,omitempty"`
+	RemoteCredentials []contracts.RemoteCredentials `json:"remote_credentials,omitempty"`
+	Dependencies      []map[string]any              `json:"dependencies,omitempty"`
+
+	err error // interpolating the profile failed (which only matters once it is selected)
+}
+
+// ListingProfileVariable names the environment variable selecting the profile of the dependency listing (see -profile).
+const ListingProfileVariable = "SATISFY_PROFILE"
+
+// composeDependencyListing reads the listing in raw (found at path; use "" when not read from a file)
+// and applies the named profile (if any).
+//
+// Every ${VAR} in the listing and the selected profile is replaced by the value of the environment
+// variable. A relative local_directory (or credentials file) is relative to the directory of the
+// listing file; so are the listing credentials when they name a key file there. The
+// listings named by include (relative to the directory of the listing file) are composed first,
+// in order: a dependency of the including listing is merged into the included dependencies with
+// the same package name (its fields replacing theirs) or else added to them. The remote
+// credentials of the including listing take precedence over those of the included listings.
+// Profiles of the same name are composed the same way.
+//
+// A profile's remote_address replaces that of every dependency, and then its dependencies are
+// merged into those of the listing by package name.
+func composeDependencyListing(raw []byte, path, profile string) (listing contracts.DependencyListing, err error) {
+	document, err := composeListingDocument(raw, path, nil)
+	if err != nil {
+		return listing, err
+	}
+	if profile != "" {
+		selected, found := document.Profiles[profile]
+		if !found {
+			return listing, fmt.Errorf("no profile %q in the dependency listing (profiles: %s)", profile, describeProfiles(document.Profiles))
+		}
+		if selected.err != nil {
+			return listing, fmt.Errorf("profile %q: %w", profile, selected.err)
+		}
+		document = applyProfile(document, selected)
+	}
+	document.Include = nil
+	document.Profiles = nil
+	raw, err = json.Marshal(document)
+	if err != nil {
+		return listing, err
+	}
+	return listing, json.Unmarshal(raw, &listing)
+}
+
+func composeListingDocument(raw []byte, path string, including []string) (document listingDocument, err error) {
+	directory := "."
+	if path != "" {
+		directory = filepath.Dir(path)
+	}
+	document, err = parseListingDocument(raw)
+	if err != nil {
+		return document, describeListingError(path, err)
+	}
+	document.Credentials = resolveCredentialsFile(directory, document.Credentials)
+	resolveRelativePaths(directory, document.Dependencies, document.RemoteCredentials)
+	for name, profile := range document.Profiles {
+		profile.Credentials = resolveCredentialsFile(directory, profile.Credentials)
+		resolveRelativePaths(directory, profile.Dependencies, profile.RemoteCredentials)
+		document.Profiles[name] = profile
+	}
+
+	if path != "" {
+		including = append(including[:len(including):len(including)], path)
+	}
+	composed := listingDocument{Dependencies: []map[string]any{}, Profiles: map[string]listingProfile{}}
+	for _, include := range document.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(directory, include)
+		}
+		included, err := includeListingDocument(include, including)
+		if err != nil {
+			return document, err
+		}
+		if included.Credentials != "" {
+			composed.Credentials = included.Credentials
+		}
+		composed.RemoteCredentials = append(included.RemoteCredentials, composed.RemoteCredentials...)
+		composed.Dependencies = mergeDependencies(composed.Dependencies, included.Dependencies)
+		for name, profile := range included.Profiles {
+			composed.Profiles[name] = mergeProfiles(composed.Profiles[name], profile)
+		}
+	}
+	if document.Credentials != "" {
+		composed.Credentials = document.Credentials
+	}
+	composed.RemoteCredentials = append(document.RemoteCredentials, composed.RemoteCredentials...)
+	composed.Dependencies = mergeDependencies(composed.Dependencies, document.Dependencies)
+	for name, profile := range document.Profiles {
+		composed.Profiles[name] = mergeProfiles(composed.Profiles[name], profile)
+	}
+	return composed, nil
+}
+
+func resolveRelativePaths(directory string, dependencies []map[string]any, remotes []contracts.RemoteCredentials) {
+	for _, dependency := range dependencies {
+		if value, ok := dependency["local_directory"].(string); ok && isRelativePath(value) {
+			dependency["local_directory"] = filepath.Join(directory, value)
+		}
+		if value, ok := dependency["credentials"].(string); ok {
+			dependency["credentials"] = resolveCredentialsFile(directory, value)
+		}
+	}
+	for i, remote := range remotes {
+		remotes[i].Credentials = resolveCredentialsFile(directory, remote.Credentials)
+	}
+}
+
+// resolveCredentialsFile resolves credentials (of the listing, of a host or of a dependency: an access token
+// or else the name of a key file) when they name a file in the directory.
+func resolveCredentialsFile(directory, credentials string) string {
+	if !isRelativePath(credentials) {
+		return credentials
+	}
+	path := filepath.Join(directory, credentials)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return credentials
+	}
+	return path
+}
+
+// mergeProfiles composes the override (of an including listing) with the profile of the same name.
+func mergeProfiles(profile, override listingProfile) listingProfile {
+	if override.RemoteAddress != "" {
+		profile.RemoteAddress = override.RemoteAddress
+	}
+	if override.Credentials != "" {
+		profile.Credentials = override.Credentials
+	}
+	profile.RemoteCredentials = append(override.RemoteCredentials, profile.RemoteCredentials...)
+	profile.Dependencies = mergeDependencies(profile.Dependencies, override.Dependencies)
+	if profile.err == nil {
+		profile.err = override.err
+	}
+	return profile
+}
+
+func applyProfile(document listingDocument, profile listingProfile) listingDocument {
+	if profile.RemoteAddress != "" {
+		for _, dependency := range document.Dependencies {
+			dependency["remote_address"] = profile.RemoteAddress
+		}
+	}
+	if profile.Credentials != "" {
+		document.Credentials = profile.Credentials
+	}
+	document.RemoteCredentials = append(profile.RemoteCredentials, document.RemoteCredentials...)
+	document.Dependencies = mergeDependencies(document.Dependencies, profile.Dependencies)
+	return document
+}
+
+func describeProfiles(profiles map[string]listingProfile) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func includeListingDocument(path string, including []string) (listingDocument, error) {
+	for _, parent := range including {
+		if sameFile(parent, path) {
+			return listingDocument{}, fmt.Errorf("dependency listing %q includes itself (%s -> %s)", path, strings.Join(including, " -> "), path)
+		}
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return listingDocument{}, fmt.Errorf("could not read included dependency listing: %w", err)
+	}
+	return composeListingDocument(raw, path, including)
+}
+
+func sameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// parseListingDocument interpolates the profiles apart from the rest of the listing: a profile
+// referring to an undefined variable is only an error once it is selected.
+func parseListingDocument(raw []byte) (document listingDocument, err error) {
+	var tree map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&tree); err != nil {
+		return document, err
+	}
+	profiles, ok := tree["profiles"].(map[string]any)
+	if !ok && tree["profiles"] != nil {
+		return document, errors.New("profiles should be an object of named profiles")
+	}
+	delete(tree, "profiles")
+	if _, err = interpolate(tree); err != nil {
+		return document, err
+	}
+	if err = decodeTree(tree, &document); err != nil {
+		return document, err
+	}
+	for name, value := range profiles {
+		var profile listingProfile
+		_, interpolationErr := interpolate(value)
+		if err = decodeTree(value, &profile); err != nil {
+			return document, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profile.err = interpolationErr
+		if document.Profiles == nil {
+			document.Profiles = make(map[string]listingProfile)
+		}
+		document.Profiles[name] = profile
+	}
+	return document, nil
+}
+
+func decodeTree(tree any, target any) error {
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+func describeListingError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("dependency listing %q: %w", path, err)
+}
+
+var variableReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+// interpolate replaces every ${VAR} in the strings of the (decoded JSON) value.
+func interpolate(value any) (any, error) {
+	switch value := value.(type) {
+	case string:
+		var err error
+		interpolated := variableReference.ReplaceAllStringFunc(value, func(reference string) string {
+			name := variableReference.FindStringSubmatch(reference)[1]
+			replacement, found := os.LookupEnv(name)
+			if !found && err == nil {
+				err = fmt.Errorf("undefined environment variable %q in %q", name, value)
+			}
+			return replacement
+		})
+		return interpolated, err
+	case []any:
+		for i, item := range value {
+			interpolated, err := interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = interpolated
+		}
+	case map[string]any:
+		for key, item := range value {
+			interpolated, err := interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = interpolated
+		}
+	}
+	return value, nil
+}
+
+// isRelativePath excludes the paths relative to the home directory (see contracts.DependencyListing.Validate).
+func isRelativePath(value string) bool {
+	return value != "" && !filepath.IsAbs(value) && !strings.HasPrefix(value, "~") && !strings.HasPrefix(value, "$")
+}
+
+// mergeDependencies merges each override into all of the dependencies with the same package name or adds it.
+func mergeDependencies(dependencies, overrides []map[string]any) []map[string]any {
+	for _, override := range overrides {
+		merged := false
+		for _, dependency := range dependencies {
+			if dependency["package_name"] != override["package_name"] {
+				continue
+			}
+			for key, value := range override {
+				dependency[key] = value
+			}
+			merged = true
+		}
+		if !merged {
+			dependencies = append(dependencies, override)
+		}
+	}
+	return dependencies
+}
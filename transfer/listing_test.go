@@ -0,0 +1,266 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestListingFixture(t *testing.T) {
+	gunit.Run(new(ListingFixture), t)
+}
+
+type ListingFixture struct {
+	*gunit.Fixture
+
+	root      string
+	variables []string
+}
+
+func (this *ListingFixture) Setup() {
+	this.root, _ = os.MkdirTemp("", "satisfy-listing-")
+	_ = os.MkdirAll(filepath.Join(this.root, "shared"), 0755)
+	_ = os.MkdirAll(filepath.Join(this.root, "service"), 0755)
+	this.write("shared/base.json", `{
+		"credentials": "base-credentials.json",
+		"dependencies": [
+			{"package_name": "tools", "package_version": "1.0.0", "remote_address": "gcs://bucket/packages", "local_directory": "tools"},
+			{"package_name": "data", "package_version": "2.0.0", "remote_address": "gcs://bucket/packages", "local_directory": "/opt/data", "include": ["a/*"]}
+		]
+	}`)
+}
+
+func (this *ListingFixture) Teardown() {
+	_ = os.RemoveAll(this.root)
+	for _, key := range this.variables {
+		_ = os.Unsetenv(key)
+	}
+}
+
+func (this *ListingFixture) write(name, content string) string {
+	path := filepath.Join(this.root, name)
+	_ = os.WriteFile(path, []byte(content), 0644)
+	return path
+}
+
+func (this *ListingFixture) TestRelativeLocalDirectoriesAreRelativeToTheListingFile() {
+	listing, err := readFromFile(filepath.Join(this.root, "shared/base.json"), "")
+
+	this.So(err, should.BeNil)
+	this.So(listing.Credentials, should.Equal, "base-credentials.json")
+	this.So(listing.Listing[0].LocalDirectory, should.Equal, filepath.Join(this.root, "shared/tools"))
+	this.So(listing.Listing[0].RemoteAddress, should.Resemble, contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"})
+	this.So(listing.Listing[1].LocalDirectory, should.Equal, "/opt/data")
+}
+
+func (this *ListingFixture) TestIncludedDependenciesAreMergedWithOverrides() {
+	path := this.write("service/listing.json", `{
+		"include": ["../shared/base.json"],
+		"dependencies": [
+			{"package_name": "data", "package_version": "2.1.0", "local_directory": "data"},
+			{"package_name": "service", "package_version": "3.0.0", "remote_address": "gcs://bucket/services", "local_directory": "/srv"}
+		]
+	}`)
+
+	listing, err := readFromFile(path, "")
+
+	this.So(err, should.BeNil)
+	this.So(listing.Credentials, should.Equal, "base-credentials.json")
+	this.So(listing.Listing, should.HaveLength, 3)
+	this.So(listing.Listing[0].LocalDirectory, should.Equal, filepath.Join(this.root, "shared/tools"))
+	this.So(listing.Listing[1], should.Resemble, contracts.Dependency{
+		PackageName:    "data",
+		PackageVersion: "2.1.0",
+		RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"},
+		LocalDirectory: filepath.Join(this.root, "service/data"),
+		Include:        []string{"a/*"},
+	})
+	this.So(listing.Listing[2].PackageName, should.Equal, "service")
+}
+
+func (this *ListingFixture) TestCredentialsAreComposedRelativeToTheirListingFile() {
+	_ = os.MkdirAll(filepath.Join(this.root, "shared/keys"), 0755)
+	this.write("shared/keys/fallback.json", "{}")
+	this.write("shared/keys/tools.json", "{}")
+	this.write("shared/credentials.json", `{
+		"remote_credentials": [{"host": "*", "credentials": "keys/fallback.json"}, {"host": "public-*", "credentials": "host-token"}],
+		"dependencies": [{"package_name": "tools", "credentials": "keys/tools.json"}, {"package_name": "data", "credentials": "data-token"}]
+	}`)
+	path := this.write("service/listing.json", `{
+		"include": ["../shared/base.json", "../shared/credentials.json"],
+		"remote_credentials": [{"host": "project-a-*", "credentials": "/etc/keys/project-a.json"}],
+		"dependencies": []
+	}`)
+
+	listing, err := readFromFile(path, "")
+
+	this.So(err, should.BeNil)
+	this.So(listing.RemoteCredentials, should.Resemble, []contracts.RemoteCredentials{
+		{Host: "project-a-*", Credentials: "/etc/keys/project-a.json"},
+		{Host: "*", Credentials: filepath.Join(this.root, "shared/keys/fallback.json")},
+		{Host: "public-*", Credentials: "host-token"},
+	})
+	this.So(listing.Listing[0].Credentials, should.Equal, filepath.Join(this.root, "shared/keys/tools.json"))
+	this.So(listing.Listing[1].Credentials, should.Equal, "data-token")
+}
+
+func (this *ListingFixture) TestListingCredentialsNamingAKeyFileAreRelativeToTheListingFile() {
+	_ = os.MkdirAll(filepath.Join(this.root, "service/keys"), 0755)
+	this.write("service/keys/default.json", "{}")
+	this.write("service/keys/prod.json", "{}")
+	path := this.write("service/listing.json", `{
+		"credentials": "keys/default.json",
+		"profiles": {"prod": {"credentials": "keys/prod.json"}, "token": {"credentials": "access-token"}},
+		"dependencies": []
+	}`)
+
+	listing, err := readFromFile(path, "")
+	this.So(err, should.BeNil)
+	this.So(listing.Credentials, should.Equal, filepath.Join(this.root, "service/keys/default.json"))
+
+	prod, err := readFromFile(path, "prod")
+	this.So(err, should.BeNil)
+	this.So(prod.Credentials, should.Equal, filepath.Join(this.root, "service/keys/prod.json"))
+
+	token, err := readFromFile(path, "token")
+	this.So(err, should.BeNil)
+	this.So(token.Credentials, should.Equal, "access-token")
+}
+
+func (this *ListingFixture) TestVariablesAreInterpolatedInEveryField() {
+	this.setenv("SATISFY_TEST_BUCKET", "other-bucket")
+	this.setenv("SATISFY_TEST_VERSION", "4.5.6")
+	this.setenv("SATISFY_TEST_ROOT", "/var/lib")
+	path := this.write("service/listing.json", `{"dependencies": [{
+		"package_name": "data",
+		"package_version": "${SATISFY_TEST_VERSION}",
+		"remote_address": "gcs://${SATISFY_TEST_BUCKET}/packages",
+		"local_directory": "${SATISFY_TEST_ROOT}/data",
+		"retain_versions": 3
+	}]}`)
+
+	listing, err := readFromFile(path, "")
+
+	this.So(err, should.BeNil)
+	this.So(listing.Listing[0].PackageVersion, should.Equal, "4.5.6")
+	this.So(listing.Listing[0].RemoteAddress.Host, should.Equal, "other-bucket")
+	this.So(listing.Listing[0].LocalDirectory, should.Equal, "/var/lib/data")
+	this.So(listing.Listing[0].RetainVersions, should.Equal, 3)
+}
+
+func (this *ListingFixture) TestUndefinedVariablesAreAnError() {
+	path := this.write("service/listing.json", `{"dependencies": [{"package_name": "${SATISFY_TEST_UNDEFINED}"}]}`)
+
+	_, err := readFromFile(path, "")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, `undefined environment variable "SATISFY_TEST_UNDEFINED"`)
+}
+
+func (this *ListingFixture) TestUndefinedVariablesOfUnselectedProfilesAreIgnored() {
+	path := this.write("service/listing.json", `{
+		"profiles": {"prod": {"credentials": "${SATISFY_TEST_UNDEFINED}"}, "dev": {}},
+		"dependencies": [{"package_name": "tools", "package_version": "1.0.0", "remote_address": "gcs://bucket/packages", "local_directory": "tools"}]
+	}`)
+
+	_, err := readFromFile(path, "dev")
+	this.So(err, should.BeNil)
+
+	_, err = readFromFile(path, "prod")
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, `profile "prod": undefined environment variable "SATISFY_TEST_UNDEFINED"`)
+}
+
+func (this *ListingFixture) TestIncludeCyclesAreAnError() {
+	this.write("shared/a.json", `{"include": ["b.json"], "dependencies": []}`)
+	this.write("shared/b.json", `{"include": ["a.json"], "dependencies": []}`)
+
+	_, err := readFromFile(filepath.Join(this.root, "shared/a.json"), "")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "includes itself")
+}
+
+func (this *ListingFixture) TestMissingIncludeIsAnError() {
+	path := this.write("service/listing.json", `{"include": ["missing.json"], "dependencies": []}`)
+
+	_, err := readFromFile(path, "")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "could not read included dependency listing")
+}
+
+func (this *ListingFixture) TestProfileOverridesBucketsVersionsAndDirectories() {
+	path := this.write("service/listing.json", `{
+		"include": ["../shared/base.json"],
+		"profiles": {
+			"prod": {
+				"remote_address": "gcs://prod-bucket/packages",
+				"dependencies": [{"package_name": "data", "package_version": "2.0.1", "local_directory": "prod-data"}]
+			}
+		},
+		"dependencies": []
+	}`)
+
+	base, err := readFromFile(path, "")
+	this.So(err, should.BeNil)
+	this.So(base.Listing[1].PackageVersion, should.Equal, "2.0.0")
+
+	prod, err := readFromFile(path, "prod")
+	this.So(err, should.BeNil)
+	this.So(prod.Listing, should.HaveLength, 2)
+	this.So(prod.Listing[0].RemoteAddress.Host, should.Equal, "prod-bucket")
+	this.So(prod.Listing[1].RemoteAddress.Host, should.Equal, "prod-bucket")
+	this.So(prod.Listing[1].PackageVersion, should.Equal, "2.0.1")
+	this.So(prod.Listing[1].LocalDirectory, should.Equal, filepath.Join(this.root, "service/prod-data"))
+}
+
+func (this *ListingFixture) TestProfilesOfIncludedListingsAreComposed() {
+	this.write("shared/profiles.json", `{
+		"profiles": {"staging": {"remote_address": "gcs://staging-bucket/packages"}},
+		"dependencies": [{"package_name": "tools", "package_version": "1.0.0", "remote_address": "gcs://bucket/packages", "local_directory": "tools"}]
+	}`)
+	path := this.write("service/listing.json", `{
+		"include": ["../shared/profiles.json"],
+		"profiles": {"staging": {"dependencies": [{"package_name": "tools", "package_version": "1.1.0-rc1"}]}},
+		"dependencies": []
+	}`)
+
+	listing, err := readFromFile(path, "staging")
+
+	this.So(err, should.BeNil)
+	this.So(listing.Listing[0].RemoteAddress.Host, should.Equal, "staging-bucket")
+	this.So(listing.Listing[0].PackageVersion, should.Equal, "1.1.0-rc1")
+}
+
+func (this *ListingFixture) TestUnknownProfileIsAnError() {
+	_, err := readFromFile(filepath.Join(this.root, "shared/base.json"), "prod")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, `no profile "prod" in the dependency listing (profiles: none)`)
+}
+
+func (this *ListingFixture) TestListingIsValidatedWithTheProfileApplied() {
+	path := this.write("service/listing.json", `{
+		"include": ["../shared/base.json"],
+		"profiles": {"prod": {"dependencies": [{"package_name": "data", "layout": "versioned", "local_directory": "../shared/tools"}]}},
+		"dependencies": []
+	}`)
+
+	_, err := loadDependencyListing(path, "", nil)
+	this.So(err, should.BeNil)
+
+	_, err = loadDependencyListing(path, "prod", nil)
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "requires exclusive use of local directory")
+}
+
+func (this *ListingFixture) setenv(key, value string) {
+	_ = os.Setenv(key, value)
+	this.variables = append(this.variables, key)
+}
@@ -0,0 +1,289 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type LockConfig struct {
+	MaxRetry     int
+	Update       bool
+	LockPath     string
+	Packages     []string
+	Anonymous    bool
+	Dependencies contracts.DependencyListing
+	jsonPath     string
+	profile      string
+}
+
+func ParseLockConfig(args []string) (config LockConfig, err error) {
+	flags := flag.NewFlagSet("satisfy lock", flag.ContinueOnError)
+	flags.IntVar(&config.MaxRetry,
+		"max-retry",
+		5,
+		"How many times to retry attempts to download manifests.",
+	)
+	flags.BoolVar(&config.Update,
+		"update",
+		false,
+		"When set, re-resolve the named packages (or all packages when none are named) even if already locked.",
+	)
+	flags.StringVar(&config.LockPath,
+		"lock",
+		DefaultLockPath,
+		"Path to the lock file to write (defaults to "+DefaultLockPath+" next to the -json listing).",
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access public buckets with unsigned requests instead of the default Google credentials (dependencies with credentials of their own still use them).",
+	)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s lock [-json <listing>] [-profile <name>] [-lock <path>] [-update] [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Resolves every dependency of the listing (and every package they require) to a concrete version and")
+		_, _ = fmt.Fprintln(output, "  records it, along with the digest of its manifest, in the lock file. Entries already locked are kept")
+		_, _ = fmt.Fprintln(output, "  unless -update is set; entries no longer needed are dropped.")
+		_, _ = fmt.Fprintln(output, "  Installations honour the lock file and fail when a remote manifest no longer matches its digest.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return LockConfig{}, err
+	}
+	if flags.NArg() > 0 && !config.Update {
+		return LockConfig{}, errors.New("package names may only be specified along with -update")
+	}
+	config.Packages = flags.Args()
+	config.LockPath = resolveLockPath(flags, config.LockPath, config.jsonPath)
+	if config.LockPath == "" {
+		return LockConfig{}, errors.New("-lock is required when the listing is read from stdin")
+	}
+
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, nil)
+	if err != nil {
+		return LockConfig{}, err
+	}
+	if len(core.Filter(config.Dependencies.Listing, config.Packages)) == 0 && len(config.Packages) > 0 {
+		return LockConfig{}, errors.New("none of the named packages are in the dependency listing")
+	}
+
+	return config, nil
+}
+
+type LockApp struct {
+	config    LockConfig
+	installer *core.PackageInstaller
+	catalog   *core.PackageCatalog
+}
+
+func NewLockApp(config LockConfig) *LockApp {
+	retryClient := newRemoteStorage(config.Dependencies, []int{http.StatusOK}, config.MaxRetry, config.Anonymous)
+	storage := newRunStorage(retryClient, retryClient)
+	installer := core.NewPackageInstaller(storage, shell.NewDiskFileSystem(""), nil, false)
+	return &LockApp{config: config, installer: installer, catalog: core.NewPackageCatalog(storage)}
+}
+
+func (this *LockApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// TryRun locks the resolved dependency graph: the packages required by the listed packages are
+// locked as well. The listed packages that are already locked are resolved as locked, so that the
+// packages they require are those that would be installed.
+func (this *LockApp) TryRun() error {
+	previous, _, err := readLockFile(this.config.LockPath)
+	if err != nil {
+		return err
+	}
+	var kept contracts.LockFile
+	for _, entry := range previous.Packages {
+		if !this.refreshes(contracts.Dependency{PackageName: entry.PackageName}) {
+			kept.Packages = append(kept.Packages, entry)
+		}
+	}
+	listing := make([]contracts.Dependency, 0, len(this.config.Dependencies.Listing))
+	for _, dependency := range this.config.Dependencies.Listing {
+		if entry, found := kept.Find(dependency); found {
+			dependency.PackageVersion, dependency.ManifestDigest = entry.PackageVersion, entry.ManifestDigest
+		}
+		listing = append(listing, dependency)
+	}
+	manifests := pinnedManifests{ManifestLoader: newManifestLoader(this.installer, this.catalog, false), lock: kept}
+	graph, err := resolveDependencyGraph(manifests, listing, nil)
+	if err != nil {
+		return err
+	}
+
+	var lock contracts.LockFile
+	for i, required := range graph {
+		dependency := required.Dependency
+		if required.Listed {
+			dependency = this.config.Dependencies.Listing[i] // as requested, rather than as locked
+		}
+		entry, found := kept.Find(dependency)
+		if !found {
+			entry, err = this.resolve(dependency)
+			if err != nil {
+				return err
+			}
+			log.Printf("Locked [%s @ %s] to version %s (%s)", dependency.PackageName, dependency.PackageVersion, entry.PackageVersion, entry.ManifestDigest)
+		}
+		lock.Put(entry)
+	}
+	return writeLockFile(this.config.LockPath, lock)
+}
+
+// refreshes reports whether -update applies to the dependency (all of them when no packages are named).
+func (this *LockApp) refreshes(dependency contracts.Dependency) bool {
+	return this.config.Update && len(core.Filter([]contracts.Dependency{dependency}, this.config.Packages)) > 0
+}
+
+func (this *LockApp) resolve(dependency contracts.Dependency) (entry contracts.LockEntry, err error) {
+	entry = contracts.LockEntry{
+		PackageName:      dependency.PackageName,
+		RemoteAddress:    dependency.RemoteAddress.Value().String(),
+		RequestedVersion: dependency.PackageVersion,
+	}
+	if dependency.PackageVersion == "latest" {
+		latest, err := this.installer.DownloadManifest(dependency.ComposeLatestManifestRemoteAddress())
+		if err != nil {
+			return entry, fmt.Errorf("could not download the latest manifest for %s: %w", dependency.Title(), err)
+		}
+		dependency.PackageVersion = latest.Version
+	} else if dependency.HasVersionConstraint() {
+		dependency.PackageVersion, err = this.catalog.ResolveConstraint(dependency)
+		if err != nil {
+			return entry, err
+		}
+	}
+	manifest, err := this.installer.DownloadManifest(dependency.ComposeRemoteAddress(contracts.RemoteManifestFilename))
+	if err != nil {
+		return entry, fmt.Errorf("could not download the manifest for %s: %w", dependency.Title(), err)
+	}
+	entry.PackageVersion = dependency.PackageVersion
+	entry.ManifestDigest = manifest.Digest
+	return entry, nil
+}
+
+// lockPins applies the lock file to the dependencies of a run: those listed and those they require.
+type lockPins struct {
+	path string
+	lock contracts.LockFile
+	used map[int]struct{}
+}
+
+// loadLockPins reads the lock file, if any (without one, nothing is pinned).
+func loadLockPins(lockPath string) (*lockPins, error) {
+	lock, found, err := readLockFile(lockPath)
+	if err != nil || !found {
+		return nil, err
+	}
+	log.Printf("Applying the versions pinned by %q", lockPath)
+	return &lockPins{path: lockPath, lock: lock, used: make(map[int]struct{})}, nil
+}
+
+// pin replaces the version of the dependency with the one recorded in the lock file
+// and requires the remote manifest to match the recorded digest.
+func (this *lockPins) pin(dependency contracts.Dependency) (contracts.Dependency, error) {
+	if this == nil {
+		return dependency, nil
+	}
+	pinned, err := this.lock.Pin(dependency)
+	if err != nil {
+		return dependency, fmt.Errorf("%w in %q", err, this.path)
+	}
+	for i, entry := range this.lock.Packages {
+		if entry.PackageName != dependency.PackageName || entry.RequestedVersion != dependency.PackageVersion {
+			continue
+		}
+		this.used[i] = struct{}{}
+		if address := dependency.RemoteAddress.Value().String(); entry.RemoteAddress != address {
+			log.Printf("[WARN] %s was locked from %s but is installed from %s (the manifest digest must still match)", dependency.Title(), entry.RemoteAddress, address)
+		}
+	}
+	if pinned.PackageVersion != dependency.PackageVersion {
+		log.Printf("Pinned %s to version %s", dependency.Title(), pinned.PackageVersion)
+	}
+	return pinned, nil
+}
+
+// pinAll pins each of the dependencies (see pin).
+func (this *lockPins) pinAll(dependencies []contracts.Dependency) (err error) {
+	for i, dependency := range dependencies {
+		dependencies[i], err = this.pin(dependency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportUnused warns about the entries of the lock file that none of the dependencies matched.
+func (this *lockPins) reportUnused() {
+	if this == nil {
+		return
+	}
+	for i, entry := range this.lock.Packages {
+		if _, found := this.used[i]; !found {
+			log.Printf("[WARN] [%s @ %s] in %q matches none of the dependencies (run 'satisfy lock' to refresh the lock file)", entry.PackageName, entry.RequestedVersion, this.path)
+		}
+	}
+}
+
+// resolveLockPath returns the lock file set with -lock or else the one next to the listing (none
+// when the listing is read from stdin): a lock file that happens to be in the working directory
+// must not pin the versions of a listing kept elsewhere.
+func resolveLockPath(flags *flag.FlagSet, lockPath, jsonPath string) string {
+	explicit := false
+	flags.Visit(func(set *flag.Flag) { explicit = explicit || set.Name == "lock" })
+	if explicit {
+		return lockPath
+	}
+	if jsonPath == "_STDIN_" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(jsonPath), DefaultLockPath)
+}
+
+func readLockFile(path string) (lock contracts.LockFile, found bool, err error) {
+	if path == "" {
+		return lock, false, nil
+	}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, false, nil
+	}
+	if err != nil {
+		return lock, false, fmt.Errorf("could not read lock file (%q): %w", path, err)
+	}
+	err = json.Unmarshal(raw, &lock)
+	if err != nil {
+		return lock, false, fmt.Errorf("malformed lock file (%q): %w", path, err)
+	}
+	return lock, true, nil
+}
+
+func writeLockFile(path string, lock contracts.LockFile) error {
+	raw, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0644)
+}
+
+const DefaultLockPath = "satisfy.lock"
@@ -0,0 +1,130 @@
+package transfer
+
+import (
+	"errors"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestLockPathFixture(t *testing.T) {
+	gunit.Run(new(LockPathFixture), t)
+}
+
+type LockPathFixture struct {
+	*gunit.Fixture
+
+	flags    *flag.FlagSet
+	lockPath string
+}
+
+func (this *LockPathFixture) Setup() {
+	this.flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	this.flags.StringVar(&this.lockPath, "lock", DefaultLockPath, "")
+}
+
+func (this *LockPathFixture) resolve(jsonPath string, args ...string) string {
+	this.So(this.flags.Parse(args), should.BeNil)
+	return resolveLockPath(this.flags, this.lockPath, jsonPath)
+}
+
+func (this *LockPathFixture) TestLockFileIsNextToTheListing() {
+	this.So(this.resolve("config/satisfy.json"), should.Equal, filepath.Join("config", DefaultLockPath))
+}
+
+func (this *LockPathFixture) TestListingFromStdinHasNoLockFileByDefault() {
+	this.So(this.resolve("_STDIN_"), should.BeEmpty)
+}
+
+func (this *LockPathFixture) TestExplicitLockFileIsKept() {
+	this.So(this.resolve("config/satisfy.json", "-lock", "other.lock"), should.Equal, "other.lock")
+	this.So(this.resolve("config/satisfy.json", "-lock", ""), should.BeEmpty)
+}
+
+//////////////////////////////////////////////////////////
+
+func TestLockPinsFixture(t *testing.T) {
+	gunit.Run(new(LockPinsFixture), t)
+}
+
+type LockPinsFixture struct {
+	*gunit.Fixture
+
+	manifests fakeManifestLoader
+	pins      *lockPins
+	listing   []contracts.Dependency
+}
+
+func (this *LockPinsFixture) Setup() {
+	this.manifests = fakeManifestLoader{
+		"app@1.0.0": {Name: "app", Version: "1.0.0", Dependencies: []contracts.ManifestDependency{
+			{PackageName: "lib", PackageVersion: "^1.0", LocalDirectory: "lib"},
+		}},
+		"lib@1.1.0": {Name: "lib", Version: "1.1.0"},
+		"lib@1.2.0": {Name: "lib", Version: "1.2.0"},
+	}
+	this.pins = &lockPins{path: DefaultLockPath, used: make(map[int]struct{}), lock: contracts.LockFile{
+		Packages: []contracts.LockEntry{
+			{PackageName: "app", RemoteAddress: "gcs://bucket/packages", RequestedVersion: "1.0.0", PackageVersion: "1.0.0", ManifestDigest: "sha256:app"},
+			{PackageName: "lib", RemoteAddress: "gcs://bucket/packages", RequestedVersion: "^1.0", PackageVersion: "1.1.0", ManifestDigest: "sha256:lib"},
+		},
+	}}
+	this.listing = []contracts.Dependency{{
+		PackageName:    "app",
+		PackageVersion: "1.0.0",
+		RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/packages"},
+		LocalDirectory: "/deps/app",
+	}}
+}
+
+func (this *LockPinsFixture) TestRequiredPackagesArePinned() {
+	this.So(this.pins.pinAll(this.listing), should.BeNil)
+
+	graph, err := resolveDependencyGraph(this.manifests, this.listing, this.pins)
+
+	this.So(err, should.BeNil)
+	this.So(graph, should.HaveLength, 2)
+	this.So(graph[1].PackageVersion, should.Equal, "1.1.0")
+	this.So(graph[1].ManifestDigest, should.Equal, "sha256:lib")
+	this.So(this.pins.used, should.HaveLength, 2)
+}
+
+func (this *LockPinsFixture) TestRequiredPackageMissingFromTheLockFileIsAnError() {
+	this.pins.lock.Packages = this.pins.lock.Packages[:1]
+	this.So(this.pins.pinAll(this.listing), should.BeNil)
+
+	graph, err := resolveDependencyGraph(this.manifests, this.listing, this.pins)
+
+	this.So(graph, should.BeNil)
+	this.So(err.Error(), should.ContainSubstring, "is not in the lock file")
+}
+
+func (this *LockPinsFixture) TestEntriesMatchingNoDependencyAreNotUsed() {
+	this.pins.lock.Packages = append(this.pins.lock.Packages, contracts.LockEntry{PackageName: "gone", RequestedVersion: "1.0.0"})
+	this.So(this.pins.pinAll(this.listing), should.BeNil)
+
+	_, err := resolveDependencyGraph(this.manifests, this.listing, this.pins)
+
+	this.So(err, should.BeNil)
+	this.So(this.pins.used, should.HaveLength, 2)
+	this.So(this.pins.used, should.NotContainKey, 2)
+}
+
+// fakeManifestLoader resolves "^1.0" to the newest 1.x version it holds.
+type fakeManifestLoader map[string]contracts.Manifest
+
+func (this fakeManifestLoader) LoadManifest(dependency contracts.Dependency) (contracts.Manifest, error) {
+	version := dependency.PackageVersion
+	if version == "^1.0" {
+		version = "1.2.0"
+	}
+	manifest, found := this[dependency.PackageName+"@"+version]
+	if !found {
+		return contracts.Manifest{}, errors.New("manifest not found")
+	}
+	return manifest, nil
+}
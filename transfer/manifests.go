@@ -0,0 +1,128 @@
+package transfer
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+// manifestLoader loads the manifest of the version of a dependency that would be installed.
+// Built on a runStorage, it downloads each remote manifest at most once per run.
+type manifestLoader struct {
+	installer *core.PackageInstaller
+	catalog   *core.PackageCatalog
+	offline   bool
+}
+
+func newManifestLoader(installer *core.PackageInstaller, catalog *core.PackageCatalog, offline bool) *manifestLoader {
+	return &manifestLoader{installer: installer, catalog: catalog, offline: offline}
+}
+
+// LoadManifest prefers the installed manifest of the (literal) version over downloading it.
+func (this *manifestLoader) LoadManifest(dependency contracts.Dependency) (contracts.Manifest, error) {
+	if !dependency.HasVersionConstraint() && dependency.PackageVersion != "latest" {
+		if manifest, err := readLocalManifest(dependency); err == nil && manifest.Version == dependency.PackageVersion {
+			return manifest, nil
+		}
+	}
+	return this.DownloadManifest(dependency)
+}
+
+// DownloadManifest downloads the remote manifest (which, unlike an installed one, lists all of the archive contents).
+func (this *manifestLoader) DownloadManifest(dependency contracts.Dependency) (contracts.Manifest, error) {
+	version, err := this.resolveVersion(dependency)
+	if err != nil {
+		return contracts.Manifest{}, err
+	}
+	dependency.PackageVersion = version
+	return this.installer.DownloadManifest(dependency.ComposeRemoteManifestAddress())
+}
+
+func (this *manifestLoader) resolveVersion(dependency contracts.Dependency) (string, error) {
+	switch {
+	case dependency.HasVersionConstraint():
+		return this.catalog.ResolveConstraint(dependency)
+	case this.offline && dependency.PackageVersion == "latest":
+		return this.catalog.ResolveLatest(dependency)
+	default:
+		return dependency.PackageVersion, nil
+	}
+}
+
+// runStorage remembers, for the duration of a run, every manifest downloaded and every listing of
+// published versions (failures included), so that the steps preceding an installation (sizes,
+// dependency graph, collisions) and the installation itself share a single request (and its retries).
+// Archives are downloaded as requested.
+type runStorage struct {
+	downloader contracts.Downloader
+	lister     contracts.Lister
+
+	mutex     sync.Mutex
+	manifests map[string]*remembered[[]byte]
+	listings  map[string]*remembered[[]contracts.RemoteObject]
+}
+
+func newRunStorage(downloader contracts.Downloader, lister contracts.Lister) *runStorage {
+	return &runStorage{
+		downloader: downloader,
+		lister:     lister,
+		manifests:  make(map[string]*remembered[[]byte]),
+		listings:   make(map[string]*remembered[[]contracts.RemoteObject]),
+	}
+}
+
+func (this *runStorage) Download(address url.URL) (io.ReadCloser, error) {
+	if path.Base(address.Path) != contracts.RemoteManifestFilename {
+		return this.downloader.Download(address)
+	}
+	raw, err := remember(&this.mutex, this.manifests, address.String(), func() ([]byte, error) {
+		body, err := this.downloader.Download(address)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = body.Close() }()
+		return io.ReadAll(body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(raw)), nil
+}
+
+func (this *runStorage) Seek(address url.URL, start, end int64) (io.ReadCloser, error) {
+	return this.downloader.Seek(address, start, end)
+}
+
+func (this *runStorage) Size(address url.URL) (int64, error) {
+	return this.downloader.Size(address)
+}
+
+func (this *runStorage) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	return remember(&this.mutex, this.listings, prefix.String(), func() ([]contracts.RemoteObject, error) {
+		return this.lister.List(prefix)
+	})
+}
+
+type remembered[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+// remember returns the outcome of the first call of load for the key; concurrent callers wait for it.
+func remember[T any](mutex *sync.Mutex, outcomes map[string]*remembered[T], key string, load func() (T, error)) (T, error) {
+	mutex.Lock()
+	outcome, found := outcomes[key]
+	if !found {
+		outcome = new(remembered[T])
+		outcomes[key] = outcome
+	}
+	mutex.Unlock()
+	outcome.once.Do(func() { outcome.value, outcome.err = load() })
+	return outcome.value, outcome.err
+}
@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+	"github.com/smarty/satisfy/contracts"
+)
+
+func TestRunStorageFixture(t *testing.T) {
+	gunit.Run(new(RunStorageFixture), t)
+}
+
+type RunStorageFixture struct {
+	*gunit.Fixture
+
+	remote  *countingStorage
+	storage *runStorage
+}
+
+func (this *RunStorageFixture) Setup() {
+	this.remote = &countingStorage{requests: make(map[string]int)}
+	this.storage = newRunStorage(this.remote, this.remote)
+}
+
+func (this *RunStorageFixture) download(address string) (string, error) {
+	parsed, _ := url.Parse(address)
+	body, err := this.storage.Download(*parsed)
+	if err != nil {
+		return "", err
+	}
+	raw, _ := io.ReadAll(body)
+	return string(raw), nil
+}
+
+func (this *RunStorageFixture) TestManifestIsDownloadedOncePerRun() {
+	first, _ := this.download("gs://bucket/package/1.0.0/manifest.json")
+	second, _ := this.download("gs://bucket/package/1.0.0/manifest.json")
+
+	this.So(first, should.Equal, "gs://bucket/package/1.0.0/manifest.json")
+	this.So(second, should.Equal, first)
+	this.So(this.remote.requests["gs://bucket/package/1.0.0/manifest.json"], should.Equal, 1)
+}
+
+func (this *RunStorageFixture) TestFailedManifestDownloadIsNotRetriedWithinTheRun() {
+	this.remote.failing = true
+
+	_, first := this.download("gs://bucket/package/1.0.0/manifest.json")
+	_, second := this.download("gs://bucket/package/1.0.0/manifest.json")
+
+	this.So(first, should.NotBeNil)
+	this.So(second, should.Equal, first)
+	this.So(this.remote.requests["gs://bucket/package/1.0.0/manifest.json"], should.Equal, 1)
+}
+
+func (this *RunStorageFixture) TestArchivesAreDownloadedAsRequested() {
+	_, _ = this.download("gs://bucket/package/1.0.0/archive.tar.gz")
+	_, _ = this.download("gs://bucket/package/1.0.0/archive.tar.gz")
+
+	this.So(this.remote.requests["gs://bucket/package/1.0.0/archive.tar.gz"], should.Equal, 2)
+}
+
+func (this *RunStorageFixture) TestVersionsAreListedOncePerRun() {
+	prefix, _ := url.Parse("gs://bucket/package/")
+
+	_, _ = this.storage.List(*prefix)
+	_, _ = this.storage.List(*prefix)
+
+	this.So(this.remote.requests["list gs://bucket/package/"], should.Equal, 1)
+}
+
+// countingStorage serves each address as its own contents and counts the requests.
+type countingStorage struct {
+	requests map[string]int
+	failing  bool
+}
+
+func (this *countingStorage) Download(address url.URL) (io.ReadCloser, error) {
+	this.requests[address.String()]++
+	if this.failing {
+		return nil, errors.New("unavailable")
+	}
+	return io.NopCloser(strings.NewReader(address.String())), nil
+}
+
+func (this *countingStorage) Seek(address url.URL, _, _ int64) (io.ReadCloser, error) {
+	return this.Download(address)
+}
+
+func (this *countingStorage) Size(url.URL) (int64, error) {
+	return 0, nil
+}
+
+func (this *countingStorage) List(prefix url.URL) ([]contracts.RemoteObject, error) {
+	this.requests["list "+prefix.String()]++
+	return nil, nil
+}
@@ -0,0 +1,207 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/shell"
+)
+
+func TestOfflineFixture(t *testing.T) {
+	gunit.Run(new(OfflineFixture), t)
+}
+
+type OfflineFixture struct {
+	*gunit.Fixture
+
+	root   string
+	bundle string
+	cache  string
+	local  string
+	config DownloadConfig
+}
+
+func (this *OfflineFixture) Setup() {
+	this.root, _ = os.MkdirTemp("", "satisfy-offline-")
+	this.bundle = filepath.Join(this.root, "bundle")
+	this.cache = filepath.Join(this.root, "cache")
+	this.local = filepath.Join(this.root, "local")
+	this.config = DownloadConfig{
+		QuickVerification: true,
+		Offline:           true,
+		BundleDirectory:   this.bundle,
+		CacheDirectory:    this.cache,
+		Dependencies: contracts.DependencyListing{Listing: []contracts.Dependency{{
+			PackageName:    "package",
+			PackageVersion: "latest",
+			RemoteAddress:  contracts.URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"},
+			LocalDirectory: this.local,
+		}}},
+	}
+}
+
+func (this *OfflineFixture) Teardown() {
+	_ = os.RemoveAll(this.root)
+}
+
+func (this *OfflineFixture) publish(version string) (manifest, archive []byte) {
+	content := "contents of " + version
+	buffer := new(bytes.Buffer)
+	compressor := gzip.NewWriter(buffer)
+	writer := tar.NewWriter(compressor)
+	_ = writer.WriteHeader(&tar.Header{Name: "file.txt", Size: int64(len(content)), Mode: 0644})
+	_, _ = writer.Write([]byte(content))
+	_ = writer.Close()
+	_ = compressor.Close()
+
+	archiveChecksum := md5.Sum(buffer.Bytes())
+	itemChecksum := md5.Sum([]byte(content))
+	manifest, _ = json.Marshal(contracts.Manifest{Name: "package", Version: version, Archive: contracts.Archive{
+		Filename:             "archive",
+		Size:                 uint64(buffer.Len()),
+		MD5Checksum:          archiveChecksum[:],
+		CompressionAlgorithm: "gzip",
+		Contents:             []contracts.ArchiveItem{{Path: "file.txt", Size: int64(len(content)), MD5Checksum: itemChecksum[:]}},
+	}})
+	return manifest, buffer.Bytes()
+}
+
+func (this *OfflineFixture) bundleVersion(version string) {
+	manifest, archive := this.publish(version)
+	directory := filepath.Join(this.bundle, "bucket", "prefix", "package", version)
+	this.So(os.MkdirAll(directory, 0755), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(directory, "manifest.json"), manifest, 0644), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(directory, "archive"), archive, 0644), should.BeNil)
+}
+
+func (this *OfflineFixture) cacheVersion(version string, includeArchive bool) {
+	manifest, archive := this.publish(version)
+	cache := shell.NewDiskArchiveCache(this.cache, 0)
+	address := url.URL{Scheme: "gcs", Host: "bucket", Path: "/prefix/package/" + version + "/manifest.json"}
+	this.So(cache.StoreManifest(address, manifest), should.BeNil)
+	if includeArchive {
+		checksum := md5.Sum(archive)
+		entry, err := cache.Store(hex.EncodeToString(checksum[:]))
+		this.So(err, should.BeNil)
+		_, _ = entry.Write(archive)
+		this.So(entry.Commit(), should.BeNil)
+	}
+}
+
+func (this *OfflineFixture) installed() string {
+	content, _ := os.ReadFile(filepath.Join(this.local, "file.txt"))
+	return string(content)
+}
+
+func (this *OfflineFixture) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(this.local, name))
+	return err == nil
+}
+
+func (this *OfflineFixture) TestLatestIsTheNewestVersionAvailableOffline() {
+	this.bundleVersion("1.2.0")
+	this.cacheVersion("1.3.0", true)
+	this.cacheVersion("1.4.0", false) // manifest only: not installable offline
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.BeNil)
+	this.So(this.installed(), should.Equal, "contents of 1.3.0")
+}
+
+func (this *OfflineFixture) TestSpecificVersionIsInstalledFromBundle() {
+	this.bundleVersion("1.2.0")
+	this.bundleVersion("1.3.0")
+	this.config.Dependencies.Listing[0].PackageVersion = "1.2.0"
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.BeNil)
+	this.So(this.installed(), should.Equal, "contents of 1.2.0")
+}
+
+func (this *OfflineFixture) TestPackagesAreInstalledOneAtATime() {
+	this.bundleVersion("1.2.0")
+	this.config.Parallel, this.config.PerHost = 1, 1
+	second := this.config.Dependencies.Listing[0]
+	second.LocalDirectory = filepath.Join(this.root, "other")
+	this.config.Dependencies.Listing = append(this.config.Dependencies.Listing, second)
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.BeNil)
+	this.So(this.installed(), should.Equal, "contents of 1.2.0")
+	content, _ := os.ReadFile(filepath.Join(this.root, "other", "file.txt"))
+	this.So(string(content), should.Equal, "contents of 1.2.0")
+}
+
+func (this *OfflineFixture) TestMissingVersionFailsClearly() {
+	this.bundleVersion("1.2.0")
+	this.config.Dependencies.Listing[0].PackageVersion = "2.0.0"
+
+	storage := shell.NewOfflineStorage(this.bundle, shell.NewDiskArchiveCache(this.cache, 0))
+	_, err := storage.Download(this.config.Dependencies.Listing[0].ComposeRemoteManifestAddress())
+
+	this.So(err, should.Wrap, contracts.ErrNotAvailableOffline)
+	this.So(err.Error(), should.ContainSubstring, "gcs://bucket/prefix/package/2.0.0/manifest.json")
+	this.So(NewDownloadApp(this.config).TryRun(), should.NotBeNil)
+	this.So(this.installed(), should.BeEmpty)
+}
+
+func (this *OfflineFixture) TestBundleRequiresOffline() {
+	_, err := ParseDownloadConfig([]string{"-bundle", this.bundle})
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "-offline")
+}
+
+func (this *OfflineFixture) TestCleanWithoutConfirmationOnlyListsStrays() {
+	this.bundleVersion("1.2.0")
+	this.So(os.MkdirAll(this.local, 0755), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(this.local, "leftover"), nil, 0644), should.BeNil)
+	this.config.Clean = true
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.BeNil)
+	this.So(this.exists("leftover"), should.BeTrue)
+}
+
+func (this *OfflineFixture) TestConfirmedCleanRemovesStrays() {
+	this.bundleVersion("1.2.0")
+	this.So(os.MkdirAll(this.local, 0755), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(this.local, "leftover"), nil, 0644), should.BeNil)
+	this.config.Clean, this.config.CleanConfirmed = true, true
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.BeNil)
+	this.So(this.exists("leftover"), should.BeFalse)
+	this.So(this.installed(), should.Equal, "contents of 1.2.0")
+}
+
+func (this *OfflineFixture) TestCleanRefusesDirectoryHoldingTheListing() {
+	this.bundleVersion("1.2.0")
+	this.So(os.MkdirAll(this.local, 0755), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(this.local, "leftover"), nil, 0644), should.BeNil)
+	this.config.Clean, this.config.CleanConfirmed = true, true
+	this.config.jsonPath = filepath.Join(this.local, "satisfy.json")
+
+	err := NewDownloadApp(this.config).TryRun()
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "refusing to remove stray files")
+	this.So(this.exists("leftover"), should.BeTrue)
+}
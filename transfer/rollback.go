@@ -0,0 +1,91 @@
+package transfer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type RollbackConfig struct {
+	Dependencies contracts.DependencyListing
+	LockTimeout  time.Duration
+	jsonPath     string
+	profile      string
+}
+
+func ParseRollbackConfig(args []string) (config RollbackConfig, err error) {
+	flags := flag.NewFlagSet("satisfy rollback", flag.ContinueOnError)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	addLockTimeoutFlag(flags, &config.LockTimeout)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s rollback [-json <listing>] [-profile <name>] [-lock-timeout <duration>] <package> [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Re-activates the previously installed version of each named package that uses the versioned layout.")
+		_, _ = fmt.Fprintln(output, "  Nothing is downloaded. Installations keep the version rolled back to until the listing requests")
+		_, _ = fmt.Fprintln(output, "  another version of the package.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return RollbackConfig{}, err
+	}
+	if flags.NArg() == 0 {
+		return RollbackConfig{}, errors.New("at least one package name is required")
+	}
+
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
+	if err != nil {
+		return RollbackConfig{}, err
+	}
+	if len(config.Dependencies.Listing) == 0 {
+		return RollbackConfig{}, errors.New("none of the named packages are in the dependency listing")
+	}
+	return config, nil
+}
+
+type RollbackApp struct {
+	config RollbackConfig
+}
+
+func NewRollbackApp(config RollbackConfig) *RollbackApp {
+	return &RollbackApp{config: config}
+}
+
+func (this *RollbackApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *RollbackApp) TryRun() error {
+	disk := shell.NewDiskFileSystem("")
+	locker := shell.NewDirectoryLocker(this.config.LockTimeout)
+	for _, dependency := range this.config.Dependencies.Listing {
+		if !dependency.IsVersioned() {
+			return fmt.Errorf("%s does not use the %q layout and cannot be rolled back", dependency.Title(), contracts.VersionedLayout)
+		}
+		version, err := rollback(locker, disk, dependency)
+		if err != nil {
+			return fmt.Errorf("could not roll back %s: %w", dependency.Title(), err)
+		}
+		log.Printf("Rolled back [%s] in %q to version %s", dependency.PackageName, dependency.LocalDirectory, version)
+	}
+	return nil
+}
+
+func rollback(locker *shell.DirectoryLocker, disk *shell.DiskFileSystem, dependency contracts.Dependency) (string, error) {
+	release, err := locker.Lock(dependency.LocalDirectory)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return core.NewVersionedLayout(disk, dependency.LocalDirectory, dependency.PackageName).Rollback(dependency.PackageVersion)
+}
@@ -0,0 +1,233 @@
+package transfer
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type StatusConfig struct {
+	MaxRetry          int
+	QuickVerification bool
+	Format            string
+	LockPath          string
+	Anonymous         bool
+	Dependencies      contracts.DependencyListing
+	jsonPath          string
+	profile           string
+	pins              *lockPins
+}
+
+func ParseStatusConfig(args []string) (config StatusConfig, err error) {
+	flags := flag.NewFlagSet("satisfy status", flag.ContinueOnError)
+	flags.IntVar(&config.MaxRetry,
+		"max-retry",
+		5,
+		"How many times to retry attempts to download manifests.",
+	)
+	flags.BoolVar(&config.QuickVerification,
+		"quick",
+		true,
+		"When set to false, perform full file content validation on installed packages.",
+	)
+	flags.StringVar(&config.Format,
+		"format",
+		formatTable,
+		"Output format: 'table' or 'json'.",
+	)
+	flags.StringVar(&config.LockPath,
+		"lock",
+		DefaultLockPath,
+		"Path to the lock file (see 'lock') whose pinned versions are expected, if it exists (defaults to "+DefaultLockPath+" next to the -json listing). Set to empty to ignore it.",
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access public buckets with unsigned requests instead of the default Google credentials (dependencies with credentials of their own still use them).",
+	)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s status [-json <listing>] [-profile <name>] [-format table|json] [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Reports whether each dependency is installed, missing, of the wrong version, outdated or failing")
+		_, _ = fmt.Fprintln(output, "  its integrity check, without changing anything. The exit code is 0 when all dependencies are")
+		_, _ = fmt.Fprintf(output, "  installed, %d when action is needed and %d when the status could not be determined.\n", StatusExitActionNeeded, StatusExitUnknown)
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return StatusConfig{}, err
+	}
+	if config.Format != formatTable && config.Format != formatJSON {
+		return StatusConfig{}, errors.New("-format must be either 'table' or 'json'")
+	}
+
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
+	if err != nil {
+		return StatusConfig{}, err
+	}
+	config.LockPath = resolveLockPath(flags, config.LockPath, config.jsonPath)
+	config.pins, err = loadLockPins(config.LockPath)
+	if err == nil {
+		err = config.pins.pinAll(config.Dependencies.Listing)
+	}
+	if err != nil {
+		return StatusConfig{}, err
+	}
+
+	return config, nil
+}
+
+type StatusApp struct {
+	config StatusConfig
+	output io.Writer
+}
+
+func NewStatusApp(config StatusConfig) *StatusApp {
+	return &StatusApp{config: config, output: os.Stdout}
+}
+
+// TryRun prints the status of each dependency and returns the exit code: StatusExitActionNeeded or
+// StatusExitUnknown unless all dependencies are installed correctly.
+func (this *StatusApp) TryRun() int {
+	statuses := this.Inspect()
+	if err := this.print(statuses); err != nil {
+		log.Println(err)
+		return StatusExitUnknown
+	}
+	return statusExitCode(statuses)
+}
+
+func (this *StatusApp) Inspect() (statuses []core.DependencyStatus) {
+	disk := shell.NewDiskFileSystem("")
+	retryClient := newRemoteStorage(this.config.Dependencies, []int{http.StatusOK}, this.config.MaxRetry, this.config.Anonymous)
+	storage := newRunStorage(retryClient, retryClient)
+	installer := core.NewPackageInstaller(storage, disk, nil, false)
+	catalog := core.NewPackageCatalog(storage)
+	integrity := core.NewCompoundIntegrityCheck(
+		core.NewFileListingIntegrityChecker(disk),
+		core.NewFileContentIntegrityCheck(md5.New, disk, !this.config.QuickVerification),
+	)
+	manifests := newManifestLoader(installer, catalog, false)
+	graph, err := resolveDependencyGraph(manifests, this.config.Dependencies.Listing, this.config.pins)
+	if err != nil {
+		log.Println("[WARN]", err)
+		graph = nil
+		for _, dependency := range this.config.Dependencies.Listing {
+			graph = append(graph, core.RequiredDependency{Dependency: dependency, Listed: true})
+		}
+	}
+	var listing []contracts.Dependency
+	for _, dependency := range graph {
+		listing = append(listing, dependency.Dependency)
+	}
+	// Unresolved collisions are left for installation to report; those packages are inspected as listed.
+	listing, _ = collisionResolver{manifests: manifests}.Resolve(listing)
+	for i, dependency := range listing {
+		resolver := core.NewDependencyResolver(disk, integrity, installer, catalog, nil, nil, dependency)
+		status := resolver.Inspect()
+		status.RequiredBy = graph[i].RequiredBy
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (this *StatusApp) print(statuses []core.DependencyStatus) error {
+	if this.config.Format == formatJSON {
+		report := make([]dependencyStatusJSON, 0, len(statuses))
+		for _, status := range statuses {
+			report = append(report, dependencyStatusJSON{
+				PackageName:      status.Dependency.PackageName,
+				PackageVersion:   status.Dependency.PackageVersion,
+				LocalDirectory:   status.Dependency.LocalDirectory,
+				Status:           string(status.Status),
+				InstalledVersion: status.InstalledVersion,
+				ExpectedVersion:  status.ExpectedVersion,
+				Detail:           status.Detail,
+				RequiredBy:       status.RequiredBy,
+			})
+		}
+		encoder := json.NewEncoder(this.output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	writer := tabwriter.NewWriter(this.output, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "PACKAGE\tREQUESTED\tINSTALLED\tEXPECTED\tDIRECTORY\tSTATUS\tDETAIL")
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			status.Dependency.PackageName,
+			status.Dependency.PackageVersion,
+			orDash(status.InstalledVersion),
+			orDash(status.ExpectedVersion),
+			status.Dependency.LocalDirectory,
+			status.Status,
+			describeStatusDetail(status),
+		)
+	}
+	return writer.Flush()
+}
+
+func statusExitCode(statuses []core.DependencyStatus) (code int) {
+	for _, status := range statuses {
+		if status.Status == core.StatusUnknown {
+			return StatusExitUnknown
+		}
+		if status.NeedsAction() {
+			code = StatusExitActionNeeded
+		}
+	}
+	return code
+}
+
+func describeStatusDetail(status core.DependencyStatus) string {
+	if len(status.RequiredBy) == 0 {
+		return status.Detail
+	}
+	requiredBy := "required by " + strings.Join(status.RequiredBy, ", ")
+	if status.Detail == "" {
+		return requiredBy
+	}
+	return status.Detail + " (" + requiredBy + ")"
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+type dependencyStatusJSON struct {
+	PackageName      string   `json:"package_name"`
+	PackageVersion   string   `json:"package_version"`
+	LocalDirectory   string   `json:"local_directory"`
+	Status           string   `json:"status"`
+	InstalledVersion string   `json:"installed_version,omitempty"`
+	ExpectedVersion  string   `json:"expected_version,omitempty"`
+	Detail           string   `json:"detail,omitempty"`
+	RequiredBy       []string `json:"required_by,omitempty"`
+}
+
+const (
+	StatusExitActionNeeded = 1
+	StatusExitUnknown      = 2
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
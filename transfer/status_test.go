@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+func TestStatusFixture(t *testing.T) {
+	gunit.Run(new(StatusFixture), t)
+}
+
+type StatusFixture struct {
+	*gunit.Fixture
+
+	output   *bytes.Buffer
+	statuses []core.DependencyStatus
+}
+
+func (this *StatusFixture) Setup() {
+	this.output = new(bytes.Buffer)
+	this.statuses = []core.DependencyStatus{
+		{
+			Dependency:       contracts.Dependency{PackageName: "a", PackageVersion: "1.0.0", LocalDirectory: "local/a"},
+			Status:           core.StatusInstalled,
+			InstalledVersion: "1.0.0",
+			ExpectedVersion:  "1.0.0",
+		},
+		{
+			Dependency: contracts.Dependency{PackageName: "b", PackageVersion: "latest", LocalDirectory: "local/b"},
+			Status:     core.StatusMissing,
+			Detail:     "no local manifest found",
+		},
+	}
+}
+
+func (this *StatusFixture) TestExitCodes() {
+	this.So(statusExitCode(this.statuses[:1]), should.Equal, 0)
+	this.So(statusExitCode(this.statuses), should.Equal, StatusExitActionNeeded)
+	this.So(statusExitCode(append(this.statuses, core.DependencyStatus{Status: core.StatusUnknown})), should.Equal, StatusExitUnknown)
+}
+
+func (this *StatusFixture) TestTable() {
+	app := &StatusApp{config: StatusConfig{Format: formatTable}, output: this.output}
+
+	this.So(app.print(this.statuses), should.BeNil)
+
+	this.So(this.output.String(), should.Equal, ""+
+		"PACKAGE  REQUESTED  INSTALLED  EXPECTED  DIRECTORY  STATUS     DETAIL\n"+
+		"a        1.0.0      1.0.0      1.0.0     local/a    installed  \n"+
+		"b        latest     -          -         local/b    missing    no local manifest found\n")
+}
+
+func (this *StatusFixture) TestJSON() {
+	app := &StatusApp{config: StatusConfig{Format: formatJSON}, output: this.output}
+
+	this.So(app.print(this.statuses[1:]), should.BeNil)
+
+	this.So(this.output.String(), should.Equal, `[
+  {
+    "package_name": "b",
+    "package_version": "latest",
+    "local_directory": "local/b",
+    "status": "missing",
+    "detail": "no local manifest found"
+  }
+]
+`)
+}
+
+func (this *StatusFixture) TestRequiredDependenciesNameTheirDependents() {
+	this.statuses[0].RequiredBy = []string{"[b @ 2.0.0]"}
+	this.statuses[1].RequiredBy = []string{"[c @ 1.0.0]", "[d @ 1.1.0]"}
+	app := &StatusApp{config: StatusConfig{Format: formatTable}, output: this.output}
+
+	this.So(app.print(this.statuses), should.BeNil)
+
+	this.So(this.output.String(), should.Equal, ""+
+		"PACKAGE  REQUESTED  INSTALLED  EXPECTED  DIRECTORY  STATUS     DETAIL\n"+
+		"a        1.0.0      1.0.0      1.0.0     local/a    installed  required by [b @ 2.0.0]\n"+
+		"b        latest     -          -         local/b    missing    no local manifest found (required by [c @ 1.0.0], [d @ 1.1.0])\n")
+}
+
+func (this *StatusFixture) TestUnrecognizedFormatIsRejected() {
+	_, err := ParseStatusConfig([]string{"-format", "xml"})
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *StatusFixture) TestTryRunReturnsTheExitCodeInsteadOfExiting() {
+	app := &StatusApp{config: StatusConfig{Format: formatJSON, Anonymous: true}, output: this.output}
+
+	this.So(app.TryRun(), should.Equal, 0)
+	this.So(this.output.String(), should.Equal, "[]\n")
+}
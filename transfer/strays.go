@@ -0,0 +1,86 @@
+package transfer
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type StraysConfig struct {
+	Dependencies contracts.DependencyListing
+	jsonPath     string
+	profile      string
+}
+
+func ParseStraysConfig(args []string) (config StraysConfig, err error) {
+	flags := flag.NewFlagSet("satisfy strays", flag.ContinueOnError)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s strays [-json <listing>] [-profile <name>] [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Prints (to stdout) each file under the local directories of the listing that is not owned")
+		_, _ = fmt.Fprintln(output, "  by any package installed there. Nothing is removed; install with -clean -clean-confirm to remove them.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return StraysConfig{}, err
+	}
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
+	if err != nil {
+		return StraysConfig{}, err
+	}
+	return config, nil
+}
+
+type StraysApp struct {
+	config StraysConfig
+}
+
+func NewStraysApp(config StraysConfig) *StraysApp {
+	return &StraysApp{config: config}
+}
+
+func (this *StraysApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *StraysApp) TryRun() error {
+	found := 0
+	for _, directory := range localDirectories(this.config.Dependencies) {
+		strays, err := core.NewStrayFinder(shell.NewDiskFileSystem(directory)).Find(directory)
+		if err != nil {
+			return fmt.Errorf("could not inspect %q: %w", directory, err)
+		}
+		for _, path := range strays {
+			fmt.Println(path)
+		}
+		found += len(strays)
+	}
+	log.Printf("Found %d stray file(s).", found)
+	return nil
+}
+
+// localDirectories returns each distinct (and existing) local directory of the listing, in listing order.
+func localDirectories(listing contracts.DependencyListing) (directories []string) {
+	seen := make(map[string]struct{})
+	for _, dependency := range listing.Listing {
+		directory := dependency.LocalDirectory
+		if _, found := seen[directory]; found {
+			continue
+		}
+		seen[directory] = struct{}{}
+		if info, err := os.Stat(directory); err == nil && info.IsDir() {
+			directories = append(directories, directory)
+		}
+	}
+	return directories
+}
@@ -0,0 +1,91 @@
+package transfer
+
+import (
+	"crypto/md5"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type UninstallConfig struct {
+	Dependencies contracts.DependencyListing
+	Force        bool
+	LockTimeout  time.Duration
+	jsonPath     string
+	profile      string
+}
+
+func ParseUninstallConfig(args []string) (config UninstallConfig, err error) {
+	flags := flag.NewFlagSet("satisfy uninstall", flag.ContinueOnError)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	flags.BoolVar(&config.Force,
+		"force",
+		false,
+		"When set, files that were modified since installation are removed as well (instead of aborting).",
+	)
+	addLockTimeoutFlag(flags, &config.LockTimeout)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s uninstall [-json <listing>] [-profile <name>] [-force] [-lock-timeout <duration>] [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Removes the files of each named package (or of every package in the listing when none are named)")
+		_, _ = fmt.Fprintln(output, "  according to its local manifest, prunes directories left empty and deletes the local manifest.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return UninstallConfig{}, err
+	}
+
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
+	if err != nil {
+		return UninstallConfig{}, err
+	}
+	if flags.NArg() > 0 && len(config.Dependencies.Listing) == 0 {
+		return UninstallConfig{}, errors.New("none of the named packages are in the dependency listing")
+	}
+	return config, nil
+}
+
+type UninstallApp struct {
+	config UninstallConfig
+}
+
+func NewUninstallApp(config UninstallConfig) *UninstallApp {
+	return &UninstallApp{config: config}
+}
+
+func (this *UninstallApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *UninstallApp) TryRun() error {
+	disk := shell.NewDiskFileSystem("")
+	uninstaller := core.NewPackageUninstaller(disk, core.NewFileContentIntegrityCheck(md5.New, disk, true), shell.NewCommandHookRunner(), this.config.Force)
+	locker := shell.NewDirectoryLocker(this.config.LockTimeout)
+	for _, dependency := range this.config.Dependencies.Listing {
+		err := uninstall(locker, uninstaller, dependency)
+		if err != nil {
+			return fmt.Errorf("could not uninstall %s: %w", dependency.Title(), err)
+		}
+	}
+	return nil
+}
+
+func uninstall(locker *shell.DirectoryLocker, uninstaller *core.PackageUninstaller, dependency contracts.Dependency) error {
+	release, err := locker.Lock(dependency.LocalDirectory)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return uninstaller.Uninstall(dependency)
+}
@@ -189,6 +189,7 @@ func (this *UploadApp) completeManifest() {
 			Contents:             this.builder.Contents(),
 			CompressionAlgorithm: this.packageConfig.CompressionAlgorithm,
 		},
+		Dependencies: this.packageConfig.Dependencies,
 	}
 }
 
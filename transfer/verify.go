@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+	"github.com/smarty/satisfy/shell"
+)
+
+type VerifyConfig struct {
+	Format       string
+	Dependencies contracts.DependencyListing
+	jsonPath     string
+	profile      string
+}
+
+func ParseVerifyConfig(args []string) (config VerifyConfig, err error) {
+	flags := flag.NewFlagSet("satisfy verify", flag.ContinueOnError)
+	flags.StringVar(&config.Format,
+		"format",
+		formatTable,
+		"Output format: 'table' or 'json'.",
+	)
+	addListingFlags(flags, &config.jsonPath, &config.profile)
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s verify [-json <listing>] [-profile <name>] [-format table|json] [<package>...]\n\n", os.Args[0])
+		_, _ = fmt.Fprintln(output, "  Checks the size and checksum of every file in every installed manifest and reports all missing,")
+		_, _ = fmt.Fprintln(output, "  mismatched and extra files. The exit code is non-zero when any problem is found.")
+		_, _ = fmt.Fprintln(output)
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return VerifyConfig{}, err
+	}
+	if config.Format != formatTable && config.Format != formatJSON {
+		return VerifyConfig{}, errors.New("-format must be either 'table' or 'json'")
+	}
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, config.profile, flags.Args())
+	if err != nil {
+		return VerifyConfig{}, err
+	}
+	return config, nil
+}
+
+type VerificationReport struct {
+	Packages   []core.PackageVerification `json:"packages"`
+	ExtraFiles []core.VerificationProblem `json:"extra_files"`
+}
+
+func (this VerificationReport) Passed() bool {
+	for _, result := range this.Packages {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return len(this.ExtraFiles) == 0
+}
+
+type VerifyApp struct {
+	config VerifyConfig
+	output io.Writer
+}
+
+func NewVerifyApp(config VerifyConfig) *VerifyApp {
+	return &VerifyApp{config: config, output: os.Stdout}
+}
+
+func (this *VerifyApp) Run() {
+	report, err := this.Verify()
+	if err == nil {
+		err = this.print(report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
+
+func (this *VerifyApp) Verify() (report VerificationReport, err error) {
+	disk := shell.NewDiskFileSystem("")
+	verifier := core.NewExhaustiveVerifier(disk, core.NewFileContentIntegrityCheck(md5.New, disk, true))
+
+	report.ExtraFiles = []core.VerificationProblem{}
+	inspected := make(map[string]struct{})
+	for _, dependency := range this.config.Dependencies.Listing {
+		result := verifier.VerifyDependency(dependency)
+		report.Packages = append(report.Packages, result)
+
+		if _, found := inspected[result.LocalPath]; found || result.Error != "" {
+			continue
+		}
+		inspected[result.LocalPath] = struct{}{}
+		strays, err := core.NewStrayFinder(shell.NewDiskFileSystem(result.LocalPath)).Find(result.LocalPath)
+		if err != nil {
+			return report, fmt.Errorf("could not look for extra files in %q: %w", result.LocalPath, err)
+		}
+		for _, path := range strays {
+			report.ExtraFiles = append(report.ExtraFiles, core.VerificationProblem{Kind: core.ProblemExtra, Path: path})
+		}
+	}
+	return report, nil
+}
+
+func (this *VerifyApp) print(report VerificationReport) error {
+	if this.config.Format == formatJSON {
+		encoder := json.NewEncoder(this.output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	for _, result := range report.Packages {
+		switch {
+		case result.Error != "":
+			_, _ = fmt.Fprintf(this.output, "[FAIL] %s in %s: %s\n", result.PackageName, result.LocalPath, result.Error)
+		case result.Passed():
+			_, _ = fmt.Fprintf(this.output, "[ OK ] %s @ %s in %s\n", result.PackageName, result.Version, result.LocalPath)
+		default:
+			_, _ = fmt.Fprintf(this.output, "[FAIL] %s @ %s in %s\n", result.PackageName, result.Version, result.LocalPath)
+		}
+		printProblems(this.output, result.Problems)
+	}
+	if len(report.ExtraFiles) > 0 {
+		_, _ = fmt.Fprintln(this.output, "[FAIL] files not owned by any installed package")
+		printProblems(this.output, report.ExtraFiles)
+	}
+	return nil
+}
+
+func printProblems(output io.Writer, problems []core.VerificationProblem) {
+	for _, problem := range problems {
+		if problem.Kind == core.ProblemSizeMismatch {
+			_, _ = fmt.Fprintf(output, "  %-18s %s (expected %d bytes, found %d)\n", problem.Kind, problem.Path, problem.ExpectedSize, problem.ActualSize)
+		} else {
+			_, _ = fmt.Fprintf(output, "  %-18s %s\n", problem.Kind, problem.Path)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+func TestVerifyFixture(t *testing.T) {
+	gunit.Run(new(VerifyFixture), t)
+}
+
+type VerifyFixture struct {
+	*gunit.Fixture
+
+	directory string
+	output    *bytes.Buffer
+	app       *VerifyApp
+}
+
+func (this *VerifyFixture) Setup() {
+	this.directory, _ = os.MkdirTemp("", "satisfy-verify-")
+	this.output = new(bytes.Buffer)
+	this.app = &VerifyApp{output: this.output, config: VerifyConfig{
+		Format: formatTable,
+		Dependencies: contracts.DependencyListing{Listing: []contracts.Dependency{
+			{PackageName: "package", LocalDirectory: this.directory},
+		}},
+	}}
+
+	this.writeFile("intact", "intact")
+	this.writeFile("modified", "MODIFIED")
+	this.writeFile("truncated", "trunc")
+	this.writeFile("extra", "extra")
+	manifest := contracts.Manifest{Name: "package", Version: "1.2.3", Archive: contracts.Archive{
+		Contents: []contracts.ArchiveItem{
+			this.item("intact", "intact"),
+			this.item("modified", "modified"),
+			this.item("truncated", "truncated"),
+			this.item("missing", "missing"),
+		},
+	}}
+	raw, _ := json.Marshal(manifest)
+	this.writeFile("manifest_package.json", string(raw))
+}
+
+func (this *VerifyFixture) Teardown() {
+	_ = os.RemoveAll(this.directory)
+}
+
+func (this *VerifyFixture) writeFile(name, content string) {
+	this.So(os.WriteFile(filepath.Join(this.directory, name), []byte(content), 0644), should.BeNil)
+}
+
+func (this *VerifyFixture) item(name, content string) contracts.ArchiveItem {
+	checksum := md5.Sum([]byte(content))
+	return contracts.ArchiveItem{Path: name, Size: int64(len(content)), MD5Checksum: checksum[:]}
+}
+
+func (this *VerifyFixture) TestAllProblemsAreReported() {
+	report, err := this.app.Verify()
+
+	this.So(err, should.BeNil)
+	this.So(report.Passed(), should.BeFalse)
+	this.So(report.Packages, should.HaveLength, 1)
+	this.So(report.Packages[0].Problems, should.Resemble, []core.VerificationProblem{
+		{Kind: core.ProblemChecksumMismatch, Path: filepath.Join(this.directory, "modified")},
+		{Kind: core.ProblemSizeMismatch, Path: filepath.Join(this.directory, "truncated"), ExpectedSize: 9, ActualSize: 5},
+		{Kind: core.ProblemMissing, Path: filepath.Join(this.directory, "missing")},
+	})
+	this.So(report.ExtraFiles, should.Resemble, []core.VerificationProblem{
+		{Kind: core.ProblemExtra, Path: filepath.Join(this.directory, "extra")},
+	})
+}
+
+func (this *VerifyFixture) TestTextReport() {
+	report, _ := this.app.Verify()
+
+	this.So(this.app.print(report), should.BeNil)
+
+	this.So(this.output.String(), should.Equal, ""+
+		"[FAIL] package @ 1.2.3 in "+this.directory+"\n"+
+		"  checksum-mismatch  "+filepath.Join(this.directory, "modified")+"\n"+
+		"  size-mismatch      "+filepath.Join(this.directory, "truncated")+" (expected 9 bytes, found 5)\n"+
+		"  missing            "+filepath.Join(this.directory, "missing")+"\n"+
+		"[FAIL] files not owned by any installed package\n"+
+		"  extra              "+filepath.Join(this.directory, "extra")+"\n")
+}
+
+func (this *VerifyFixture) TestNotInstalledIsReported() {
+	this.app.config.Dependencies.Listing[0].PackageName = "other"
+
+	report, err := this.app.Verify()
+
+	this.So(err, should.BeNil)
+	this.So(report.Passed(), should.BeFalse)
+	this.So(report.Packages[0].Error, should.StartWith, "not installed")
+}
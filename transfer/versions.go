@@ -0,0 +1,154 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/smarty/satisfy/contracts"
+	"github.com/smarty/satisfy/core"
+)
+
+type VersionsConfig struct {
+	PackageName   string
+	RemoteAddress contracts.URL
+	Anonymous     bool
+	MaxRetry      int
+	Sort          string
+	JSON          bool
+}
+
+func ParseVersionsConfig(args []string) (config VersionsConfig, err error) {
+	flags := flag.NewFlagSet("satisfy versions", flag.ContinueOnError)
+
+	var bucket, prefix, packageName string
+	flags.StringVar(&bucket,
+		"bucket",
+		"",
+		"GCS bucket name (required), e.g. liveaddress-downloads-dev.",
+	)
+	flags.StringVar(&prefix,
+		"path",
+		"",
+		"Optional path prefix within the bucket where packages live, e.g. /releases.",
+	)
+	flags.StringVar(&packageName,
+		"package",
+		"",
+		"Package name (required), e.g. master-address-list/2026/04/premium/az.",
+	)
+	flags.StringVar(&config.Sort,
+		"sort",
+		sortBySemver,
+		"Order of the listed versions, oldest first: 'semver' or 'time' (of upload).",
+	)
+	flags.BoolVar(&config.JSON,
+		"json",
+		false,
+		"When set, print the versions as a JSON array instead of a table.",
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, access a public bucket with unsigned requests instead of Google credentials.",
+	)
+	flags.IntVar(&config.MaxRetry,
+		"max-retry",
+		5,
+		"How many times to retry the listing request.",
+	)
+
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage: %s versions -bucket <name> [-path <prefix>] -package <name> [-sort semver|time] [-json] [-anonymous]\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return VersionsConfig{}, err
+	}
+	if config.Sort != sortBySemver && config.Sort != sortByTime {
+		return VersionsConfig{}, errors.New("-sort must be either 'semver' or 'time'")
+	}
+	config.RemoteAddress, config.PackageName, err = parsePackageLocation(bucket, prefix, packageName)
+	if err != nil {
+		return VersionsConfig{}, err
+	}
+
+	return config, nil
+}
+
+type VersionsApp struct {
+	config VersionsConfig
+	output io.Writer
+}
+
+func NewVersionsApp(config VersionsConfig) *VersionsApp {
+	return &VersionsApp{config: config, output: os.Stdout}
+}
+
+func (this *VersionsApp) Run() {
+	if err := this.TryRun(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (this *VersionsApp) TryRun() error {
+	storage := newRemoteStorage(contracts.DependencyListing{}, []int{http.StatusOK}, this.config.MaxRetry, this.config.Anonymous)
+	catalog := core.NewPackageCatalog(storage)
+
+	versions, err := catalog.ListVersions(contracts.Dependency{
+		PackageName:   this.config.PackageName,
+		RemoteAddress: this.config.RemoteAddress,
+	})
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no published versions of %q found at %s", this.config.PackageName, this.config.RemoteAddress.Value())
+	}
+	return this.print(versions)
+}
+
+func (this *VersionsApp) print(versions []core.PublishedVersion) error {
+	if this.config.Sort == sortByTime {
+		core.SortByUpdated(versions)
+	} else {
+		core.SortBySemanticVersion(versions)
+	}
+
+	if this.config.JSON {
+		listing := make([]publishedVersionJSON, 0, len(versions))
+		for _, version := range versions {
+			listing = append(listing, publishedVersionJSON{Version: version.Version, Size: version.ArchiveSize, Uploaded: version.Updated})
+		}
+		encoder := json.NewEncoder(this.output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listing)
+	}
+
+	writer := tabwriter.NewWriter(this.output, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "VERSION\tSIZE\tUPLOADED")
+	for _, version := range versions {
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%s\n", version.Version, version.ArchiveSize, version.Updated.UTC().Format(time.RFC3339))
+	}
+	return writer.Flush()
+}
+
+type publishedVersionJSON struct {
+	Version  string    `json:"version"`
+	Size     int64     `json:"size"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+const (
+	sortBySemver = "semver"
+	sortByTime   = "time"
+)
@@ -0,0 +1,76 @@
+package transfer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+
+	"github.com/smarty/satisfy/core"
+)
+
+func TestVersionsFixture(t *testing.T) {
+	gunit.Run(new(VersionsFixture), t)
+}
+
+type VersionsFixture struct {
+	*gunit.Fixture
+
+	output   *bytes.Buffer
+	versions []core.PublishedVersion
+}
+
+func (this *VersionsFixture) Setup() {
+	this.output = new(bytes.Buffer)
+	this.versions = []core.PublishedVersion{
+		{Version: "1.10.0", ArchiveSize: 110, Updated: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Version: "1.9.0", ArchiveSize: 19, Updated: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func (this *VersionsFixture) app(config VersionsConfig) *VersionsApp {
+	return &VersionsApp{config: config, output: this.output}
+}
+
+func (this *VersionsFixture) TestUnrecognizedSortIsRejected() {
+	_, err := ParseVersionsConfig([]string{"-bucket", "b", "-package", "p", "-sort", "size"})
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "-sort")
+}
+
+func (this *VersionsFixture) TestMissingPackageIsRejected() {
+	_, err := ParseVersionsConfig([]string{"-bucket", "b"})
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "-package is required")
+}
+
+func (this *VersionsFixture) TestTableSortedBySemver() {
+	err := this.app(VersionsConfig{Sort: sortBySemver}).print(this.versions)
+
+	this.So(err, should.BeNil)
+	this.So(this.output.String(), should.Equal, ""+
+		"VERSION  SIZE  UPLOADED\n"+
+		"1.9.0    19    2026-02-01T00:00:00Z\n"+
+		"1.10.0   110   2026-01-01T00:00:00Z\n")
+}
+
+func (this *VersionsFixture) TestJSONSortedByTime() {
+	err := this.app(VersionsConfig{Sort: sortByTime, JSON: true}).print(this.versions)
+
+	this.So(err, should.BeNil)
+	this.So(this.output.String(), should.Equal, `[
+  {
+    "version": "1.10.0",
+    "size": 110,
+    "uploaded": "2026-01-01T00:00:00Z"
+  },
+  {
+    "version": "1.9.0",
+    "size": 19,
+    "uploaded": "2026-02-01T00:00:00Z"
+  }
+]
+`)
+}